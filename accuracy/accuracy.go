@@ -0,0 +1,217 @@
+// Rating how accurately scouters scout, by comparing their data to TBA's official score breakdowns.
+//
+// For every played qualification match, each alliance's official totals (such as notes scored or robots climbed, as configured
+// in the game definition) are compared to the sum of what was scouted at its three driverstations. A scouter's error for a
+// match is how far off the alliance would be using their data for their driverstation and the average of everyone else's for the
+// other two, so with one scouter per driverstation the whole alliance shares the blame, and with multi-scouting it falls on
+// whoever disagrees with the official totals.
+package accuracy
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"GreenScoutBackend/tba"
+	"GreenScoutBackend/userDB"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Held while comparing, so a slow run is never overlapped by the next one
+var running sync.Mutex
+
+// One alliance of one match, as stored
+type alliance struct {
+	match  int  // The qualification match number
+	isBlue bool // If it is the blue alliance
+}
+
+// Compares every played qualification match of the current event to its score breakdown, updating every scouter's rating.
+// Does nothing at custom events, which TBA doesn't know about.
+func Run() {
+	if !running.TryLock() {
+		greenlogger.LogMessage("Skipping scouter accuracy rating, the last run is still going")
+		return
+	}
+	defer running.Unlock()
+
+	configs := constants.CachedConfigs
+	if constants.CustomEventKey || len(constants.CachedGameConfig.Accuracy) == 0 {
+		return
+	}
+
+	matches, err := tba.NewClient(configs.TBAKey).EventMatches(configs.EventKey)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem requesting the matches of %v from TBA", configs.EventKey)
+		return
+	}
+
+	scouted := make(map[alliance][][]lib.TeamData)
+	for _, stored := range matchDB.GetEventTeamData(configs.EventKey) {
+		entry := stored.Data
+		if entry.DriverStation.Number < 1 || entry.DriverStation.Number > 3 {
+			continue
+		}
+
+		key := alliance{match: int(entry.Match.Number), isBlue: entry.DriverStation.IsBlue}
+		if scouted[key] == nil {
+			scouted[key] = make([][]lib.TeamData, 3)
+		}
+		scouted[key][entry.DriverStation.Number-1] = append(scouted[key][entry.DriverStation.Number-1], entry)
+	}
+
+	compared := 0
+	for _, match := range matches {
+		if match.CompLevel != tba.Qualification || !match.IsPlayed() || match.ScoreBreakdown == nil {
+			continue
+		}
+
+		for _, isBlue := range []bool{true, false} {
+			stations, found := scouted[alliance{match: match.MatchNumber, isBlue: isBlue}]
+			breakdown := match.ScoreBreakdown[allianceKey(isBlue)]
+			if !found || breakdown == nil {
+				continue
+			}
+
+			for _, result := range CompareAlliance(breakdown, latestPlay(stations)) {
+				result.Event, result.Match, result.IsBlue = configs.EventKey, match.MatchNumber, isBlue
+				userDB.RecordMatchAccuracy(result)
+				awardPoints(result)
+				compared++
+			}
+		}
+	}
+
+	userDB.UpdateAccuracyRatings()
+	greenlogger.LogMessagef("Rated %v scouted matches against TBA score breakdowns", compared)
+}
+
+// Returns the key of an alliance in a score breakdown
+func allianceKey(isBlue bool) string {
+	if isBlue {
+		return "blue"
+	}
+	return "red"
+}
+
+// Keeps only the entries of the replay of a match, if it was replayed, as that is the play the breakdown describes
+func latestPlay(stations [][]lib.TeamData) [][]lib.TeamData {
+	replayed := false
+	for _, entries := range stations {
+		for _, entry := range entries {
+			replayed = replayed || entry.Match.IsReplay
+		}
+	}
+
+	if !replayed {
+		return stations
+	}
+
+	latest := make([][]lib.TeamData, len(stations))
+	for i, entries := range stations {
+		for _, entry := range entries {
+			if entry.Match.IsReplay {
+				latest[i] = append(latest[i], entry)
+			}
+		}
+	}
+	return latest
+}
+
+// Compares an alliance's scouting data, by driverstation, to its score breakdown, returning the error of every scouter.
+// Nothing is returned unless every driverstation was scouted, as the alliance's totals can't be known otherwise.
+func CompareAlliance(breakdown map[string]any, stations [][]lib.TeamData) []userDB.MatchAccuracy {
+	if len(stations) != 3 || slices.ContainsFunc(stations, func(entries []lib.TeamData) bool { return len(entries) == 0 }) {
+		return nil
+	}
+
+	metrics := constants.CachedGameConfig.Accuracy
+
+	// The mean scouted value of every metric at every driverstation
+	means := make([][]float64, len(stations))
+	for station, entries := range stations {
+		means[station] = make([]float64, len(metrics))
+		for _, entry := range entries {
+			for i, metric := range metrics {
+				means[station][i] += ScoutedTotal(entry, metric) / float64(len(entries))
+			}
+		}
+	}
+
+	var results []userDB.MatchAccuracy
+	for station, entries := range stations {
+		for _, entry := range entries {
+			result := userDB.MatchAccuracy{Username: entry.Scouter}
+
+			for i, metric := range metrics {
+				scouted := ScoutedTotal(entry, metric)
+				for other := range stations {
+					if other != station {
+						scouted += means[other][i]
+					}
+				}
+
+				official := OfficialTotal(breakdown, metric)
+				result.Error += math.Abs(scouted - official)
+				result.Official += official
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// Returns an alliance's official total of a quantity from its score breakdown
+func OfficialTotal(breakdown map[string]any, metric constants.AccuracyConfig) float64 {
+	var total float64
+	for _, field := range metric.Breakdown {
+		value := breakdown[field]
+
+		if text, isText := value.(string); isText {
+			if slices.Contains(metric.Values, text) {
+				total++
+			}
+			continue
+		}
+
+		if number, isNumber := lib.ToNumber(value); isNumber {
+			total += number
+		}
+	}
+	return total
+}
+
+// Returns one scouter's total of a quantity from their match data
+func ScoutedTotal(team lib.TeamData, metric constants.AccuracyConfig) float64 {
+	var total float64
+
+	for _, cycle := range team.Cycles {
+		if cycle.Success && slices.Contains(metric.CycleTypes, cycle.Type) {
+			total++
+		}
+	}
+
+	for _, path := range metric.Paths {
+		if number, isNumber := lib.ToNumber(lib.GetField(team, path)); isNumber {
+			total += number
+		}
+	}
+
+	return total
+}
+
+// Gives a scouter leaderboard points for an accurate match, if configured, once per match
+func awardPoints(result userDB.MatchAccuracy) {
+	configs := constants.CachedConfigs.AccuracyConfigs
+	if configs.LeaderboardPoints <= 0 || result.Error > configs.AccurateWithin || result.Username == "" {
+		return
+	}
+
+	if userDB.MarkAccuracyAwarded(result) {
+		userDB.ModifyUserScore(result.Username, userDB.Increase, configs.LeaderboardPoints)
+	}
+}
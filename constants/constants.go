@@ -38,6 +38,32 @@ var DefaultLogDirectory = "logs"
 var DefaultTeamsDirectory = "teams"
 var DefaultCertsDirectory = "certs"
 
+// The defaults of the ingestion queue, used when IngestConfigs are missing or invalid
+var DefaultIngestWorkers = 4
+var DefaultIngestQueueSize = 256
+var DefaultIngestRescanSeconds = 30
+var DefaultIngestMaxAttempts = 5
+var DefaultIngestRetryBaseSeconds = 2
+var DefaultIngestRetryMaxSeconds = 300
+
+// The defaults of scouter accuracy rating, used when AccuracyConfigs are missing or invalid
+var DefaultAccuracySchedule = "@every 15m"
+var DefaultAccuracyAccurateWithin = 1.0
+
+// How often OPR, DPR and CCWM are recomputed when RatingConfigs are missing or invalid
+var DefaultRatingSchedule = "@every 10m"
+
+// The defaults of match predictions, used when PredictionConfigs are missing or invalid
+var DefaultPredictionSchedule = "@every 5m"
+var DefaultPredictionSimulations = 10000
+
+// The lowest average confidence of a multi-scouted match's cycles, as a percentage, before they're reported as disagreeing,
+// used when MultiScoutConfigs are missing or invalid
+var DefaultMinCycleConfidence = 75.0
+
+// How submissions are checked against the schedule when ValidationConfigs are missing or invalid
+var DefaultValidationMode = "warn"
+
 var RSAPubKeyPath string
 var RSAPrivateKeyPath string
 var SheetsTokenFile string
@@ -48,7 +74,6 @@ var Teams []int
 
 // The structure of the server configurations.
 type GeneralConfigs struct {
-	PythonDriver       string             `yaml:"PythonDriver"`       // The driver used to run python files
 	SqliteDriver       string             `yaml:"SqliteDriver"`       // The driver used to execute sqlite queries
 	TBAKey             string             `yaml:"TBAKey"`             // The API Key used to connect to https://www.thebluealliance.com/apidocs/v3
 	EventKey           string             `yaml:"EventKey"`           // The Blue alliance key of the event currently configured
@@ -66,8 +91,16 @@ type GeneralConfigs struct {
 	PfpDirectory       string             `yaml:"PfpDirectory"`
 	GalleryDirectory   string             `yaml:"GalleryDirectory"`
 	CertsDirectory     string             `yaml:"CertsDirectory"`
-	SlackConfigs       SlackConfigs       `yaml:"SlackConfigs"`   // The configurations for the server's slack integration
-	LogConfigs         LoggingConfigs     `yaml:"LoggingConfigs"` // The configurations for the server's logging
+	SlackConfigs       SlackConfigs       `yaml:"SlackConfigs"`      // The configurations for the server's slack integration
+	LogConfigs         LoggingConfigs     `yaml:"LoggingConfigs"`    // The configurations for the server's logging
+	AccountConfigs     AccountConfigs     `yaml:"AccountConfigs"`    // The configurations for user accounts
+	IngestConfigs      IngestConfigs      `yaml:"IngestConfigs"`     // The configurations for processing submitted scouting data
+	ValidationConfigs  ValidationConfigs  `yaml:"ValidationConfigs"` // The configurations for checking submitted scouting data against the schedule
+	AccuracyConfigs    AccuracyConfigs    `yaml:"AccuracyConfigs"`   // The configurations for rating scouters against TBA score breakdowns
+	RatingConfigs      RatingConfigs      `yaml:"RatingConfigs"`     // The configurations for computing OPR, DPR and CCWM from TBA match results
+	PredictionConfigs  PredictionConfigs  `yaml:"PredictionConfigs"` // The configurations for predicting upcoming matches
+	SwapConfigs        SwapConfigs        `yaml:"SwapConfigs"`       // The configurations for scouters swapping parts of their schedules
+	MultiScoutConfigs  MultiScoutConfigs  `yaml:"MultiScoutConfigs"` // The configurations for combining multi-scouted matches
 }
 
 // Configuration for slack integration
@@ -84,6 +117,61 @@ type LoggingConfigs struct {
 	LoggingHttp bool `yaml:"LogHttp"`    // If the server will be logging output of the HTTP client to GSLogs
 }
 
+type AccountConfigs struct {
+	Configured       bool `yaml:"Configured"`       // If these configs have ever been generated; DO NOT EDIT THIS
+	AllowJoinCode    bool `yaml:"AllowJoinCode"`    // If a shared role password can be used as a join code, creating an account for a new username
+	SessionIdleHours int  `yaml:"SessionIdleHours"` // How long a login session lasts without being used
+	SessionMaxDays   int  `yaml:"SessionMaxDays"`   // How long a login session can last in total, however much it is used
+}
+
+type IngestConfigs struct {
+	Configured       bool `yaml:"Configured"`       // If these configs have ever been generated; DO NOT EDIT THIS
+	Workers          int  `yaml:"Workers"`          // How many submissions can be processed at once. Submissions for the same match and driverstation are never processed at once.
+	QueueSize        int  `yaml:"QueueSize"`        // How many submissions can wait to be processed before new ones are turned away with a 503
+	RescanSeconds    int  `yaml:"RescanSeconds"`    // How often the In directory is checked for files that aren't queued, such as ones left by a crash
+	MaxAttempts      int  `yaml:"MaxAttempts"`      // How many times a submission is tried before it is moved to Errored
+	RetryBaseSeconds int  `yaml:"RetryBaseSeconds"` // How long to wait before the first retry of a submission that failed to write. Each retry waits twice as long as the last.
+	RetryMaxSeconds  int  `yaml:"RetryMaxSeconds"`  // The longest to ever wait between retries
+}
+
+type ValidationConfigs struct {
+	Configured bool   `yaml:"Configured"` // If these configs have ever been generated; DO NOT EDIT THIS
+	Mode       string `yaml:"Mode"`       // What happens to submissions that don't match the schedule: "reject", "warn", "correct" (the team number from the schedule), or "off"
+}
+
+type AccuracyConfigs struct {
+	Configured        bool    `yaml:"Configured"`        // If these configs have ever been generated; DO NOT EDIT THIS
+	Enabled           bool    `yaml:"Enabled"`           // If scouters are rated against TBA score breakdowns. Never runs at custom events.
+	Schedule          string  `yaml:"Schedule"`          // How often played matches are compared, as a cron spec such as "@every 15m"
+	AccurateWithin    float64 `yaml:"AccurateWithin"`    // How far off, in total across every compared quantity, an alliance can be for a scouter's match to count as accurate
+	LeaderboardPoints int     `yaml:"LeaderboardPoints"` // The leaderboard points given once for every accurate match; 0 gives none
+}
+
+type RatingConfigs struct {
+	Configured bool   `yaml:"Configured"` // If these configs have ever been generated; DO NOT EDIT THIS
+	Enabled    bool   `yaml:"Enabled"`    // If OPR, DPR and CCWM are computed from TBA match results. Never runs at custom events.
+	Schedule   string `yaml:"Schedule"`   // How often they are recomputed, as a cron spec such as "@every 10m"
+	WriteSheet bool   `yaml:"WriteSheet"` // If they are also written to the Ratings tab of the sheet
+}
+
+type PredictionConfigs struct {
+	Configured  bool   `yaml:"Configured"`  // If these configs have ever been generated; DO NOT EDIT THIS
+	Enabled     bool   `yaml:"Enabled"`     // If the upcoming matches in schedule.json are predicted
+	Schedule    string `yaml:"Schedule"`    // How often they are predicted, and played ones checked, as a cron spec such as "@every 5m"
+	Simulations int    `yaml:"Simulations"` // The number of times every match is simulated
+	UseOPR      bool   `yaml:"UseOPR"`      // If teams that have barely been scouted are simulated with their OPR, when RatingConfigs are enabled
+}
+
+type SwapConfigs struct {
+	Configured      bool `yaml:"Configured"`      // If these configs have ever been generated; DO NOT EDIT THIS
+	RequireApproval bool `yaml:"RequireApproval"` // If an admin has to approve accepted swaps before the schedules change
+}
+
+type MultiScoutConfigs struct {
+	Configured         bool    `yaml:"Configured"`         // If these configs have ever been generated; DO NOT EDIT THIS
+	MinCycleConfidence float64 `yaml:"MinCycleConfidence"` // The lowest average confidence of a match's aligned cycles, from 0 to 100, before its scouters' cycles are reported as disagreeing. 0 only reports differing cycle counts and times.
+}
+
 type CustomEventConfigs struct {
 	Configured     bool `yaml:"Configured"`     // If these configs have ever been generated; DO NOT EDIT THIS
 	CustomSchedule bool `yaml:"CustomSchedule"` // If there is a custom schedule.json file to be used with the custom event key
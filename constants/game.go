@@ -0,0 +1,162 @@
+package constants
+
+// Declarative definition of the game being played this season
+
+import "path/filepath"
+
+// The constant reference to the game definition yaml, kept alongside the setup yaml
+var GameConfigFilePath = filepath.Join("conf", "game.config.yaml")
+
+// The game definition held in memory
+var CachedGameConfig GameConfigs
+
+// The structure of a season's game definition. Everything season-specific about parsing,
+// multi-scout merging and sheet writing is driven from here.
+type GameConfigs struct {
+	Season         int                   `yaml:"Season"`         // The year of the game
+	Name           string                `yaml:"Name"`           // The name of the game
+	CycleTypes     []CycleTypeConfig     `yaml:"CycleTypes"`     // Every type a recorded cycle can have
+	ScoringActions []ScoringActionConfig `yaml:"ScoringActions"` // Scoring done outside of cycles, such as in auto
+	EndgameOptions []ScoringActionConfig `yaml:"EndgameOptions"` // What a robot can do at the end of a match
+	Columns        []ColumnConfig        `yaml:"Columns"`        // The RawData columns, in order, starting at column B
+	PitColumns     []ColumnConfig        `yaml:"PitColumns"`     // The PitScouting columns, in order, starting at column B
+	Accuracy       []AccuracyConfig      `yaml:"Accuracy"`       // What is compared to TBA score breakdowns to rate scouter accuracy
+	RatingFields   []string              `yaml:"RatingFields"`   // Score breakdown fields that get a component OPR each, such as autoPoints
+}
+
+// One type of cycle, such as scoring in a specific goal
+type CycleTypeConfig struct {
+	Name   string  `yaml:"Name"`   // The name sent by the app as the cycle's type
+	Points float64 `yaml:"Points"` // The points a successful cycle of this type is worth
+}
+
+// One scoring action recorded as a field of a submission
+type ScoringActionConfig struct {
+	Name   string  `yaml:"Name"`   // The name of the action
+	Path   string  `yaml:"Path"`   // The path to the field recording it; numbers are counts and booleans are one-offs
+	Points float64 `yaml:"Points"` // The points each occurence is worth
+}
+
+// One column written to the spreadsheet
+type ColumnConfig struct {
+	Header        string           `yaml:"Header"`                  // The name of the column, for documentation
+	Source        string           `yaml:"Source"`                  // How the value is derived; see lib/game.go for every source
+	Path          string           `yaml:"Path,omitempty"`          // The path to the field used by field sources, such as "Auto.Scores"
+	CycleType     string           `yaml:"CycleType,omitempty"`     // The cycle type used by cycle tendency and accuracy sources
+	Made          string           `yaml:"Made,omitempty"`          // The path counting successes, used by accuracy sources
+	Missed        string           `yaml:"Missed,omitempty"`        // The path counting failures, used by accuracy sources
+	Flags         []FlagConfig     `yaml:"Flags,omitempty"`         // The labeled boolean fields used by flags sources
+	AllLabel      string           `yaml:"AllLabel,omitempty"`      // The label written when every flag is set
+	NoneLabel     string           `yaml:"NoneLabel,omitempty"`     // The label written when no flag is set
+	Condition     *ConditionConfig `yaml:"Condition,omitempty"`     // If set, N/A is written unless the condition holds
+	Format        string           `yaml:"Format,omitempty"`        // An optional format applied to the value, such as "integer"
+	Merge         string           `yaml:"Merge,omitempty"`         // How values from multiple scouters are merged; see lib/game.go
+	DissentWithin float64          `yaml:"DissentWithin,omitempty"` // How far a scouter's number can be from the merged value before it's recorded as dissent; 1 if unset
+}
+
+// One quantity compared between an alliance's scouting data and its TBA score breakdown
+type AccuracyConfig struct {
+	Name       string   `yaml:"Name"`                 // The name of the quantity
+	Breakdown  []string `yaml:"Breakdown"`            // The score breakdown fields summed for the official total; numbers are counts and booleans are one-offs
+	Values     []string `yaml:"Values,omitempty"`     // If set, text breakdown fields count once when they are one of these
+	CycleTypes []string `yaml:"CycleTypes,omitempty"` // Successful cycles of these types count towards the scouted total
+	Paths      []string `yaml:"Paths,omitempty"`      // The fields of a submission summed for the scouted total; numbers are counts and booleans are one-offs
+}
+
+// A labeled boolean field
+type FlagConfig struct {
+	Path  string `yaml:"Path"`  // The path to the field
+	Label string `yaml:"Label"` // The label written when it is set
+}
+
+// A condition on one field of a submission
+type ConditionConfig struct {
+	Path   string `yaml:"Path"`   // The path to the field
+	Equals any    `yaml:"Equals"` // The value it must have
+}
+
+// The game definition of the 2024 game, Crescendo. Written to GameConfigFilePath if no game definition exists.
+var DefaultGameConfig = GameConfigs{
+	Season: 2024,
+	Name:   "Crescendo",
+	CycleTypes: []CycleTypeConfig{
+		{Name: "Amp", Points: 1},
+		{Name: "Speaker", Points: 2},
+		{Name: "Distance", Points: 2},
+		{Name: "Shuttle", Points: 0},
+	},
+	ScoringActions: []ScoringActionConfig{
+		{Name: "Auto Scores", Path: "Auto.Scores", Points: 5},
+	},
+	EndgameOptions: []ScoringActionConfig{
+		{Name: "Climb", Path: "Climbing.Succeeded", Points: 3},
+		{Name: "Park", Path: "Misc.Parked", Points: 1},
+		{Name: "Trap", Path: "Trap.Score", Points: 5},
+	},
+	Columns: []ColumnConfig{
+		{Header: "Team Number", Source: "team"},
+		{Header: "Avg Cycle Time", Source: "cycleAverage"},
+		{Header: "Num Cycles", Source: "cycleCount"},
+		{Header: "Amp Tendency", Source: "cycleTendency", CycleType: "Amp"},
+		{Header: "Amp Accuracy", Source: "cycleAccuracy", CycleType: "Amp"},
+		{Header: "Speaker Tendency", Source: "cycleTendency", CycleType: "Speaker"},
+		{Header: "Speaker Accuracy", Source: "cycleAccuracy", CycleType: "Speaker"},
+		{Header: "Distance Tendency", Source: "cycleTendency", CycleType: "Distance"},
+		{Header: "Distance Accuracy", Source: "cycleAccuracy", CycleType: "Distance"},
+		{Header: "Shuttle Tendency", Source: "cycleTendency", CycleType: "Shuttle"},
+		{Header: "Shuttle Accuracy", Source: "cycleAccuracy", CycleType: "Shuttle"},
+		{
+			Header: "Speaker Positions", Source: "flags",
+			Flags:    []FlagConfig{{Path: "Speaker Positions.sides", Label: "SIDES"}, {Path: "Speaker Positions.Middle", Label: "MIDDLE"}},
+			AllLabel: "BOTH", NoneLabel: "NONE",
+		},
+		{
+			Header: "Pickup Locations", Source: "flags",
+			Flags:    []FlagConfig{{Path: "Pickup Locations.ground", Label: "GROUND"}, {Path: "Pickup Locations.source", Label: "SOURCE"}},
+			AllLabel: "BOTH", NoneLabel: "NONE",
+		},
+		{Header: "Had Auto", Source: "field", Path: "Auto.Can", Merge: "majority"},
+		{Header: "Auto Scores", Source: "field", Path: "Auto.Scores", Merge: "floor"},
+		{Header: "Auto Accuracy", Source: "accuracy", Made: "Auto.Scores", Missed: "Auto.Misses", Merge: "floor"},
+		{Header: "Auto Shuttles", Source: "field", Path: "Auto.Ejects", Merge: "floor"},
+		{Header: "Climbed", Source: "field", Path: "Climbing.Succeeded", Merge: "majority"},
+		{Header: "Climb Time", Source: "field", Path: "Climbing.Time", Merge: "meanNonZero"},
+		{Header: "Parked", Source: "field", Path: "Misc.Parked", Merge: "majority"},
+		{Header: "Trap Score", Source: "field", Path: "Trap.Score", Merge: "round"},
+		{Header: "Notes", Source: "notes"},
+	},
+	PitColumns: []ColumnConfig{
+		{Header: "Team Number", Source: "field", Path: "Team"},
+		{Header: "Pit", Source: "field", Path: "Pit"},
+		{Header: "Drivetrain", Source: "field", Path: "Drivetrain"},
+		{
+			Header: "Speaker Positions", Source: "flags",
+			Flags:    []FlagConfig{{Path: "Sides.sides", Label: "SIDES"}, {Path: "Sides.Middle", Label: "MIDDLE"}},
+			AllLabel: "BOTH", NoneLabel: "NONE",
+		},
+		{Header: "Can Distance", Source: "field", Path: "Distance.Can"},
+		{Header: "Distance", Source: "field", Path: "Distance.Distance", Format: "integer", Condition: &ConditionConfig{Path: "Distance.Can", Equals: true}},
+		{Header: "Auto Scores", Source: "field", Path: "Auto Scores"},
+		{Header: "Middle Notes", Source: "field", Path: "Middle Notes"},
+		{Header: "Note Detection", Source: "field", Path: "Detection"},
+		{Header: "Cycles", Source: "field", Path: "Cycles"},
+		{Header: "Driver Experience", Source: "field", Path: "Experience"},
+		{Header: "Bot Type", Source: "field", Path: "Bot Type"},
+		{Header: "Endgame Behavior", Source: "field", Path: "Endgame Behavior"},
+		{Header: "Climb Time", Source: "field", Path: "Climb Time", Condition: &ConditionConfig{Path: "Endgame Behavior", Equals: "Climb"}},
+	},
+	Accuracy: []AccuracyConfig{
+		{Name: "Auto Notes", Breakdown: []string{"autoAmpNoteCount", "autoSpeakerNoteCount"}, Paths: []string{"Auto.Scores"}},
+		{Name: "Amp Notes", Breakdown: []string{"teleopAmpNoteCount"}, CycleTypes: []string{"Amp"}},
+		{Name: "Speaker Notes", Breakdown: []string{"teleopSpeakerNoteCount", "teleopSpeakerNoteAmplifiedCount"}, CycleTypes: []string{"Speaker", "Distance"}},
+		{
+			Name: "Climbs", Breakdown: []string{"endGameRobot1", "endGameRobot2", "endGameRobot3"},
+			Values: []string{"CenterStage", "StageLeft", "StageRight"}, Paths: []string{"Climbing.Succeeded"},
+		},
+		{
+			Name: "Traps", Breakdown: []string{"endGameNoteInTrapCenterStage", "endGameNoteInTrapStageLeft", "endGameNoteInTrapStageRight"},
+			Paths: []string{"Trap.Score"},
+		},
+	},
+	RatingFields: []string{"autoPoints", "teleopTotalNotePoints", "endGameTotalStagePoints", "foulPoints"},
+}
@@ -0,0 +1,313 @@
+package lib
+
+// Utility for summarizing every match a team has been scouted in at an event
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/montanaflynn/stats"
+)
+
+// The number of most recent matches trends are computed over by default
+const DefaultTrendMatches = 3
+
+// A summary of one quantity over a team's matches
+type Stat struct {
+	Mean   float64 // The mean
+	Median float64 // The median
+	StdDev float64 // The population standard deviation
+	Min    float64 // The lowest value
+	Max    float64 // The highest value
+}
+
+// How a team did at one type of cycle
+type CycleTypeStats struct {
+	Type     string  // The type of cycle
+	Attempts float64 // The attempts per match
+	Made     float64 // The successful attempts per match
+	Accuracy float64 // The percentage of attempts that succeeded, or 0 if there were none
+}
+
+// How a team has done in its most recent matches
+type Trend struct {
+	Matches    []int   // The match numbers the trend covers, in order
+	Cycles     Stat    // The number of cycles per match
+	AutoScores Stat    // The number of scores in auto per match
+	Slope      float64 // How many more cycles the team runs each match, from a linear fit over the trend's matches
+}
+
+// Aggregate statistics over every match a team has been scouted in
+type TeamStats struct {
+	Team          int              // The team number
+	Matches       []int            // The match numbers it was scouted in, in order
+	Entries       int              // The number of entries, counting every scouter of a multi-scouted match
+	Points        Stat             // The points it scored per match, estimated from the game definition
+	Cycles        Stat             // The number of cycles per match
+	CycleTime     Stat             // The average cycle time per match, over matches with cycles
+	CycleTypes    []CycleTypeStats // How it did at every type of cycle, by type
+	AutoRate      float64          // The percentage of matches it had an auto in
+	AutoScores    Stat             // The number of scores in auto per match
+	ClimbRate     float64          // The percentage of matches it climbed in
+	ClimbTime     Stat             // How long it took to climb, over matches it climbed in
+	TrapRate      float64          // The percentage of matches it scored in the trap in
+	TrapScores    Stat             // The number of notes it scored in the trap per match
+	ParkRate      float64          // The percentage of matches it parked in
+	DCRate        float64          // The percentage of matches it lost communication in
+	DisabledRate  float64          // The percentage of matches it was disabled in
+	LostTrackRate float64          // The percentage of matches a scouter lost track of it in
+	Trend         Trend            // How it has done in its most recent matches
+}
+
+// One team's performance in one match, averaged over everyone who scouted it
+type matchPerformance struct {
+	match      int     // The match number
+	cycles     []Cycle // The reconciled cycles
+	points     float64 // The estimated points scored
+	autoCan    float64 // The fraction of scouters who saw an auto
+	autoScores float64 // The mean number of scores in auto
+	climbed    float64 // The fraction of scouters who saw a climb
+	climbTime  float64 // The mean climb time of the scouters who saw a climb
+	trapScores float64 // The mean number of notes scored in the trap
+	parked     float64 // The fraction of scouters who saw a park
+	dc         float64 // The fraction of scouters who saw it lose communication
+	disabled   float64 // The fraction of scouters who saw it disabled
+	lostTrack  float64 // The fraction of scouters who lost track of it
+}
+
+// Compiles the statistics of every team in entries, ordered by team number.
+// Only the latest play of a replayed match is counted, and trends cover the last trendMatches matches.
+func CompileTeamStats(entries []TeamData, trendMatches int) []TeamStats {
+	byTeam := make(map[int][]TeamData)
+	for _, entry := range entries {
+		byTeam[int(entry.TeamNumber)] = append(byTeam[int(entry.TeamNumber)], entry)
+	}
+
+	var results []TeamStats
+	for team, teamEntries := range byTeam {
+		results = append(results, compileTeam(team, teamEntries, trendMatches))
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Team < results[j].Team })
+	return results
+}
+
+// Compiles the statistics of one team from all of its entries
+func compileTeam(team int, entries []TeamData, trendMatches int) TeamStats {
+	result := TeamStats{Team: team, Entries: len(entries)}
+
+	performances := compilePerformances(entries)
+
+	var points, cycles, cycleTimes, autoScores, climbTimes, trapScores []float64
+	var autoCan, climbed, trapped, parked, dc, disabled, lostTrack float64
+	cycleTypes := make(map[string]*CycleTypeStats)
+
+	for _, performance := range performances {
+		result.Matches = append(result.Matches, performance.match)
+		points = append(points, performance.points)
+
+		cycles = append(cycles, float64(GetNumCycles(performance.cycles)))
+		if average := GetAvgCycleTimeExclusive(performance.cycles); average != 0 {
+			cycleTimes = append(cycleTimes, average)
+		}
+
+		if cyclesAreValid(performance.cycles) {
+			for _, cycle := range performance.cycles {
+				if cycleTypes[cycle.Type] == nil {
+					cycleTypes[cycle.Type] = &CycleTypeStats{Type: cycle.Type}
+				}
+				cycleTypes[cycle.Type].Attempts++
+				if cycle.Success {
+					cycleTypes[cycle.Type].Made++
+				}
+			}
+		}
+
+		autoScores = append(autoScores, performance.autoScores)
+		trapScores = append(trapScores, performance.trapScores)
+		if performance.climbed > 0 {
+			climbTimes = append(climbTimes, performance.climbTime)
+		}
+		if performance.trapScores > 0 {
+			trapped++
+		}
+
+		autoCan += performance.autoCan
+		climbed += performance.climbed
+		parked += performance.parked
+		dc += performance.dc
+		disabled += performance.disabled
+		lostTrack += performance.lostTrack
+	}
+
+	result.Points = summarize(points)
+	result.Cycles = summarize(cycles)
+	result.CycleTime = summarize(cycleTimes)
+	result.AutoScores = summarize(autoScores)
+	result.ClimbTime = summarize(climbTimes)
+	result.TrapScores = summarize(trapScores)
+
+	played := float64(len(performances))
+	result.AutoRate = percentage(autoCan, played)
+	result.ClimbRate = percentage(climbed, played)
+	result.TrapRate = percentage(trapped, played)
+	result.ParkRate = percentage(parked, played)
+	result.DCRate = percentage(dc, played)
+	result.DisabledRate = percentage(disabled, played)
+	result.LostTrackRate = percentage(lostTrack, played)
+
+	for _, cycleType := range cycleTypes {
+		cycleType.Accuracy = percentage(cycleType.Made, cycleType.Attempts)
+		cycleType.Attempts /= played
+		cycleType.Made /= played
+		result.CycleTypes = append(result.CycleTypes, *cycleType)
+	}
+	sort.Slice(result.CycleTypes, func(i, j int) bool { return result.CycleTypes[i].Type < result.CycleTypes[j].Type })
+
+	result.Trend = compileTrend(performances, trendMatches)
+
+	return result
+}
+
+// Compiles one performance for every match in a team's entries, ordered by match
+func compilePerformances(entries []TeamData) []matchPerformance {
+	byMatch := make(map[int][]TeamData)
+	for _, entry := range entries {
+		byMatch[int(entry.Match.Number)] = append(byMatch[int(entry.Match.Number)], entry)
+	}
+
+	var performances []matchPerformance
+	for match, matchEntries := range byMatch {
+		performances = append(performances, compilePerformance(match, latestEntries(matchEntries)))
+	}
+	sort.Slice(performances, func(i, j int) bool { return performances[i].match < performances[j].match })
+
+	return performances
+}
+
+// Returns the estimated points every team in entries scored in each match it was scouted in, in match order
+func TeamPointSamples(entries []TeamData) map[int][]float64 {
+	byTeam := make(map[int][]TeamData)
+	for _, entry := range entries {
+		byTeam[int(entry.TeamNumber)] = append(byTeam[int(entry.TeamNumber)], entry)
+	}
+
+	samples := make(map[int][]float64)
+	for team, teamEntries := range byTeam {
+		for _, performance := range compilePerformances(teamEntries) {
+			samples[team] = append(samples[team], performance.points)
+		}
+	}
+	return samples
+}
+
+// Keeps only the entries of the replay of a match, if it was replayed
+func latestEntries(entries []TeamData) []TeamData {
+	if !slices.ContainsFunc(entries, func(entry TeamData) bool { return entry.Match.IsReplay }) {
+		return entries
+	}
+
+	return slices.DeleteFunc(slices.Clone(entries), func(entry TeamData) bool { return !entry.Match.IsReplay })
+}
+
+// Averages the entries of one team in one match into one performance, reconciling their cycles
+func compilePerformance(match int, entries []TeamData) matchPerformance {
+	performance := matchPerformance{match: match, cycles: compileCycles(entries).Cycles}
+	performance.points = CyclePoints(performance.cycles)
+
+	var climbTimes []float64
+	for _, entry := range entries {
+		performance.points += ActionPoints(entry) / float64(len(entries))
+		performance.autoCan += fraction(entry.Auto.Can, len(entries))
+		performance.autoScores += float64(entry.Auto.Scores) / float64(len(entries))
+		performance.climbed += fraction(entry.Climb.Succeeded, len(entries))
+		performance.trapScores += float64(entry.Trap.Score) / float64(len(entries))
+		performance.parked += fraction(entry.Misc.Parked, len(entries))
+		performance.dc += fraction(entry.Misc.DC, len(entries))
+		performance.disabled += fraction(entry.Misc.Disabled, len(entries))
+		performance.lostTrack += fraction(entry.Misc.LostTrack, len(entries))
+
+		if entry.Climb.Succeeded {
+			climbTimes = append(climbTimes, entry.Climb.Time)
+		}
+	}
+
+	if len(climbTimes) > 0 {
+		performance.climbTime, _ = stats.Mean(climbTimes)
+	}
+
+	return performance
+}
+
+// Compiles how a team did in its last matches
+func compileTrend(performances []matchPerformance, matches int) Trend {
+	var trend Trend
+	recent := performances[max(0, len(performances)-matches):]
+
+	var matchNumbers, cycles, autoScores []float64
+	for _, performance := range recent {
+		trend.Matches = append(trend.Matches, performance.match)
+		cycles = append(cycles, float64(GetNumCycles(performance.cycles)))
+		autoScores = append(autoScores, performance.autoScores)
+		matchNumbers = append(matchNumbers, float64(performance.match))
+	}
+
+	trend.Cycles = summarize(cycles)
+	trend.AutoScores = summarize(autoScores)
+	trend.Slope = slope(matchNumbers, cycles)
+
+	return trend
+}
+
+// Summarizes a list of values, leaving everything 0 if there are none
+func summarize(values []float64) Stat {
+	if len(values) == 0 {
+		return Stat{}
+	}
+
+	var summary Stat
+	summary.Mean, _ = stats.Mean(values)
+	summary.Median, _ = stats.Median(values)
+	summary.StdDev, _ = stats.StandardDeviationPopulation(values)
+	summary.Min, _ = stats.Min(values)
+	summary.Max, _ = stats.Max(values)
+	return summary
+}
+
+// Returns the slope of the least squares line through points (xs[i], ys[i]), or 0 if there aren't two different xs
+func slope(xs []float64, ys []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+
+	meanX, _ := stats.Mean(xs)
+	meanY, _ := stats.Mean(ys)
+
+	var covariance, variance float64
+	for i := range xs {
+		covariance += (xs[i] - meanX) * (ys[i] - meanY)
+		variance += (xs[i] - meanX) * (xs[i] - meanX)
+	}
+
+	if variance == 0 {
+		return 0
+	}
+	return covariance / variance
+}
+
+// Returns 1/total if a flag is set, so summing it over every entry gives the fraction of entries with it set
+func fraction(flag bool, total int) float64 {
+	if flag {
+		return 1 / float64(total)
+	}
+	return 0
+}
+
+// Returns part as a percentage of whole, rounded to two decimal places, or 0 if whole is 0
+func percentage(part float64, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(part/whole*10000) / 100
+}
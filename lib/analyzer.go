@@ -1,10 +1,11 @@
 package lib
 
-// Utility for analyzing differences in cycle times
+// Utility for analyzing differences in cycle times, and aligning the cycles of multiple scouters
 
 import (
 	greenlogger "GreenScoutBackend/greenLogger"
 	"math"
+	"slices"
 
 	"github.com/montanaflynn/stats"
 )
@@ -12,13 +13,19 @@ import (
 // The allowable difference between cycle time averages
 const kAllowableSeconds = 1.0
 
+// How far apart the times of two scouters' cycles can be while still being the same cycle
+const kCycleAlignmentSeconds = 3.0
+
+// How much more aligning two cycles of different types costs than aligning two of the same type
+const kTypeMismatchCost = 0.5
+
 // Returns if the cycles passed in were within the configured acceptable range
 // of similarity (time-based)
 func CompareCycles(data [][]Cycle) bool {
 
 	var averages []float64
 	for _, entry := range data {
-		averages = append(averages, entry[len(entry)-1].Time/float64(len(entry)))
+		averages = append(averages, GetAvgCycleTimeExclusive(entry))
 	}
 
 	return isNearSeconds(averages, kAllowableSeconds)
@@ -42,3 +49,143 @@ func isNearSeconds(averages []float64, allowableErr float64) bool {
 
 	return math.Abs(max-min) <= allowableErr
 }
+
+// One cycle reconciled from every scouter of a match
+type AlignedCycle struct {
+	Cycle      Cycle   // The reconciled cycle: the mean time, and the most common type and success
+	Recorded   int     // How many scouters recorded the cycle at all
+	Confidence float64 // The fraction of scouters who recorded the cycle with the reconciled type and success, from 0 to 1
+}
+
+// Cycles from different scouters that were aligned as the same cycle
+type cycleGroup struct {
+	cycles []Cycle // One cycle from each scouter who recorded it
+	time   float64 // The mean time of the cycles
+	kind   string  // The most common type of the cycles
+}
+
+// Adds a cycle to a group, updating its time and type
+func (group *cycleGroup) add(cycle Cycle) {
+	group.cycles = append(group.cycles, cycle)
+	group.time += (cycle.Time - group.time) / float64(len(group.cycles))
+
+	var kinds []any
+	for _, member := range group.cycles {
+		kinds = append(kinds, member.Type)
+	}
+	group.kind = vote(kinds, nil, false).(string)
+}
+
+// Returns the cost of aligning a cycle with a group, and if they can be aligned at all
+func alignmentCost(group cycleGroup, cycle Cycle) (float64, bool) {
+	distance := math.Abs(group.time - cycle.Time)
+	if distance > kCycleAlignmentSeconds {
+		return 0, false
+	}
+
+	cost := distance / kCycleAlignmentSeconds
+	if group.kind != cycle.Type {
+		cost += kTypeMismatchCost
+	}
+	return cost, true
+}
+
+// Aligns one scouter's cycles with the groups aligned so far, keeping both in order. Cycles that can't be aligned with any group,
+// such as ones other scouters missed, start new groups. Leaving a cycle or group unaligned costs 1, so aligning cycles within
+// kCycleAlignmentSeconds is always preferred.
+func alignInto(groups []cycleGroup, cycles []Cycle) []cycleGroup {
+	// costs[i][j] is the cheapest alignment of the first i groups with the first j cycles
+	costs := make([][]float64, len(groups)+1)
+	for i := range costs {
+		costs[i] = make([]float64, len(cycles)+1)
+		for j := range costs[i] {
+			switch {
+			case i == 0:
+				costs[i][j] = float64(j)
+			case j == 0:
+				costs[i][j] = float64(i)
+			default:
+				costs[i][j] = math.Min(costs[i-1][j], costs[i][j-1]) + 1
+				if cost, alignable := alignmentCost(groups[i-1], cycles[j-1]); alignable {
+					costs[i][j] = math.Min(costs[i][j], costs[i-1][j-1]+cost)
+				}
+			}
+		}
+	}
+
+	// Walk back from the end, building the aligned groups in reverse
+	var reversed []cycleGroup
+	i, j := len(groups), len(cycles)
+	for i > 0 || j > 0 {
+		if i > 0 && j > 0 {
+			if cost, alignable := alignmentCost(groups[i-1], cycles[j-1]); alignable && costs[i][j] == costs[i-1][j-1]+cost {
+				group := groups[i-1]
+				group.cycles = slices.Clone(group.cycles)
+				group.add(cycles[j-1])
+				reversed = append(reversed, group)
+				i, j = i-1, j-1
+				continue
+			}
+		}
+
+		if i > 0 && (j == 0 || costs[i][j] == costs[i-1][j]+1) {
+			reversed = append(reversed, groups[i-1])
+			i--
+		} else {
+			var group cycleGroup
+			group.add(cycles[j-1])
+			reversed = append(reversed, group)
+			j--
+		}
+	}
+
+	slices.Reverse(reversed)
+	return reversed
+}
+
+// Aligns the cycles of every scouter of a match by time and type, reconciling each aligned cycle.
+// Scouters without valid cycles count as having recorded none.
+func AlignCycles(scouted [][]Cycle) []AlignedCycle {
+	var groups []cycleGroup
+	for _, cycles := range scouted {
+		if cyclesAreValid(cycles) {
+			groups = alignInto(groups, cycles)
+		}
+	}
+
+	var aligned []AlignedCycle
+	for _, group := range groups {
+		var successes []any
+		for _, cycle := range group.cycles {
+			successes = append(successes, cycle.Success)
+		}
+
+		reconciled := Cycle{Time: group.time, Type: group.kind, Success: vote(successes, nil, false).(bool)}
+
+		agreeing := 0
+		for _, cycle := range group.cycles {
+			if cycle.Type == reconciled.Type && cycle.Success == reconciled.Success {
+				agreeing++
+			}
+		}
+
+		aligned = append(aligned, AlignedCycle{
+			Cycle:      reconciled,
+			Recorded:   len(group.cycles),
+			Confidence: float64(agreeing) / float64(len(scouted)),
+		})
+	}
+
+	return aligned
+}
+
+// Returns the reconciled cycles recorded by at least half of the scouters of a match, in order
+func ReconciledCycles(aligned []AlignedCycle, scouters int) []Cycle {
+	var cycles []Cycle
+	for _, cycle := range aligned {
+		if cycle.Recorded*2 >= scouters {
+			cycles = append(cycles, cycle.Cycle)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,112 @@
+package lib
+
+// Utility for reporting where the scouters of a multi-scouted match disagreed, so strategy leads know what to rewatch
+
+import (
+	"math"
+	"time"
+)
+
+// One field the scouters of a match disagreed on
+type Discrepancy struct {
+	Column  string         // The header of the column the field is written to
+	Path    string         // The path to the field
+	Merged  any            // The value that was written, after merging
+	Spread  float64        // The difference between the highest and lowest numbers, or 0 for anything that isn't a number
+	Outlier string         // The scouter furthest from the merged value, or the only one who disagreed with it
+	Values  map[string]any // What every scouter recorded, by scouter
+}
+
+// Everything the scouters of one multi-scouted match and driverstation disagreed on
+type DiscrepancyReport struct {
+	Event         string        // The event key
+	Match         int           // The match number
+	IsBlue        bool          // If the driverstation is on the blue alliance
+	DSNumber      int           // The driverstation number
+	Team          int           // The merged team number
+	Scouters      []string      // Everyone who scouted it
+	CycleMismatch bool          // If the number of cycles or cycle times disagreed
+	Discrepancies []Discrepancy // Every field that was disagreed on
+	ReportedAt    time.Time     // When it was last compiled
+}
+
+// Compiles the report of everything the scouters of a multi-scouted match disagreed on
+func CompileDiscrepancyReport(match MultiMatch, entries []TeamData) DiscrepancyReport {
+	report := DiscrepancyReport{
+		Event:         GetCurrentEvent(),
+		Match:         int(match.Match.Number),
+		IsBlue:        match.DriverStation.IsBlue,
+		DSNumber:      match.DriverStation.Number,
+		Team:          int(match.TeamNumber),
+		CycleMismatch: match.CycleData.HadMismatches,
+		ReportedAt:    time.Now(),
+	}
+
+	var counts, times []any
+	countsDiffer := false
+	for _, entry := range entries {
+		report.Scouters = append(report.Scouters, entry.Scouter)
+		counts = append(counts, float64(GetNumCycles(entry.Cycles)))
+		times = append(times, GetAvgCycleTimeExclusive(entry.Cycles))
+		countsDiffer = countsDiffer || counts[len(counts)-1] != counts[0]
+	}
+
+	if countsDiffer {
+		report.Discrepancies = append(report.Discrepancies, newDiscrepancy("Num Cycles", "Cycles", float64(match.CycleData.NumCycles), counts, entries))
+	}
+
+	if report.CycleMismatch {
+		average := newDiscrepancy("Avg Cycle Time", "Cycles", match.CycleData.AvgCycleTime, times, entries)
+		if average.Spread > kAllowableSeconds {
+			report.Discrepancies = append(report.Discrepancies, average)
+		}
+	}
+
+	for _, merge := range match.Merges {
+		if len(merge.Dissent) == 0 {
+			continue
+		}
+
+		var values []any
+		for _, entry := range entries {
+			values = append(values, GetField(entry, merge.Path))
+		}
+
+		discrepancy := newDiscrepancy(merge.Column, merge.Path, merge.Value, values, entries)
+		if discrepancy.Outlier == "" && len(merge.Dissent) == 1 {
+			discrepancy.Outlier = merge.Dissent[0].Scouter
+		}
+		report.Discrepancies = append(report.Discrepancies, discrepancy)
+	}
+
+	return report
+}
+
+// Describes a field the scouters of a match disagreed on, from every scouter's value in the same order as entries
+func newDiscrepancy(column string, path string, merged any, values []any, entries []TeamData) Discrepancy {
+	discrepancy := Discrepancy{Column: column, Path: path, Merged: merged, Values: make(map[string]any)}
+
+	mergedNumber, mergedIsNumber := ToNumber(merged)
+	_, mergedIsBool := merged.(bool)
+	low, high, furthest := math.Inf(1), math.Inf(-1), 0.0
+
+	for i, value := range values {
+		discrepancy.Values[entries[i].Scouter] = value
+
+		number, isNumber := ToNumber(value)
+		if !isNumber || !mergedIsNumber || mergedIsBool {
+			continue
+		}
+
+		low, high = math.Min(low, number), math.Max(high, number)
+		if distance := math.Abs(number - mergedNumber); distance > furthest {
+			furthest, discrepancy.Outlier = distance, entries[i].Scouter
+		}
+	}
+
+	if high >= low {
+		discrepancy.Spread = high - low
+	}
+
+	return discrepancy
+}
@@ -1,42 +1,55 @@
 package lib
 
 import (
-	greenlogger "GreenScoutBackend/greenLogger"
-	"errors"
+	"GreenScoutBackend/constants"
 	"fmt"
 	"math"
-
-	"github.com/montanaflynn/stats"
 )
 
 // Utility for merging multiple MatchData instances into data to be written to the spreadsheet when multi-scouting
 
 // Compliled data for an entire match from multiple scouters
 type MultiMatch struct {
-	TeamNumber       uint64             `json:"Team"`  // The team number
-	Match            MatchInfo          `json:"Match"` // The match number
-	Scouters         string             // The scouters who scouted this entry
-	DriverStation    DriverStationData  `json:"Driver Station"` // The driverstation of this entry
-	CycleData        CompositeCycleData // The compiled cycle data from multiple scouters
-	SpeakerPositions SpeakerPositions   // The compiled speaker positions from multiple scouters
-	Pickups          PickupLocations    // The compiled pickup locations from multiple scouters
-	Auto             AutoData           // The compiled auto data from multiple scouters
-	Climb            ClimbingData       // The compiled climbing data from multiple scouters
-	Parked           bool               // If any scouter recorded a park
-	TrapScore        int                // The compiled trap score from multiple scouters
-	Notes            []string           // The compiled notes from multiple scouters
+	TeamNumber    uint64             `json:"Team"`  // The team number
+	Match         MatchInfo          `json:"Match"` // The match number
+	Scouters      string             // The scouters who scouted this entry
+	DriverStation DriverStationData  `json:"Driver Station"` // The driverstation of this entry
+	CycleData     CompositeCycleData // The compiled cycle data from multiple scouters
+	Notes         []string           // The compiled notes from multiple scouters
+	Merges        []MergeResult      // How every field used by a RawData column was merged, and who disagreed
+	Columns       []interface{}      // The merged value of every configured RawData column, in order
 }
 
 // Compiled scouting data from multiple scouters
 type CompositeCycleData struct {
-	NumCycles     int     // The computed number of cycles
-	AvgCycleTime  float64 // The average cycle time
-	AllCycles     []Cycle // All cycles raw
-	HadMismatches bool    // If there were any mismatches
+	NumCycles     int            // The computed number of cycles
+	AvgCycleTime  float64        // The average cycle time
+	Cycles        []Cycle        // The reconciled cycles most scouters recorded, in order
+	Alignment     []AlignedCycle // Every cycle any scouter recorded, aligned across scouters, with its confidence
+	AllCycles     []Cycle        // All cycles raw
+	HadMismatches bool           // If there were any mismatches
+}
+
+// The merged value of one field from multiple scouters
+type MergeResult struct {
+	Column   string    // The header of the column the field was merged for
+	Path     string    // The path to the field
+	Strategy string    // The merge strategy used, after defaults
+	Value    any       // The merged value
+	Dissent  []Dissent // The scouters whose values disagreed with the merged value
 }
 
-// Compiles Teamdata entries into one MultiMatch
-func CompileMultiMatch(entries ...TeamData) MultiMatch {
+// One scouter's value that disagreed with the merged value
+type Dissent struct {
+	Scouter string // The scouter
+	Value   any    // What they recorded
+}
+
+// How reliable each scouter is, from 0 to 1, by username. Used by the weighted merge strategy and to break ties.
+type ScouterWeights map[string]float64
+
+// Compiles Teamdata entries into one MultiMatch, weighing each scouter by their reliability
+func CompileMultiMatch(weights ScouterWeights, entries ...TeamData) MultiMatch {
 	var finalData MultiMatch
 
 	teamNum, _ := compositeTeamNum(entries)
@@ -51,240 +64,223 @@ func CompileMultiMatch(entries ...TeamData) MultiMatch {
 
 	finalData.CycleData = compileCycles(entries)
 
-	finalData.SpeakerPositions = compileSpeakerPositions(entries)
-
-	finalData.Pickups = compilePickupPositions(entries)
-
-	finalData.Auto = compileAutoData(entries)
-
-	finalData.Climb = compileClimbData(entries)
+	var mismatches []string
+	if finalData.CycleData.HadMismatches {
+		mismatches = append(mismatches, "CYCLES DISAGREE")
+	}
 
-	finalData.Parked = compileParked(entries)
+	finalData.Notes = compileNotes(entries, mismatches)
 
-	finalData.TrapScore = compileTrapScore(entries)
+	finalData.Merges = compileMerges(entries, entryWeights(weights, entries))
 
-	finalData.Notes = compileNotes(entries, nil)
+	finalData.Columns = compileColumns(finalData, entries)
 
 	return finalData
 }
 
-// Compiles the team number of all entries passed in. Always returns the first team number, as well as wether or not there were any mismatches
-func compositeTeamNum(entries []TeamData) (int, bool) {
-	initial := entries[0].TeamNumber
-
-	for i := 1; i < len(entries); i++ {
-		if initial != entries[i].TeamNumber {
-			return int(initial), true
+// Returns the weight of the scouter of every entry. Scouters without a known reliability are weighed as the average of
+// those with one, or 1 if none have one.
+func entryWeights(weights ScouterWeights, entries []TeamData) []float64 {
+	var knownSum float64
+	var known int
+	for _, entry := range entries {
+		if weight, found := weights[entry.Scouter]; found {
+			knownSum += weight
+			known++
 		}
 	}
 
-	return int(initial), false
-}
+	unknown := 1.0
+	if known > 0 {
+		unknown = knownSum / float64(known)
+	}
 
-// Compiles the scouter names from all matches
-func compositeScouters(entries []TeamData) string {
-	var finalScouter string
-	for _, entry := range entries {
-		finalScouter += fmt.Sprintf(", %s", entry.Scouter)
+	results := make([]float64, len(entries))
+	for i, entry := range entries {
+		results[i] = unknown
+		if weight, found := weights[entry.Scouter]; found {
+			results[i] = weight
+		}
 	}
+	return results
+}
 
-	return finalScouter
+// A field used by a column, and how it's merged
+type columnField struct {
+	path  string // The path to the field
+	merge string // The configured merge strategy
 }
 
-// Compiles the cycle data from all matches into one CompositeCycleData
-func compileCycles(entries []TeamData) CompositeCycleData {
-	var finalCycles CompositeCycleData
-	var allNumCycles []int
-	for _, entry := range entries {
-		allNumCycles = append(allNumCycles, GetNumCycles(entry.Cycles))
+// Returns every field a column looks up
+func columnFields(column constants.ColumnConfig) []columnField {
+	var fields []columnField
+	if column.Condition != nil {
+		fields = append(fields, columnField{column.Condition.Path, ""})
 	}
 
-	for _, cycleNum := range allNumCycles {
-		if cycleNum != allNumCycles[0] {
-			finalCycles.HadMismatches = true
+	switch column.Source {
+	case SourceField:
+		fields = append(fields, columnField{column.Path, column.Merge})
+	case SourceAccuracy:
+		fields = append(fields, columnField{column.Made, column.Merge}, columnField{column.Missed, column.Merge})
+	case SourceFlags:
+		for _, flag := range column.Flags {
+			fields = append(fields, columnField{flag.Path, column.Merge})
 		}
 	}
+	return fields
+}
 
-	cycleCompositeTime, hadMismatches := avgCycleTimes(entries)
-
-	finalCycles.AvgCycleTime = cycleCompositeTime
-
-	if hadMismatches {
-		finalCycles.HadMismatches = true
-	}
-
-	var massiveBlockOfCycles []Cycle
+// Merges every field used by the configured RawData columns, recording the strategy used and every scouter who disagreed
+func compileMerges(entries []TeamData, weights []float64) []MergeResult {
+	var fieldMaps []map[string]any
 	for _, entry := range entries {
-		massiveBlockOfCycles = append(massiveBlockOfCycles, entry.Cycles...)
+		fieldMaps = append(fieldMaps, asFieldMap(entry))
 	}
 
-	finalCycles.AllCycles = massiveBlockOfCycles
+	var results []MergeResult
+	merged := make(map[columnField]bool)
+	for _, column := range constants.CachedGameConfig.Columns {
+		within := column.DissentWithin
+		if within <= 0 {
+			within = DefaultDissentWithin
+		}
 
-	return finalCycles
-}
+		for _, field := range columnFields(column) {
+			if merged[field] {
+				continue
+			}
+			merged[field] = true
 
-// Averages out the cycle times from all entries, returning this average as well as if there were any times that were outside
-// of the configured acceptable range
-func avgCycleTimes(entries []TeamData) (float64, bool) {
-	var sum float64
-	var count int = 0
+			var values []any
+			for _, fieldMap := range fieldMaps {
+				values = append(values, LookupField(fieldMap, field.path))
+			}
 
-	var allCycles [][]Cycle
+			result := MergeResult{Column: column.Header, Path: field.path, Strategy: resolveStrategy(field.merge, values)}
+			result.Value = MergeWeightedValues(result.Strategy, values, weights)
 
-	for _, entry := range entries {
-		allCycles = append(allCycles, entry.Cycles)
-		entryAvg := GetAvgCycleTimeExclusive(entry.Cycles)
-		if entryAvg != 0 {
-			sum += entryAvg
-			count++
+			for i, value := range values {
+				if dissents(value, result.Value, within) {
+					result.Dissent = append(result.Dissent, Dissent{Scouter: entries[i].Scouter, Value: value})
+				}
+			}
+
+			results = append(results, result)
 		}
 	}
 
-	finalAvg := sum / float64(count)
-
-	if math.IsNaN(finalAvg) {
-		finalAvg = 0
-	}
-	return finalAvg, !CompareCycles(allCycles)
+	return results
 }
 
-// Combines the speaker positions from all entries
-func compileSpeakerPositions(entries []TeamData) SpeakerPositions {
-	var sides bool = false
-	var middle bool = false
-
+// Merges every configured RawData column from all entries, according to each column's merge strategy
+func compileColumns(match MultiMatch, entries []TeamData) []interface{} {
+	var fieldMaps []map[string]any
 	for _, entry := range entries {
-		if entry.Positions.Sides {
-			sides = true
-		}
-
-		if entry.Positions.Middle {
-			middle = true
-		}
+		fieldMaps = append(fieldMaps, asFieldMap(entry))
 	}
 
-	return SpeakerPositions{
-		Sides:  sides,
-		Middle: middle,
+	merges := make(map[columnField]any)
+	for _, result := range match.Merges {
+		merges[columnField{result.Path, result.Strategy}] = result.Value
 	}
-}
 
-// Combines the pickup locations from all entries
-func compilePickupPositions(entries []TeamData) PickupLocations {
-	var ground bool = false
-	var source bool = false
-
-	for _, entry := range entries {
-		if entry.Pickups.Ground {
-			ground = true
-		}
-
-		if entry.Pickups.Source {
-			source = true
-		}
+	inputs := columnInputs{
+		field: func(path string, merge string) any {
+			var values []any
+			for _, fieldMap := range fieldMaps {
+				values = append(values, LookupField(fieldMap, path))
+			}
+
+			if value, found := merges[columnField{path, resolveStrategy(merge, values)}]; found {
+				return value
+			}
+			return MergeValues(merge, values)
+		},
+		team:            match.TeamNumber,
+		cycles:          match.CycleData.Cycles,
+		numCycles:       match.CycleData.NumCycles,
+		avgCycleTime:    match.CycleData.AvgCycleTime,
+		cycleConfidence: cycleConfidence(match.CycleData.Alignment),
+		notes:           CompileNotes2(match, entries),
 	}
 
-	return PickupLocations{
-		Ground: ground,
-		Source: source,
+	var row []interface{}
+	for _, column := range constants.CachedGameConfig.Columns {
+		row = append(row, columnValue(column, inputs))
 	}
+	return row
 }
 
-// Compiles autonomous data from all entries
-func compileAutoData(entries []TeamData) AutoData {
-	// No need to mess with return values if err, as the NaNs do that well enough.
-
-	var can bool = false
-	var allScores []float64
-	var allMisses []float64
-	var allEjects []float64
+// Compiles the team number of all entries passed in. Always returns the first team number, as well as wether or not there were any mismatches
+func compositeTeamNum(entries []TeamData) (int, bool) {
+	initial := entries[0].TeamNumber
 
-	for _, entry := range entries {
-		if entry.Auto.Can {
-			can = true
+	for i := 1; i < len(entries); i++ {
+		if initial != entries[i].TeamNumber {
+			return int(initial), true
 		}
-
-		allScores = append(allScores, float64(entry.Auto.Scores))
-		allMisses = append(allMisses, float64(entry.Auto.Misses))
-		allEjects = append(allEjects, float64(entry.Auto.Ejects))
-	}
-
-	scoresAvgd, scoresMeanErr := stats.Mean(allScores)
-	if scoresMeanErr != nil {
-		greenlogger.LogErrorf(scoresMeanErr, "Error finding mean of %v for all scores", allScores)
 	}
 
-	missesAvgd, missesMeanErr := stats.Mean(allMisses)
-	if missesMeanErr != nil {
-		greenlogger.LogErrorf(missesMeanErr, "Error finding mean of %v for all misses", allMisses)
-	}
+	return int(initial), false
+}
 
-	ejectsAvgd, ejectsMeanErr := stats.Mean(allEjects)
-	if ejectsMeanErr != nil {
-		greenlogger.LogErrorf(ejectsMeanErr, "Error finding mean of %v for all ejects", allEjects)
+// Compiles the scouter names from all matches
+func compositeScouters(entries []TeamData) string {
+	var finalScouter string
+	for _, entry := range entries {
+		finalScouter += fmt.Sprintf(", %s", entry.Scouter)
 	}
 
-	return AutoData{
-		Can:    can,
-		Scores: int(scoresAvgd),
-		Misses: int(missesAvgd),
-		Ejects: int(ejectsAvgd),
-	}
+	return finalScouter
 }
 
-// Compiles climbing data from all entries
-func compileClimbData(entries []TeamData) ClimbingData {
-	var success bool = false
-	var times []float64
-
+// Compiles the cycle data from all matches into one CompositeCycleData, aligning the scouters' cycles into one reconciled timeline.
+// The cycles count as mismatched if the scouters recorded different numbers of them or average cycle times, or their average
+// confidence is below the configured minimum.
+func compileCycles(entries []TeamData) CompositeCycleData {
+	var finalCycles CompositeCycleData
+	var allNumCycles []int
+	var scouted [][]Cycle
 	for _, entry := range entries {
-		if entry.Climb.Succeeded {
-			success = true
-		}
-
-		if entry.Climb.Time > 0 {
-			times = append(times, entry.Climb.Time)
-		}
+		allNumCycles = append(allNumCycles, GetNumCycles(entry.Cycles))
+		scouted = append(scouted, entry.Cycles)
+		finalCycles.AllCycles = append(finalCycles.AllCycles, entry.Cycles...)
 	}
-	timeAvgd, err := stats.Mean(times)
 
-	if err != nil {
-		if errors.Is(err, stats.EmptyInput) {
-			timeAvgd = 0
-		} else {
-			greenlogger.LogErrorf(err, "Error finding mean of %v for climb times", times)
+	for _, cycleNum := range allNumCycles {
+		if cycleNum != allNumCycles[0] {
+			finalCycles.HadMismatches = true
 		}
 	}
 
-	return ClimbingData{
-		Succeeded: success,
-		Time:      timeAvgd,
+	finalCycles.Alignment = AlignCycles(scouted)
+	finalCycles.Cycles = ReconciledCycles(finalCycles.Alignment, len(entries))
+	finalCycles.NumCycles = GetNumCycles(finalCycles.Cycles)
+	finalCycles.AvgCycleTime = GetAvgCycleTimeExclusive(finalCycles.Cycles)
+
+	if !CompareCycles(scouted) {
+		finalCycles.HadMismatches = true
 	}
-}
 
-// Returns if any scouter recorded a park
-func compileParked(entries []TeamData) bool {
-	for _, entry := range entries {
-		if entry.Misc.Parked {
-			return true
-		}
+	if cycleConfidence(finalCycles.Alignment) < constants.CachedConfigs.MultiScoutConfigs.MinCycleConfidence {
+		finalCycles.HadMismatches = true
 	}
-	return false
+
+	return finalCycles
 }
 
-// Averages out the trap scores from all scouters
-func compileTrapScore(entries []TeamData) int {
-	var trapScores []float64
-	for _, entry := range entries {
-		trapScores = append(trapScores, float64(entry.Trap.Score))
+// Returns the mean confidence of aligned cycles as a percentage, or 100 if there are none
+func cycleConfidence(aligned []AlignedCycle) float64 {
+	if len(aligned) == 0 {
+		return 100
 	}
 
-	trapAvgd, err := stats.Mean(trapScores)
-	if err != nil {
-		greenlogger.LogErrorf(err, "Error finding mean of %v for trap scores", trapScores)
+	var sum float64
+	for _, cycle := range aligned {
+		sum += cycle.Confidence
 	}
-
-	return int(math.Round(trapAvgd))
+	return math.Round(sum/float64(len(aligned))*10000) / 100
 }
 
 // Combines the notes from all passed in scouters
@@ -292,7 +288,6 @@ func compileNotes(entries []TeamData, mismatches []string) []string {
 	var finalNotes []string
 	for _, entry := range entries {
 		finalNotes = append(finalNotes, entry.Notes)
-		finalNotes = append(finalNotes, mismatches...)
 	}
-	return finalNotes
+	return append(finalNotes, mismatches...)
 }
@@ -0,0 +1,589 @@
+package lib
+
+// Utility for deriving spreadsheet values from the game definition in constants.CachedGameConfig
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Column sources, as used in the Source of a constants.ColumnConfig
+const (
+	SourceTeam            = "team"            // The team number
+	SourceField           = "field"           // The value of the field at Path
+	SourceCycleCount      = "cycleCount"      // The number of cycles
+	SourceCycleAverage    = "cycleAverage"    // The average cycle time
+	SourceCycleTendency   = "cycleTendency"   // The percentage of cycles that were of CycleType
+	SourceCycleAccuracy   = "cycleAccuracy"   // The percentage of cycles of CycleType that succeeded
+	SourceCycleConfidence = "cycleConfidence" // The percentage of cycles every scouter agreed on, on average. Always 100 for a single scouter
+	SourceAccuracy        = "accuracy"        // The percentage Made / (Made + Missed)
+	SourceFlags           = "flags"           // The labels of every set flag, AllLabel if all are set and NoneLabel if none are
+	SourceNotes           = "notes"           // The compiled notes
+)
+
+// Merge strategies, as used in the Merge of a constants.ColumnConfig.
+// If none is configured, booleans use MergeAny, numbers use MergeMean, and everything else uses MergeFirst.
+const (
+	MergeMean        = "mean"        // The mean of all values
+	MergeRound       = "round"       // The mean of all values, rounded to the nearest integer
+	MergeFloor       = "floor"       // The mean of all values, truncated to an integer
+	MergeMeanNonZero = "meanNonZero" // The mean of all values that aren't 0, or 0 if there are none
+	MergeAny         = "any"         // True if any value is true
+	MergeAll         = "all"         // True if every value is true
+	MergeFirst       = "first"       // The first value that exists
+	MergeMajority    = "majority"    // The most common value, with ties going to the more reliable scouters, then the first value
+	MergeMedian      = "median"      // The median of all values
+	MergeWeighted    = "weighted"    // The mean of all values weighted by each scouter's reliability, or a weighted vote for anything that isn't a number
+	MergeTrimmedMean = "trimmedMean" // The mean of all values, leaving out the highest and lowest when there are at least 3
+)
+
+// How far a number can be from its merged value without being recorded as dissent, unless a column configures its own
+const DefaultDissentWithin = 1.0
+
+// Format names, as used in the Format of a constants.ColumnConfig
+const (
+	FormatInteger = "integer" // Truncates numbers to integers
+)
+
+// Returns the json keys of every field of a struct type
+func jsonKeys(structType reflect.Type) []string {
+	var keys []string
+	for i := 0; i < structType.NumField(); i++ {
+		tag := strings.Split(structType.Field(i).Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = structType.Field(i).Name
+		}
+		keys = append(keys, tag)
+	}
+	return keys
+}
+
+// Decodes every key of a json object that isn't one of the passed in struct type's fields.
+// Returns nil if there are none.
+func decodeExtra(data []byte, structType reflect.Type) (map[string]any, error) {
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	for _, key := range jsonKeys(structType) {
+		delete(all, key)
+	}
+
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return all, nil
+}
+
+// Adds the extra keys to an already marshalled json object
+func encodeExtra(encoded []byte, extra map[string]any) ([]byte, error) {
+	if len(extra) == 0 {
+		return encoded, nil
+	}
+
+	var all map[string]any
+	if err := json.Unmarshal(encoded, &all); err != nil {
+		return nil, err
+	}
+
+	for key, value := range extra {
+		if _, exists := all[key]; !exists {
+			all[key] = value
+		}
+	}
+
+	return json.Marshal(all)
+}
+
+// Decodes TeamData, keeping any fields it doesn't know about in Extra
+func (team *TeamData) UnmarshalJSON(data []byte) error {
+	type plain TeamData
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	extra, err := decodeExtra(data, reflect.TypeOf(decoded))
+	if err != nil {
+		return err
+	}
+
+	*team = TeamData(decoded)
+	team.Extra = extra
+	return nil
+}
+
+// Encodes TeamData, including any fields kept in Extra
+func (team TeamData) MarshalJSON() ([]byte, error) {
+	type plain TeamData
+	encoded, err := json.Marshal(plain(team))
+	if err != nil {
+		return nil, err
+	}
+	return encodeExtra(encoded, team.Extra)
+}
+
+// Decodes PitScoutingData, keeping any fields it doesn't know about in Extra
+func (pit *PitScoutingData) UnmarshalJSON(data []byte) error {
+	type plain PitScoutingData
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	extra, err := decodeExtra(data, reflect.TypeOf(decoded))
+	if err != nil {
+		return err
+	}
+
+	*pit = PitScoutingData(decoded)
+	pit.Extra = extra
+	return nil
+}
+
+// Encodes PitScoutingData, including any fields kept in Extra
+func (pit PitScoutingData) MarshalJSON() ([]byte, error) {
+	type plain PitScoutingData
+	encoded, err := json.Marshal(plain(pit))
+	if err != nil {
+		return nil, err
+	}
+	return encodeExtra(encoded, pit.Extra)
+}
+
+// Converts anything json-encodable into a generic json object, so its fields can be looked up by path
+func asFieldMap(data any) map[string]any {
+	var values map[string]any
+
+	encoded, marshalErr := json.Marshal(data)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", data)
+		return values
+	}
+
+	if unmarshalErr := json.Unmarshal(encoded, &values); unmarshalErr != nil {
+		greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", string(encoded))
+	}
+
+	return values
+}
+
+// Looks up the field at a path of json keys separated by dots (ex. "Auto.Scores"), returning nil if it doesn't exist
+func LookupField(values map[string]any, path string) any {
+	var current any = values
+	for _, key := range strings.Split(path, ".") {
+		asMap, isMap := current.(map[string]any)
+		if !isMap {
+			return nil
+		}
+		current = asMap[key]
+	}
+	return current
+}
+
+// Looks up a field of one scouter's match data by path
+func GetField(team TeamData, path string) any {
+	return LookupField(asFieldMap(team), path)
+}
+
+// Converts a json value into a number, treating booleans as 1 or 0. Returns false if it isn't numeric.
+func ToNumber(value any) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case int:
+		return float64(typed), true
+	case uint64:
+		return float64(typed), true
+	case bool:
+		if typed {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// Returns if a json value is set, meaning true, non-zero, or non-empty
+func isTruthy(value any) bool {
+	switch typed := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return typed
+	case string:
+		return typed != ""
+	}
+	number, isNumber := ToNumber(value)
+	return !isNumber || number != 0
+}
+
+// Returns the merge strategy used for values, picking the default for their type if none is configured
+func resolveStrategy(strategy string, values []any) string {
+	if strategy != "" || len(values) == 0 {
+		return strategy
+	}
+
+	switch values[0].(type) {
+	case bool:
+		return MergeAny
+	case float64:
+		return MergeMean
+	}
+	return MergeFirst
+}
+
+// Merges the values from multiple scouters according to a merge strategy, weighing every scouter equally
+func MergeValues(strategy string, values []any) any {
+	return MergeWeightedValues(strategy, values, nil)
+}
+
+// Merges the values from multiple scouters according to a merge strategy.
+// Weights are the reliability of the scouter of each value, used by MergeWeighted and to break ties; nil weighs every scouter equally.
+func MergeWeightedValues(strategy string, values []any, weights []float64) any {
+	if len(values) == 0 {
+		return nil
+	}
+
+	strategy = resolveStrategy(strategy, values)
+
+	switch strategy {
+	case MergeAny:
+		for _, value := range values {
+			if isTruthy(value) {
+				return true
+			}
+		}
+		return false
+
+	case MergeAll:
+		for _, value := range values {
+			if !isTruthy(value) {
+				return false
+			}
+		}
+		return true
+
+	case MergeFirst:
+		for _, value := range values {
+			if value != nil {
+				return value
+			}
+		}
+		return nil
+
+	case MergeMean, MergeRound, MergeFloor, MergeMeanNonZero:
+		var sum float64
+		var count int
+		for _, value := range values {
+			number, isNumber := ToNumber(value)
+			if !isNumber || (strategy == MergeMeanNonZero && number <= 0) {
+				continue
+			}
+			sum += number
+			count++
+		}
+
+		if count == 0 {
+			return 0
+		}
+
+		mean := sum / float64(count)
+		switch strategy {
+		case MergeRound:
+			return int(math.Round(mean))
+		case MergeFloor:
+			return int(mean)
+		}
+		return mean
+
+	case MergeMajority:
+		return vote(values, weights, false)
+
+	case MergeMedian, MergeTrimmedMean:
+		var numbers []float64
+		for _, value := range values {
+			if number, isNumber := ToNumber(value); isNumber {
+				numbers = append(numbers, number)
+			}
+		}
+
+		if len(numbers) == 0 {
+			return 0
+		}
+		slices.Sort(numbers)
+
+		if strategy == MergeMedian {
+			middle := len(numbers) / 2
+			if len(numbers)%2 == 0 {
+				return (numbers[middle-1] + numbers[middle]) / 2
+			}
+			return numbers[middle]
+		}
+
+		if len(numbers) >= 3 {
+			numbers = numbers[1 : len(numbers)-1]
+		}
+
+		var sum float64
+		for _, number := range numbers {
+			sum += number
+		}
+		return sum / float64(len(numbers))
+
+	case MergeWeighted:
+		if _, isBool := values[0].(bool); isBool {
+			return vote(values, weights, true)
+		}
+
+		var sum, totalWeight float64
+		for i, value := range values {
+			if number, isNumber := ToNumber(value); isNumber {
+				sum += number * weightOf(weights, i)
+				totalWeight += weightOf(weights, i)
+			}
+		}
+
+		if totalWeight == 0 {
+			return MergeValues(MergeMean, values)
+		}
+		return sum / totalWeight
+
+	default:
+		greenlogger.LogMessagef("Unknown merge strategy %v, using the first value", strategy)
+		return MergeValues(MergeFirst, values)
+	}
+}
+
+// Returns the weight of the scouter of the value at an index, 1 if there are no weights
+func weightOf(weights []float64, index int) float64 {
+	if index >= len(weights) {
+		return 1
+	}
+	return weights[index]
+}
+
+// Returns the value with the most votes. Each value is one vote, or its scouter's weight if byWeight is set.
+// Ties go to the value with the most total weight, then the value that appears first.
+func vote(values []any, weights []float64, byWeight bool) any {
+	var candidates []any
+	votes := make(map[string]float64)
+	support := make(map[string]float64)
+
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+
+		key := fmt.Sprint(value)
+		if _, seen := votes[key]; !seen {
+			candidates = append(candidates, value)
+		}
+
+		if byWeight {
+			votes[key] += weightOf(weights, i)
+		} else {
+			votes[key]++
+		}
+		support[key] += weightOf(weights, i)
+	}
+
+	var winner any
+	for _, candidate := range candidates {
+		key, winnerKey := fmt.Sprint(candidate), fmt.Sprint(winner)
+		if winner == nil || votes[key] > votes[winnerKey] || (votes[key] == votes[winnerKey] && support[key] > support[winnerKey]) {
+			winner = candidate
+		}
+	}
+	return winner
+}
+
+// Returns if a scouter's value disagrees with the merged value. Numbers disagree when they're further apart than within.
+func dissents(value any, merged any, within float64) bool {
+	if mergedBool, isBool := merged.(bool); isBool {
+		return isTruthy(value) != mergedBool
+	}
+
+	mergedNumber, mergedIsNumber := ToNumber(merged)
+	number, isNumber := ToNumber(value)
+	if mergedIsNumber && isNumber {
+		return math.Abs(number-mergedNumber) > within
+	}
+
+	return fmt.Sprint(value) != fmt.Sprint(merged)
+}
+
+// Everything a column's value can be derived from, whether it came from one scouter or many
+type columnInputs struct {
+	field           func(path string, merge string) any // Looks up (and merges, if needed) a field
+	team            any                                 // The team number
+	cycles          []Cycle                             // The cycles
+	numCycles       any                                 // The number of cycles
+	avgCycleTime    any                                 // The average cycle time
+	cycleConfidence float64                             // The mean confidence of the cycles, as a percentage
+	notes           string                              // The compiled notes
+}
+
+// Computes the value of one column from its inputs
+func columnValue(column constants.ColumnConfig, inputs columnInputs) any {
+	if column.Condition != nil {
+		actual := inputs.field(column.Condition.Path, "")
+		if fmt.Sprint(actual) != fmt.Sprint(column.Condition.Equals) {
+			return "N/A"
+		}
+	}
+
+	var value any
+	switch column.Source {
+	case SourceTeam:
+		value = inputs.team
+	case SourceField:
+		value = inputs.field(column.Path, column.Merge)
+	case SourceCycleCount:
+		value = inputs.numCycles
+	case SourceCycleAverage:
+		value = inputs.avgCycleTime
+	case SourceCycleTendency:
+		value = math.Round(GetCycleTendency(inputs.cycles, column.CycleType)*10000) / 100
+	case SourceCycleAccuracy:
+		value = GetCycleTypeAccuracy(inputs.cycles, column.CycleType)
+	case SourceCycleConfidence:
+		value = inputs.cycleConfidence
+	case SourceAccuracy:
+		value = GetAccuracy(inputs.field(column.Made, column.Merge), inputs.field(column.Missed, column.Merge))
+	case SourceFlags:
+		value = flagsLabel(column, inputs)
+	case SourceNotes:
+		value = inputs.notes
+	default:
+		greenlogger.LogMessagef("Unknown column source %v for column %v", column.Source, column.Header)
+		value = "N/A"
+	}
+
+	return formatValue(column.Format, value)
+}
+
+// Applies a column format to a value
+func formatValue(format string, value any) any {
+	switch format {
+	case "":
+		return value
+	case FormatInteger:
+		if number, isNumber := ToNumber(value); isNumber {
+			return int(number)
+		}
+		return value
+	}
+
+	greenlogger.LogMessagef("Unknown column format %v", format)
+	return value
+}
+
+// Calculates the label of a flags column
+func flagsLabel(column constants.ColumnConfig, inputs columnInputs) string {
+	var labels []string
+	for _, flag := range column.Flags {
+		if isTruthy(inputs.field(flag.Path, column.Merge)) {
+			labels = append(labels, flag.Label)
+		}
+	}
+
+	if len(labels) == 0 {
+		return column.NoneLabel
+	}
+
+	if len(labels) == len(column.Flags) && column.AllLabel != "" {
+		return column.AllLabel
+	}
+
+	return strings.Join(labels, "/")
+}
+
+// Calculates Made / (Made + Missed) as a percentage, returning N/A if nothing was attempted
+func GetAccuracy(made any, missed any) any {
+	madeNum, _ := ToNumber(made)
+	missedNum, _ := ToNumber(missed)
+
+	if madeNum+missedNum == 0 {
+		return "N/A"
+	}
+	return (madeNum / (madeNum + missedNum)) * 100
+}
+
+// Computes every configured RawData column for one scouter's match data
+func GetRowValues(team TeamData) []interface{} {
+	values := asFieldMap(team)
+
+	inputs := columnInputs{
+		field:           func(path string, _ string) any { return LookupField(values, path) },
+		team:            team.TeamNumber,
+		cycles:          team.Cycles,
+		numCycles:       GetNumCycles(team.Cycles),
+		avgCycleTime:    GetAvgCycleTime(team.Cycles),
+		cycleConfidence: 100,
+		notes:           CompileNotes(team),
+	}
+
+	var row []interface{}
+	for _, column := range constants.CachedGameConfig.Columns {
+		row = append(row, columnValue(column, inputs))
+	}
+	return row
+}
+
+// Computes every configured PitScouting column for pit scouting data
+func GetPitRowValues(pit PitScoutingData) []interface{} {
+	values := asFieldMap(pit)
+
+	inputs := columnInputs{
+		field: func(path string, _ string) any { return LookupField(values, path) },
+		team:  pit.TeamNumber,
+		notes: pit.Notes,
+	}
+
+	var row []interface{}
+	for _, column := range constants.CachedGameConfig.PitColumns {
+		row = append(row, columnValue(column, inputs))
+	}
+	return row
+}
+
+// Returns the points a list of cycles is worth, counting every successful cycle at its type's configured points
+func CyclePoints(cycles []Cycle) float64 {
+	if !cyclesAreValid(cycles) {
+		return 0
+	}
+
+	var points float64
+	for _, cycle := range cycles {
+		for _, cycleType := range constants.CachedGameConfig.CycleTypes {
+			if cycle.Success && cycle.Type == cycleType.Name {
+				points += cycleType.Points
+			}
+		}
+	}
+	return points
+}
+
+// Returns the points of everything in a submission recorded outside of cycles, by the configured scoring actions and endgame options
+func ActionPoints(team TeamData) float64 {
+	values := asFieldMap(team)
+
+	var points float64
+	for _, action := range slices.Concat(constants.CachedGameConfig.ScoringActions, constants.CachedGameConfig.EndgameOptions) {
+		if count, isNumber := ToNumber(LookupField(values, action.Path)); isNumber {
+			points += count * action.Points
+		}
+	}
+	return points
+}
@@ -13,22 +13,26 @@ import (
 	"strings"
 )
 
-// Data from one scouter from one match
+// Data from one scouter from one match.
+// The typed fields are what the app currently sends; fields it sends that aren't listed here are kept in Extra,
+// so the game config can refer to them without this struct changing every season.
 type TeamData struct {
-	TeamNumber    uint64            `json:"Team"`              // The team number
-	Match         MatchInfo         `json:"Match"`             // The match number
-	Scouter       string            `json:"Scouter"`           // The scouter who recorded this data
-	DriverStation DriverStationData `json:"Driver Station"`    // The driver station
-	Cycles        []Cycle           `json:"Cycles"`            // The cycle data
-	Positions     SpeakerPositions  `json:"Speaker Positions"` // The recorded speaker positions
-	Pickups       PickupLocations   `json:"Pickup Locations"`  // The recorded speaker locations
-	Auto          AutoData          `json:"Auto"`              // The autonomous data
-	Climb         ClimbingData      `json:"Climbing"`          // The recorded climbing data
-	Trap          TrapData          `json:"Trap"`              // The recorded trap data
-	Misc          MiscData          `json:"Misc"`              // Miscellaneous data
-	Penalties     []string          `json:"Penalties"`         // Recorded penalties
-	Rescouting    bool              `json:"Rescouting"`        // If this match is rescouting (Will override all previous data of this match with this driverstation)
-	Notes         string            `json:"Notes"`             // Notes from the scouter
+	TeamNumber    uint64            `json:"Team"`               // The team number
+	Match         MatchInfo         `json:"Match"`              // The match number
+	Scouter       string            `json:"Scouter"`            // The scouter who recorded this data
+	DriverStation DriverStationData `json:"Driver Station"`     // The driver station
+	Cycles        []Cycle           `json:"Cycles"`             // The cycle data
+	Positions     SpeakerPositions  `json:"Speaker Positions"`  // The recorded speaker positions
+	Pickups       PickupLocations   `json:"Pickup Locations"`   // The recorded speaker locations
+	Auto          AutoData          `json:"Auto"`               // The autonomous data
+	Climb         ClimbingData      `json:"Climbing"`           // The recorded climbing data
+	Trap          TrapData          `json:"Trap"`               // The recorded trap data
+	Misc          MiscData          `json:"Misc"`               // Miscellaneous data
+	Penalties     []string          `json:"Penalties"`          // Recorded penalties
+	Rescouting    bool              `json:"Rescouting"`         // If this match is rescouting (Will override all previous data of this match with this driverstation)
+	Notes         string            `json:"Notes"`              // Notes from the scouter
+	Warnings      []string          `json:"Warnings,omitempty"` // Problems the server found checking it against the schedule, set when it is submitted
+	Extra         map[string]any    `json:"-"`                  // Any other fields sent by the app
 }
 
 // Basic info about the match
@@ -230,6 +234,8 @@ type PitScoutingData struct {
 	ClimbTime       float64 `json:"Climb Time"`       // How long does it take for this robot to climb?
 
 	Notes string `json:"Notes"` // Other notes
+
+	Extra map[string]any `json:"-"` // Any other fields sent by the app
 }
 
 // Pit scouting data regarding distance shooting
@@ -245,9 +251,15 @@ type HumanPlayerData struct {
 }
 
 // Parses through the file at the passed in location, returning a compiled PitScoutingData object and wether or not there were errors.
-func ParsePitScout(file string) (PitScoutingData, bool) {
+// Params: The filepath, if it has already been written (for backfilling stored data)
+func ParsePitScout(file string, hasBeenWritten bool) (PitScoutingData, bool) {
 
-	path := filepath.Join(constants.JsonInDirectory, file)
+	var path string
+	if hasBeenWritten {
+		path = filepath.Join(constants.JsonPitWrittenDirectory, file)
+	} else {
+		path = filepath.Join(constants.JsonInDirectory, file)
+	}
 
 	// Open file
 	jsonFile, fileErr := os.Open(path)
@@ -0,0 +1,180 @@
+package lib
+
+// Utility for predicting the outcome of matches by simulating them from every robot's scouted performances
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/montanaflynn/stats"
+)
+
+// The number of scouted matches a team needs before its own performances are simulated instead of its OPR
+const kMinPredictionSamples = 3
+
+// Who won a match, or is predicted to
+const (
+	WinnerRed  = "red"
+	WinnerBlue = "blue"
+	WinnerTie  = "tie"
+)
+
+// The predicted score of one alliance
+type ScorePrediction struct {
+	Mean float64 // The mean simulated score
+	Low  float64 // The 10th percentile of simulated scores
+	High float64 // The 90th percentile of simulated scores
+}
+
+// The prediction of one match, and how it turned out once played
+type Prediction struct {
+	Event              string          // The event key
+	Match              int             // The qualification match number
+	Red                []int           // The teams on the red alliance
+	Blue               []int           // The teams on the blue alliance
+	RedScore           ScorePrediction // The predicted score of the red alliance
+	BlueScore          ScorePrediction // The predicted score of the blue alliance
+	RedWinProbability  float64         // The fraction of simulations red won
+	BlueWinProbability float64         // The fraction of simulations blue won
+	TieProbability     float64         // The fraction of simulations that tied
+	Predicted          string          // The predicted winner
+	Simulations        int             // The number of simulations
+	PredictedAt        time.Time       // When it was predicted
+
+	Played     bool      // If the match has been played and the prediction was checked against it
+	RedActual  int       // The red alliance's actual score
+	BlueActual int       // The blue alliance's actual score
+	Winner     string    // The actual winner
+	Correct    bool      // If the predicted winner won
+	Brier      float64   // The squared difference between the red win probability and the result (1 for a red win, 0.5 for a tie, 0 for a blue win)
+	ScoreError float64   // The mean absolute difference between the predicted and actual scores of both alliances
+	ResolvedAt time.Time // When it was checked
+}
+
+// How accurate the predictions of played matches were
+type PredictionAccuracy struct {
+	Predicted      int     // The number of matches predicted
+	Resolved       int     // The number of predicted matches that have been played
+	Correct        int     // The number of played matches whose winner was predicted
+	WinAccuracy    float64 // The percentage of played matches whose winner was predicted
+	Brier          float64 // The mean Brier score of the red win probability; 0 is perfect and 0.25 is a coin flip
+	MeanScoreError float64 // The mean absolute difference between predicted and actual alliance scores
+}
+
+// Predicts a match by simulating it. Each simulated alliance score is the sum of one performance drawn at random for each of its
+// teams: from the team's scouted points per match if it has at least kMinPredictionSamples of them, otherwise its OPR if known,
+// otherwise whatever it has been scouted for, otherwise from every scouted performance at the event.
+func PredictMatch(event string, match int, red []int, blue []int, samples map[int][]float64, oprs map[int]float64, simulations int, random *rand.Rand) Prediction {
+	prediction := Prediction{Event: event, Match: match, Red: red, Blue: blue, Simulations: simulations, PredictedAt: time.Now()}
+
+	var everyone []float64
+	for _, teamSamples := range samples {
+		everyone = append(everyone, teamSamples...)
+	}
+
+	draws := func(team int) []float64 {
+		teamSamples := samples[team]
+		if opr, found := oprs[team]; len(teamSamples) < kMinPredictionSamples && found {
+			return []float64{opr}
+		}
+		if len(teamSamples) > 0 {
+			return teamSamples
+		}
+		return everyone
+	}
+
+	simulate := func(alliance []int) []float64 {
+		scores := make([]float64, simulations)
+		for _, team := range alliance {
+			teamDraws := draws(team)
+			if len(teamDraws) == 0 {
+				continue
+			}
+			for i := range scores {
+				scores[i] += teamDraws[random.Intn(len(teamDraws))]
+			}
+		}
+		return scores
+	}
+
+	redScores, blueScores := simulate(red), simulate(blue)
+
+	var redWins, blueWins, ties int
+	for i := range redScores {
+		switch redScore, blueScore := math.Round(redScores[i]), math.Round(blueScores[i]); {
+		case redScore > blueScore:
+			redWins++
+		case blueScore > redScore:
+			blueWins++
+		default:
+			ties++
+		}
+	}
+
+	prediction.RedScore = predictScore(redScores)
+	prediction.BlueScore = predictScore(blueScores)
+	prediction.RedWinProbability = float64(redWins) / float64(simulations)
+	prediction.BlueWinProbability = float64(blueWins) / float64(simulations)
+	prediction.TieProbability = float64(ties) / float64(simulations)
+
+	prediction.Predicted = WinnerTie
+	if redWins > blueWins {
+		prediction.Predicted = WinnerRed
+	} else if blueWins > redWins {
+		prediction.Predicted = WinnerBlue
+	}
+
+	return prediction
+}
+
+// Summarizes simulated scores
+func predictScore(scores []float64) ScorePrediction {
+	var prediction ScorePrediction
+	prediction.Mean, _ = stats.Mean(scores)
+	prediction.Low, _ = stats.Percentile(scores, 10)
+	prediction.High, _ = stats.Percentile(scores, 90)
+	return prediction
+}
+
+// Checks a prediction against the actual scores of its match
+func ResolvePrediction(prediction *Prediction, redActual int, blueActual int) {
+	prediction.Played = true
+	prediction.RedActual, prediction.BlueActual = redActual, blueActual
+	prediction.ResolvedAt = time.Now()
+
+	result := 0.5
+	prediction.Winner = WinnerTie
+	if redActual > blueActual {
+		result, prediction.Winner = 1, WinnerRed
+	} else if blueActual > redActual {
+		result, prediction.Winner = 0, WinnerBlue
+	}
+
+	prediction.Correct = prediction.Predicted == prediction.Winner
+	prediction.Brier = math.Pow(prediction.RedWinProbability-result, 2)
+	prediction.ScoreError = (math.Abs(prediction.RedScore.Mean-float64(redActual)) + math.Abs(prediction.BlueScore.Mean-float64(blueActual))) / 2
+}
+
+// Summarizes how accurate the predictions of played matches were
+func SummarizePredictions(predictions []Prediction) PredictionAccuracy {
+	summary := PredictionAccuracy{Predicted: len(predictions)}
+
+	for _, prediction := range slices.DeleteFunc(slices.Clone(predictions), func(prediction Prediction) bool { return !prediction.Played }) {
+		summary.Resolved++
+		if prediction.Correct {
+			summary.Correct++
+		}
+		summary.Brier += prediction.Brier
+		summary.MeanScoreError += prediction.ScoreError
+	}
+
+	if summary.Resolved > 0 {
+		summary.WinAccuracy = percentage(float64(summary.Correct), float64(summary.Resolved))
+		summary.Brier /= float64(summary.Resolved)
+		summary.MeanScoreError /= float64(summary.Resolved)
+	}
+
+	return summary
+}
@@ -0,0 +1,180 @@
+package lib
+
+// Utility for computing OPR, DPR and CCWM from match results with least squares
+
+import (
+	"GreenScoutBackend/tba"
+	"math"
+	"sort"
+	"time"
+)
+
+// Added to the diagonal of the normal equations, so they can still be solved early in an event when some teams' contributions
+// can't be told apart. Small enough to not noticeably change ratings that can be.
+const kRatingRidge = 1e-3
+
+// A team's ratings at an event
+type TeamRating struct {
+	Event      string             // The event key
+	Team       int                // The team number
+	Matches    int                // The number of played qualification matches the ratings are from
+	OPR        float64            // Offensive power rating: the points the team contributes to its alliance's score
+	DPR        float64            // Defensive power rating: the points the team's opponents score against it
+	CCWM       float64            // Calculated contribution to winning margin: the margin the team contributes
+	Components map[string]float64 // The team's contribution to every configured score breakdown field, by field
+	ComputedAt time.Time          // When the ratings were computed
+}
+
+// Computes the ratings of every team that played a qualification match, ordered by OPR, best first.
+// components are score breakdown fields that get a rating each, such as autoPoints.
+func ComputeRatings(event string, matches []tba.Match, components []string) []TeamRating {
+	// Every row is one alliance of one match: the teams on it and what it scored
+	var rows [][]int
+	var scores, opponentScores []float64
+	componentScores := make([][]float64, len(components))
+
+	index := make(map[int]int)
+	var teams []int
+	played := make(map[int]int)
+
+	for _, match := range matches {
+		if match.CompLevel != tba.Qualification || !match.IsPlayed() {
+			continue
+		}
+
+		for _, isBlue := range []bool{true, false} {
+			alliance, opponent := match.Alliances.Red, match.Alliances.Blue
+			breakdown := match.ScoreBreakdown["red"]
+			if isBlue {
+				alliance, opponent = match.Alliances.Blue, match.Alliances.Red
+				breakdown = match.ScoreBreakdown["blue"]
+			}
+
+			var row []int
+			for _, key := range alliance.TeamKeys {
+				team := tba.TeamNumber(key)
+				if _, found := index[team]; !found {
+					index[team] = len(teams)
+					teams = append(teams, team)
+				}
+				row = append(row, index[team])
+				played[team]++
+			}
+
+			rows = append(rows, row)
+			scores = append(scores, float64(alliance.Score))
+			opponentScores = append(opponentScores, float64(opponent.Score))
+			for i, component := range components {
+				value, _ := ToNumber(breakdown[component])
+				componentScores[i] = append(componentScores[i], value)
+			}
+		}
+	}
+
+	if len(teams) == 0 {
+		return nil
+	}
+
+	margins := make([]float64, len(scores))
+	for i := range scores {
+		margins[i] = scores[i] - opponentScores[i]
+	}
+
+	factor := choleskyFactor(normalMatrix(rows, len(teams)))
+	oprs := solveRatings(factor, rows, scores)
+	dprs := solveRatings(factor, rows, opponentScores)
+	ccwms := solveRatings(factor, rows, margins)
+
+	componentRatings := make([][]float64, len(components))
+	for i := range components {
+		componentRatings[i] = solveRatings(factor, rows, componentScores[i])
+	}
+
+	computedAt := time.Now()
+	var results []TeamRating
+	for i, team := range teams {
+		rating := TeamRating{
+			Event:      event,
+			Team:       team,
+			Matches:    played[team],
+			OPR:        oprs[i],
+			DPR:        dprs[i],
+			CCWM:       ccwms[i],
+			Components: make(map[string]float64),
+			ComputedAt: computedAt,
+		}
+		for j, component := range components {
+			rating.Components[component] = componentRatings[j][i]
+		}
+		results = append(results, rating)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].OPR > results[j].OPR })
+	return results
+}
+
+// Returns AᵀA + kRatingRidge·I, where A has a row for every alliance with a 1 for every team on it
+func normalMatrix(rows [][]int, teams int) [][]float64 {
+	matrix := make([][]float64, teams)
+	for i := range matrix {
+		matrix[i] = make([]float64, teams)
+		matrix[i][i] = kRatingRidge
+	}
+
+	for _, row := range rows {
+		for _, i := range row {
+			for _, j := range row {
+				matrix[i][j]++
+			}
+		}
+	}
+	return matrix
+}
+
+// Returns the lower triangular L where LLᵀ is the passed in symmetric positive definite matrix
+func choleskyFactor(matrix [][]float64) [][]float64 {
+	factor := make([][]float64, len(matrix))
+	for i := range matrix {
+		factor[i] = make([]float64, len(matrix))
+		for j := 0; j <= i; j++ {
+			sum := matrix[i][j]
+			for k := 0; k < j; k++ {
+				sum -= factor[i][k] * factor[j][k]
+			}
+
+			if i == j {
+				factor[i][i] = math.Sqrt(math.Max(sum, kRatingRidge))
+			} else {
+				factor[i][j] = sum / factor[j][j]
+			}
+		}
+	}
+	return factor
+}
+
+// Solves the normal equations for the rating of every team, given what every alliance scored
+func solveRatings(factor [][]float64, rows [][]int, scores []float64) []float64 {
+	// Aᵀb
+	solution := make([]float64, len(factor))
+	for r, row := range rows {
+		for _, i := range row {
+			solution[i] += scores[r]
+		}
+	}
+
+	// Forward substitution with L, then back substitution with Lᵀ
+	for i := range solution {
+		for k := 0; k < i; k++ {
+			solution[i] -= factor[i][k] * solution[k]
+		}
+		solution[i] /= factor[i][i]
+	}
+	for i := len(solution) - 1; i >= 0; i-- {
+		for k := i + 1; k < len(solution); k++ {
+			solution[i] -= factor[k][i] * solution[k]
+		}
+		solution[i] /= factor[i][i]
+	}
+
+	return solution
+}
@@ -6,15 +6,16 @@ import (
 	"GreenScoutBackend/constants"
 	filemanager "GreenScoutBackend/fileManager"
 	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/tba"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Simple wrapper for converting bool to string for replays
@@ -70,128 +71,51 @@ func GetCycleAccuracy(cycles []Cycle) any {
 	return "N/A"
 }
 
-// Calculates the tendencies of amp, speaker, shuttling, and distance shooting from an array of cycles.
-// Returns: Tendency to AMP, SPEAKER, DISTANCE, SHUTTLE
-func GetCycleTendencies(cycles []Cycle) (float64, float64, float64, float64) {
+// Calculates the fraction of an array of cycles that were of the passed in type
+func GetCycleTendency(cycles []Cycle, cycleType string) float64 {
 	if len(cycles) < 1 {
-		return 0, 0, 0, 0
+		return 0
 	}
 
-	var numAmps float64
-	var numSpeakers float64
-	var numShuttles float64
-	var numDistances float64
-
-	numCycles := len(cycles)
-
+	var numOfType float64
 	for _, cycle := range cycles {
-		switch cycle.Type {
-		case "Amp":
-			numAmps++
-		case "Speaker":
-			numSpeakers++
-		case "Shuttle":
-			numShuttles++
-		case "Distance":
-			numDistances++
+		if cycle.Type == cycleType {
+			numOfType++
 		}
 	}
 
-	return numAmps / float64(numCycles),
-		numSpeakers / float64(numCycles),
-		numDistances / float64(numCycles),
-		numShuttles / float64(numCycles)
+	return numOfType / float64(len(cycles))
 }
 
-// Calculates the accuracies of amp, speaker, shuttling, and distance shooting from an array of cycles, returning N/A for any that had 0 attempts.
-// Returns: Accuracy of AMP, SPEAKER, DISTANCE, SHUTTLE
-func GetCycleAccuracies(cycles []Cycle) (any, any, any, any) {
-	if cyclesAreValid(cycles) {
-		ampsAttempted, ampsMade := 0, 0
-		speakersAttempted, speakersMade := 0, 0
-		distancesAttempted, distancesMade := 0, 0
-		shuttlesAttempted, shuttlesMade := 0, 0
+// Calculates the accuracy of the cycles of the passed in type, returning N/A if there were 0 attempts.
+func GetCycleTypeAccuracy(cycles []Cycle, cycleType string) any {
+	if !cyclesAreValid(cycles) {
+		return "N/A"
+	}
 
-		for _, cycle := range cycles {
-			switch cycle.Type {
-			case "Amp":
-				{
-					ampsAttempted++
-					if cycle.Success {
-						ampsMade++
-					}
-				}
-			case "Speaker":
-				{
-					speakersAttempted++
-					if cycle.Success {
-						speakersMade++
-					}
-				}
-			case "Distance":
-				{
-					distancesAttempted++
-					if cycle.Success {
-						distancesMade++
-					}
-				}
-			case "Shuttle":
-				{
-					shuttlesAttempted++
-					if cycle.Success {
-						shuttlesMade++
-					}
-				}
+	attempted, made := 0, 0
+	for _, cycle := range cycles {
+		if cycle.Type == cycleType {
+			attempted++
+			if cycle.Success {
+				made++
 			}
 		}
-
-		var ampAccuracy any
-		var speakerAccuracy any
-		var distanceAccuracy any
-		var shuttleAccuracy any
-
-		if ampsAttempted == 0 {
-			ampAccuracy = "N/A"
-		} else {
-			ampAccuracy = (float64(ampsMade) / float64(ampsAttempted)) * 100
-		}
-
-		if speakersAttempted == 0 {
-			speakerAccuracy = "N/A"
-		} else {
-			speakerAccuracy = (float64(speakersMade) / float64(speakersAttempted)) * 100
-		}
-
-		if distancesAttempted == 0 {
-			distanceAccuracy = "N/A"
-		} else {
-			distanceAccuracy = (float64(distancesMade) / float64(distancesAttempted)) * 100
-		}
-
-		if shuttlesAttempted == 0 {
-			shuttleAccuracy = "N/A"
-		} else {
-			shuttleAccuracy = (float64(shuttlesMade) / float64(shuttlesAttempted)) * 100
-		}
-
-		return ampAccuracy, speakerAccuracy, distanceAccuracy, shuttleAccuracy
 	}
-	return "N/A", "N/A", "N/A", "N/A"
-}
-
-// Gets the accuracy of a robot during an autonomous period, returning N/A if 0 attempts were made
-func GetAutoAccuracy(auto AutoData) any {
-	attempts := auto.Scores + auto.Misses
 
-	if attempts == 0 {
+	if attempted == 0 {
 		return "N/A"
 	}
-	return (float64(auto.Scores) / float64(attempts)) * 100
+	return (float64(made) / float64(attempted)) * 100
 }
 
 // Compiles Losing track, DCs, penalties, and notes into one string of notes
 func CompileNotes(team TeamData) string {
 	var finalNote string = ""
+	if len(team.Warnings) > 0 {
+		finalNote += "WARNINGS= " + strings.Join(team.Warnings, ", ") + "; "
+	}
+
 	if team.Misc.LostTrack {
 		finalNote += "LOST TRACK; "
 	}
@@ -214,8 +138,15 @@ func CompileNotes2(match MultiMatch, teams []TeamData) string {
 	var finalNote string = ""
 	var lostTrack bool = false
 	var DC bool = false
+	var warnings []string
 
 	for _, entry := range teams {
+		for _, warning := range entry.Warnings {
+			if !slices.Contains(warnings, warning) {
+				warnings = append(warnings, warning)
+			}
+		}
+
 		if entry.Misc.LostTrack {
 			lostTrack = true
 		}
@@ -225,6 +156,10 @@ func CompileNotes2(match MultiMatch, teams []TeamData) string {
 		}
 	}
 
+	if len(warnings) > 0 {
+		finalNote += "WARNINGS= " + strings.Join(warnings, ", ") + "; "
+	}
+
 	if lostTrack {
 		finalNote += "LOST TRACK; "
 	}
@@ -233,6 +168,22 @@ func CompileNotes2(match MultiMatch, teams []TeamData) string {
 		finalNote += "DISCONNECTED; "
 	}
 
+	var dissent []string
+	for _, merge := range match.Merges {
+		var scouters []string
+		for _, dissenter := range merge.Dissent {
+			scouters = append(scouters, fmt.Sprintf("%v: %v", dissenter.Scouter, dissenter.Value))
+		}
+
+		if len(scouters) > 0 {
+			dissent = append(dissent, fmt.Sprintf("%v (%v)", merge.Column, strings.Join(scouters, ", ")))
+		}
+	}
+
+	if len(dissent) > 0 {
+		finalNote += "DISSENT= " + strings.Join(dissent, ", ") + "; "
+	}
+
 	finalNote += strings.Join(match.Notes, "; ")
 	return finalNote
 }
@@ -244,15 +195,49 @@ func CheckForTeamLists(eventKey string) bool {
 	return err == nil
 }
 
-// Writes the teams attending an event to the matching file in TeamLists
+// Writes the teams attending an event to the matching file in TeamLists.
+// The first line is the short name of the event, followed by every team number in ascending order.
 func WriteTeamsToFile(configs constants.GeneralConfigs) {
-	runnable := exec.Command(configs.PythonDriver, "getTeamList.py", configs.TBAKey, configs.EventKey, configs.TeamListsDirectory)
+	client := tba.NewClient(configs.TBAKey)
+
+	event, eventErr := client.Event(configs.EventKey)
+	if eventErr != nil {
+		greenlogger.LogErrorf(eventErr, "Problem requesting event %v from TBA", configs.EventKey)
+		return
+	}
+
+	teams, teamsErr := client.EventTeams(configs.EventKey)
+	if teamsErr != nil {
+		greenlogger.LogErrorf(teamsErr, "Problem requesting the teams of %v from TBA", configs.EventKey)
+		return
+	}
+
+	var teamNumbers []int
+	for _, team := range teams {
+		teamNumbers = append(teamNumbers, team.TeamNumber)
+	}
+	slices.Sort(teamNumbers)
+
+	eventName := event.ShortName
+	if eventName == "" {
+		eventName = event.Name
+	}
 
-	_, err := runnable.Output()
+	var builder strings.Builder
+	builder.WriteString(eventName + "\n")
+	for _, number := range teamNumbers {
+		builder.WriteString(strconv.Itoa(number) + "\n")
+	}
+
+	greenlogger.HandleMkdirAll(configs.TeamListsDirectory)
 
-	if err != nil && !strings.Contains(err.Error(), "exit status 1") {
-		greenlogger.LogErrorf(err, "Error executing command %v %v %v %v %v", configs.PythonDriver, "getTeamlist.py", configs.TBAKey, configs.EventKey, configs.TeamListsDirectory)
+	teamListPath := filepath.Join(configs.TeamListsDirectory, configs.EventKey)
+	if writeErr := filemanager.WriteFileWithPermissions(teamListPath, []byte(builder.String())); writeErr != nil {
+		greenlogger.LogErrorf(writeErr, "Problem writing %v", teamListPath)
+		return
 	}
+
+	greenlogger.LogMessagef("Finished filling out team list for %v", configs.EventKey)
 }
 
 // Reads the Teams from teamlists and stores them in memory
@@ -286,60 +271,80 @@ func StoreTeams() {
 	constants.Teams = resultInts
 }
 
-// Writes the schedule of an event to schedule/schedule.json
+// Writes the qualification schedule of an event to schedule.json, mapping each match number to the team numbers of both alliances
 func WriteScheduleToFile(configs constants.GeneralConfigs) {
-	runnable := exec.Command(configs.PythonDriver, "getSchedule.py", configs.TBAKey, configs.EventKey, configs.RuntimeDirectory)
-
-	_, err := runnable.Output()
-
-	if err != nil && !strings.Contains(err.Error(), "exit status 1") {
-		greenlogger.LogErrorf(err, "Error executing command %v %v %v", configs.PythonDriver, "getSchedule.py", configs.EventKey)
+	matches, err := tba.NewClient(configs.TBAKey).EventMatches(configs.EventKey)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem requesting the matches of %v from TBA", configs.EventKey)
+		return
 	}
-}
 
-// Writes all events for the current year to events.json
-func WriteEventsToFile(configs constants.GeneralConfigs) {
-	runnable := exec.Command(configs.PythonDriver, "getAllEvents.py", configs.TBAKey)
+	schedule := make(map[int]map[string][]int)
+	for _, match := range matches {
+		if match.CompLevel != tba.Qualification {
+			continue
+		}
 
-	out, err := runnable.Output()
+		var blueNumbers []int
+		for _, key := range match.Alliances.Blue.TeamKeys {
+			blueNumbers = append(blueNumbers, tba.TeamNumber(key))
+		}
 
-	if err != nil && !strings.Contains(err.Error(), "exit status 1") {
-		greenlogger.LogErrorf(err, "Error executing command %v %v %v", configs.PythonDriver, "getAllEvents.py", configs.TBAKey)
+		var redNumbers []int
+		for _, key := range match.Alliances.Red.TeamKeys {
+			redNumbers = append(redNumbers, tba.TeamNumber(key))
+		}
+
+		schedule[match.MatchNumber] = map[string][]int{"Blue": blueNumbers, "Red": redNumbers}
 	}
 
-	if strings.Contains(string(out), "ERR") {
-		greenlogger.LogMessagef("Error executing command %v %v %v; Investigate in python", configs.PythonDriver, "getAllEvents.py", configs.TBAKey)
+	scheduleBytes, marshalErr := json.MarshalIndent(schedule, "", "    ")
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling the schedule of %v", configs.EventKey)
+		return
+	}
 
+	schedPath := filepath.Join(configs.RuntimeDirectory, "schedule.json")
+	if writeErr := filemanager.WriteFileWithPermissions(schedPath, scheduleBytes); writeErr != nil {
+		greenlogger.LogErrorf(writeErr, "Problem writing %v", schedPath)
+		return
 	}
+
+	greenlogger.LogMessage("Finished filling out match schedule!")
 }
 
-// Calculates the string from a SpeakerPositions object
-func GetSpeakerPosAsString(positions SpeakerPositions) string {
-	if positions.Sides && positions.Middle {
-		return "BOTH"
+// Writes all events for the current year to events.json, mapping event keys to names in alphabetical order of name
+func WriteEventsToFile(configs constants.GeneralConfigs) {
+	events, err := tba.NewClient(configs.TBAKey).EventsByYear(time.Now().Year())
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem requesting the events of %v from TBA", time.Now().Year())
+		return
 	}
 
-	if positions.Middle {
-		return "MIDDLE"
-	} else if positions.Sides {
-		return "SIDES"
-	} else {
-		return "NONE"
-	}
-}
+	events = slices.DeleteFunc(events, func(event tba.Event) bool {
+		return event.Country == "Israel" // Excluded, as it always has been in the event list
+	})
 
-// Calculates the string from a PickupLocations object
-func GetPickupLocations(locations PickupLocations) string {
-	if locations.Ground && locations.Source {
-		return "BOTH"
+	slices.SortStableFunc(events, func(a, b tba.Event) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	// Written by hand, as encoding/json would sort the object by key instead of by name
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i, event := range events {
+		keyBytes, _ := json.Marshal(event.Key)
+		nameBytes, _ := json.Marshal(event.Name)
+
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString("\n    " + string(keyBytes) + ": " + string(nameBytes))
 	}
+	builder.WriteString("\n}")
 
-	if locations.Ground {
-		return "GROUND"
-	} else if locations.Source {
-		return "SOURCE"
-	} else {
-		return "NONE"
+	if writeErr := filemanager.WriteFileWithPermissions("events.json", []byte(builder.String())); writeErr != nil {
+		greenlogger.LogErrorf(writeErr, "Problem writing %v", "events.json")
 	}
 }
 
@@ -498,21 +503,3 @@ func MoveFile(originalPath string, newPath string) bool {
 
 	return true
 }
-
-// Gets the distance a robot can shoot from, returning N/A if it has no ability to do so.
-func GetDistance(data PitScoutingData) any {
-	if data.Distance.Can {
-		return int(data.Distance.Distance)
-	}
-
-	return "N/A"
-}
-
-// Gets the time a robot takes to climb, returning N/A if it doesn't do so in endgame.
-func GetClimbTime(data PitScoutingData) any {
-	if data.EndgameBehavior == "Climb" {
-		return data.ClimbTime
-	}
-
-	return "N/A"
-}
@@ -0,0 +1,110 @@
+package lib
+
+// Checking submitted scouting data against the event schedule and team list
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// What happens to submissions that don't match the schedule
+const (
+	ValidationReject  = "reject"  // They are turned away, so the scouter can fix them
+	ValidationWarn    = "warn"    // They are accepted, with the problems flagged in their notes
+	ValidationCorrect = "correct" // They are accepted with the team number the schedule has for their match and driver station, and flagged like warn
+	ValidationOff     = "off"     // They aren't checked at all
+)
+
+// Returns if a string is one of the validation modes
+func IsValidationMode(mode string) bool {
+	switch mode {
+	case ValidationReject, ValidationWarn, ValidationCorrect, ValidationOff:
+		return true
+	}
+	return false
+}
+
+// The result of checking one submission
+type Validation struct {
+	Warnings      []string // Everything that doesn't match the schedule or team list
+	ScheduledTeam uint64   // The team the schedule has at the submission's match and driver station, or 0 if it isn't known
+}
+
+// Returns the match schedule from schedule.json, mapping each match number to the team numbers of both alliances, and if it could be read
+func ReadSchedule() (map[int]map[string][]int, bool) {
+	var schedule map[int]map[string][]int
+
+	schedPath := filepath.Join(constants.CachedConfigs.RuntimeDirectory, "schedule.json")
+	scheduleBytes, readErr := os.ReadFile(schedPath)
+	if readErr != nil {
+		if !os.IsNotExist(readErr) {
+			greenlogger.LogErrorf(readErr, "Error reading %v", schedPath)
+		}
+		return nil, false
+	}
+
+	if unmarshalErr := json.Unmarshal(scheduleBytes, &schedule); unmarshalErr != nil {
+		greenlogger.LogErrorf(unmarshalErr, "Error decoding %v", schedPath)
+		return nil, false
+	}
+
+	return schedule, true
+}
+
+// Returns the alliance key used by schedule.json
+func scheduleAlliance(isBlue bool) string {
+	if isBlue {
+		return "Blue"
+	}
+	return "Red"
+}
+
+// Checks a submission against schedule.json and the event's team list.
+// Checks that need a schedule or team list are skipped when there isn't one, such as at custom events.
+func ValidateTeamData(team TeamData) Validation {
+	var result Validation
+	station := GetDSString(team.DriverStation.IsBlue, uint(team.DriverStation.Number))
+
+	if team.DriverStation.Number < 1 || team.DriverStation.Number > 3 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%v isn't a driver station", station))
+	}
+
+	if len(constants.Teams) > 0 && !slices.Contains(constants.Teams, int(team.TeamNumber)) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Team %v isn't on the team list", team.TeamNumber))
+	}
+
+	schedule, found := ReadSchedule()
+	if !found || len(schedule) == 0 {
+		return result
+	}
+
+	match, scheduled := schedule[int(team.Match.Number)]
+	if !scheduled {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Match %v isn't in the schedule", team.Match.Number))
+		return result
+	}
+
+	alliance := match[scheduleAlliance(team.DriverStation.IsBlue)]
+	if team.DriverStation.Number >= 1 && team.DriverStation.Number <= len(alliance) {
+		result.ScheduledTeam = uint64(alliance[team.DriverStation.Number-1])
+	}
+
+	if result.ScheduledTeam == 0 || result.ScheduledTeam == team.TeamNumber {
+		return result
+	}
+
+	warning := fmt.Sprintf("Match %v %v is team %v, not %v", team.Match.Number, station, result.ScheduledTeam, team.TeamNumber)
+	for _, isBlue := range []bool{true, false} {
+		if index := slices.Index(match[scheduleAlliance(isBlue)], int(team.TeamNumber)); index >= 0 {
+			warning += fmt.Sprintf(" (%v is %v)", team.TeamNumber, GetDSString(isBlue, uint(index+1)))
+		}
+	}
+	result.Warnings = append(result.Warnings, warning)
+
+	return result
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"GreenScoutBackend/accuracy"
 	"GreenScoutBackend/constants"
 	filemanager "GreenScoutBackend/fileManager"
 	greenlogger "GreenScoutBackend/greenLogger"
 	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"GreenScoutBackend/pickList"
+	"GreenScoutBackend/predictions"
+	"GreenScoutBackend/ratings"
 	"GreenScoutBackend/schedule"
 	"GreenScoutBackend/server"
 	"GreenScoutBackend/setup"
@@ -16,6 +21,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -59,9 +65,39 @@ func main() {
 	schedule.InitScoutDB()
 	userDB.InitAuthDB()
 	userDB.InitUserDB()
+	matchDB.InitMatchDB()
+	pickList.InitPickListDB()
 
+	// Before the subcommands below, as importing QR codes checks submitted teams against the list
 	lib.StoreTeams()
 
+	// Decode a file of scanned QR codes into In, then exit
+	if index := slices.Index(os.Args, "qr"); index >= 0 {
+		if index+1 >= len(os.Args) {
+			greenlogger.FatalLogMessage("Please pass the file of scanned QR codes to import, as in 'go run main.go qr scans.txt'")
+		}
+
+		server.ImportQRFile(os.Args[index+1])
+		os.Exit(0)
+	}
+
+	// Log a report of gaps and conflicts in the stored schedules, then exit
+	if index := slices.Index(os.Args, "report"); index >= 0 {
+		maxShiftLength := schedule.DefaultMaxShiftLength
+		if index+1 < len(os.Args) {
+			// Anything after report that isn't a number is another argument, like test
+			if parsed, parseErr := strconv.Atoi(os.Args[index+1]); parseErr == nil {
+				if parsed <= 0 {
+					greenlogger.FatalLogMessage("Please pass the longest shift allowed as a positive number, as in 'go run main.go report 8'")
+				}
+				maxShiftLength = parsed
+			}
+		}
+
+		schedule.LogCoverageReport(schedule.GenerateCoverageReport(maxShiftLength))
+		os.Exit(0)
+	}
+
 	// Write all match numbers to the sheet with a 1 minute cooldown to avoid rate limiting
 	if slices.Contains(os.Args, "matches") {
 		var usingRemainder bool = false
@@ -110,16 +146,40 @@ func main() {
 
 	}
 
+	cronManager := cron.New()
 	if updateDB {
 		// Daily commit + push
-		cronManager := cron.New()
 		_, cronErr := cronManager.AddFunc("@midnight", userDB.CommitAndPushDBs)
 		if cronErr != nil {
 			greenlogger.FatalError(cronErr, "Problem assigning commit and push task to cron")
 		}
-		cronManager.Start()
 	}
 
+	if constants.CachedConfigs.AccuracyConfigs.Enabled && !constants.CustomEventKey {
+		// Scouter accuracy against TBA score breakdowns
+		_, cronErr := cronManager.AddFunc(constants.CachedConfigs.AccuracyConfigs.Schedule, accuracy.Run)
+		if cronErr != nil {
+			greenlogger.FatalError(cronErr, "Problem assigning scouter accuracy task to cron")
+		}
+	}
+
+	if constants.CachedConfigs.RatingConfigs.Enabled && !constants.CustomEventKey {
+		// OPR, DPR and CCWM from TBA match results
+		_, cronErr := cronManager.AddFunc(constants.CachedConfigs.RatingConfigs.Schedule, ratings.Run)
+		if cronErr != nil {
+			greenlogger.FatalError(cronErr, "Problem assigning ratings task to cron")
+		}
+	}
+
+	if constants.CachedConfigs.PredictionConfigs.Enabled {
+		// Predictions of upcoming matches
+		_, cronErr := cronManager.AddFunc(constants.CachedConfigs.PredictionConfigs.Schedule, predictions.Run)
+		if cronErr != nil {
+			greenlogger.FatalError(cronErr, "Problem assigning predictions task to cron")
+		}
+	}
+	cronManager.Start()
+
 	go func() {
 		if serveTLS {
 			crtPath := ""
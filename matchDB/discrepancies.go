@@ -0,0 +1,80 @@
+package matchDB
+
+// Utilities for storing what the scouters of multi-scouted matches disagreed on in matches.db
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"encoding/json"
+	"time"
+)
+
+// Stores the discrepancy report of a match and driverstation, replacing any earlier one. Returns if it was successfully stored.
+func StoreDiscrepancyReport(report lib.DiscrepancyReport) bool {
+	scouters, _ := json.Marshal(report.Scouters)
+	fields, marshalErr := json.Marshal(report.Discrepancies)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", report.Discrepancies)
+		return false
+	}
+
+	_, execErr := matchDB.Exec(
+		"insert or replace into discrepancies values(?,?,?,?,?,?,?,?,?)",
+		report.Event, report.Match, report.IsBlue, report.DSNumber, report.Team, string(scouters), report.CycleMismatch, string(fields), report.ReportedAt.UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT OR REPLACE INTO discrepancies VALUES (?,?,?,?,?,?,?,?,?) with args: %v", report)
+		return false
+	}
+
+	return true
+}
+
+// Removes the discrepancy report of a match and driverstation, such as when it's rescouted. Returns if there was one.
+func ClearDiscrepancyReport(event string, match int, isBlue bool, dsNumber int) bool {
+	result, execErr := matchDB.Exec("delete from discrepancies where event = ? and match = ? and is_blue = ? and ds_number = ?", event, match, isBlue, dsNumber)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query DELETE FROM discrepancies WHERE ... with args: %v, %v, %v, %v", event, match, isBlue, dsNumber)
+		return false
+	}
+
+	deleted, _ := result.RowsAffected()
+	return deleted > 0
+}
+
+// Returns every discrepancy report of an event, ordered by match and driverstation
+func GetDiscrepancyReports(event string) []lib.DiscrepancyReport {
+	var results []lib.DiscrepancyReport
+
+	rows, queryErr := matchDB.Query(
+		"select event, match, is_blue, ds_number, team, scouters, cycle_mismatch, fields, reported_at from discrepancies where event = ? order by match, is_blue desc, ds_number",
+		event,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM discrepancies WHERE event = ? with arg: %v", event)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var report lib.DiscrepancyReport
+		var scouters, fields string
+		var reportedAt int64
+		scanErr := rows.Scan(&report.Event, &report.Match, &report.IsBlue, &report.DSNumber, &report.Team, &scouters, &report.CycleMismatch, &fields, &reportedAt)
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM discrepancies")
+			continue
+		}
+
+		if unmarshalErr := json.Unmarshal([]byte(scouters), &report.Scouters); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", scouters)
+		}
+		if unmarshalErr := json.Unmarshal([]byte(fields), &report.Discrepancies); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", fields)
+		}
+		report.ReportedAt = time.UnixMilli(reportedAt)
+		results = append(results, report)
+	}
+
+	return results
+}
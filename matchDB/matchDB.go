@@ -0,0 +1,403 @@
+package matchDB
+
+// Utilities for storing and querying parsed scouting data in matches.db
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The reference to matches.db
+var matchDB *sql.DB
+
+// The name of the scouting data database, stored next to scout.db in the runtime directory
+const DatabaseName = "matches.db"
+
+// The statements that create every table of matches.db if they do not already exist
+var Schema = []string{
+	`create table if not exists matches(
+		file text not null primary key,
+		event text not null,
+		match integer not null,
+		replay boolean not null default 0,
+		team integer not null,
+		is_blue boolean not null,
+		ds_number integer not null,
+		scouter text,
+		rescouting boolean not null default 0,
+		discarded boolean not null default 0,
+		speaker_sides boolean, speaker_middle boolean,
+		pickup_ground boolean, pickup_source boolean,
+		auto_can boolean, auto_scores integer, auto_misses integer, auto_ejects integer,
+		climb_succeeded boolean, climb_time real,
+		trap_attempts integer, trap_score integer,
+		parked boolean, lost_communication boolean, lost_track boolean, disabled boolean,
+		penalties text,
+		notes text,
+		raw text not null,
+		stored_at integer not null)`,
+	`create table if not exists cycles(
+		file text not null,
+		idx integer not null,
+		time real,
+		type text,
+		success boolean,
+		primary key(file, idx))`,
+	`create table if not exists pits(
+		file text not null primary key,
+		event text not null,
+		team integer not null,
+		pit text,
+		scouter text,
+		drivetrain text,
+		speaker_sides boolean, speaker_middle boolean,
+		can_distance boolean, distance real,
+		auto_scores integer, middle_notes integer, note_detection boolean,
+		cycles integer, driver_experience integer, bot_type text,
+		human_player_position integer, human_player_stage_accuracy integer,
+		endgame_behavior text, climb_time real,
+		notes text,
+		raw text not null,
+		stored_at integer not null)`,
+	`create table if not exists queue(
+		file text not null primary key,
+		key text not null,
+		enqueued_at integer not null,
+		attempts integer not null default 0,
+		retry_at integer not null default 0,
+		last_error text)`,
+	`create table if not exists failures(
+		file text not null primary key,
+		directory text not null,
+		reason text not null,
+		attempts integer not null,
+		failed_at integer not null)`,
+	`create table if not exists submissions(
+		id text not null primary key,
+		endpoint text not null,
+		pending boolean not null default 1,
+		status integer not null default 0,
+		content_type text not null default '',
+		response text not null default '',
+		submitted_at integer not null)`,
+	`create table if not exists validation_issues(
+		id integer primary key autoincrement,
+		file text not null default '',
+		event text not null,
+		match integer not null,
+		is_blue boolean not null,
+		ds_number integer not null,
+		team integer not null,
+		scheduled_team integer not null default 0,
+		scouter text,
+		action text not null,
+		warnings text not null,
+		recorded_at integer not null)`,
+	`create table if not exists discrepancies(
+		event text not null,
+		match integer not null,
+		is_blue boolean not null,
+		ds_number integer not null,
+		team integer not null,
+		scouters text not null,
+		cycle_mismatch boolean not null,
+		fields text not null,
+		reported_at integer not null,
+		primary key(event, match, is_blue, ds_number))`,
+	`create table if not exists team_ratings(
+		event text not null,
+		team integer not null,
+		matches integer not null,
+		opr real not null,
+		dpr real not null,
+		ccwm real not null,
+		components text not null,
+		computed_at integer not null,
+		primary key(event, team))`,
+	`create table if not exists predictions(
+		event text not null,
+		match integer not null,
+		played boolean not null,
+		raw text not null,
+		predicted_at integer not null,
+		primary key(event, match))`,
+	`create index if not exists matches_by_slot on matches(event, match, is_blue, ds_number)`,
+	`create index if not exists matches_by_team on matches(event, team)`,
+	`create index if not exists validation_issues_by_event on validation_issues(event, recorded_at)`,
+}
+
+// Opens the reference to matches.db, backfilling it from Written if it is empty
+func InitMatchDB() {
+	dbPath := filepath.Join(constants.CachedConfigs.RuntimeDirectory, DatabaseName)
+	dbRef, dbOpenErr := sql.Open(constants.CachedConfigs.SqliteDriver, dbPath)
+
+	matchDB = dbRef
+
+	if dbOpenErr != nil {
+		greenlogger.FatalError(dbOpenErr, "Problem opening database "+dbPath)
+	}
+
+	var count int
+	scanErr := matchDB.QueryRow("select count(1) from matches").Scan(&count)
+	if scanErr != nil {
+		greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT COUNT(1) FROM matches")
+		return
+	}
+
+	if count == 0 {
+		backfillFromWritten()
+	}
+}
+
+// Gets the event key from the name of a stored json file (EVENT_...)
+func eventFromFile(fileName string) string {
+	return strings.Split(fileName, "_")[0]
+}
+
+// Stores one scouter's match data under the name of the file it was submitted as, replacing any earlier copy of that file.
+// Returns if it was successfully stored.
+func StoreTeamData(fileName string, team lib.TeamData) bool {
+	raw, marshalErr := json.Marshal(team)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", team)
+		return false
+	}
+
+	penalties, marshalErr := json.Marshal(team.Penalties)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", team.Penalties)
+		return false
+	}
+
+	tx, beginErr := matchDB.Begin()
+	if beginErr != nil {
+		greenlogger.LogError(beginErr, "Problem beginning transaction on matches.db")
+		return false
+	}
+	defer tx.Rollback()
+
+	_, execErr := tx.Exec(
+		"insert or replace into matches values(?,?,?,?,?,?,?,?,?,0,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		fileName,
+		eventFromFile(fileName),
+		team.Match.Number,
+		team.Match.IsReplay,
+		team.TeamNumber,
+		team.DriverStation.IsBlue,
+		team.DriverStation.Number,
+		team.Scouter,
+		team.Rescouting,
+		team.Positions.Sides, team.Positions.Middle,
+		team.Pickups.Ground, team.Pickups.Source,
+		team.Auto.Can, team.Auto.Scores, team.Auto.Misses, team.Auto.Ejects,
+		team.Climb.Succeeded, team.Climb.Time,
+		team.Trap.Attempts, team.Trap.Score,
+		team.Misc.Parked, team.Misc.DC, team.Misc.LostTrack, team.Misc.Disabled,
+		string(penalties),
+		team.Notes,
+		string(raw),
+		time.Now().UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem inserting %v into matches", fileName)
+		return false
+	}
+
+	_, execErr = tx.Exec("delete from cycles where file = ?", fileName)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem clearing cycles of %v", fileName)
+		return false
+	}
+
+	for i, cycle := range team.Cycles {
+		_, execErr = tx.Exec("insert into cycles values(?,?,?,?,?)", fileName, i, cycle.Time, cycle.Type, cycle.Success)
+		if execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem inserting cycle %v of %v", i, fileName)
+			return false
+		}
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		greenlogger.LogErrorf(commitErr, "Problem committing %v to matches.db", fileName)
+		return false
+	}
+
+	return true
+}
+
+// Stores pit scouting data under the name of the file it was submitted as, replacing any earlier copy of that file.
+// Returns if it was successfully stored.
+func StorePitData(fileName string, pit lib.PitScoutingData) bool {
+	raw, marshalErr := json.Marshal(pit)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", pit)
+		return false
+	}
+
+	_, execErr := matchDB.Exec(
+		"insert or replace into pits values(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		fileName,
+		eventFromFile(fileName),
+		pit.TeamNumber,
+		pit.PitIdentifier,
+		pit.Scouter,
+		pit.Drivetrain,
+		pit.Sides.Sides, pit.Sides.Middle,
+		pit.Distance.Can, pit.Distance.Distance,
+		pit.AutoScores, pit.MiddleControls, pit.NoteDetection,
+		pit.Cycles, pit.DriverExperience, pit.BotType,
+		pit.HumanPlayer.Position, pit.HumanPlayer.StageAccuracy,
+		pit.EndgameBehavior, pit.ClimbTime,
+		pit.Notes,
+		string(raw),
+		time.Now().UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem inserting %v into pits", fileName)
+		return false
+	}
+
+	return true
+}
+
+// Marks a stored match entry as discarded, which happens when its match and driverstation are rescouted
+func MarkDiscarded(fileName string) {
+	_, execErr := matchDB.Exec("update matches set discarded = 1 where file = ?", fileName)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE matches SET discarded = 1 WHERE file = ? with arg: %v", fileName)
+	}
+}
+
+// One stored match entry, along with the file it was submitted as
+type StoredTeamData struct {
+	File string       // The name of the json file the entry was submitted as
+	Data lib.TeamData // The entry itself
+}
+
+// Runs a query selecting the raw column of matches and decodes every row
+func queryTeamData(query string, args ...any) []StoredTeamData {
+	var results []StoredTeamData
+
+	rows, queryErr := matchDB.Query(query, args...)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query %v with args %v", query, args)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var file string
+		var raw string
+		if scanErr := rows.Scan(&file, &raw); scanErr != nil {
+			greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query %v", query)
+			continue
+		}
+
+		var team lib.TeamData
+		if unmarshalErr := json.Unmarshal([]byte(raw), &team); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling stored entry %v", file)
+			continue
+		}
+
+		results = append(results, StoredTeamData{File: file, Data: team})
+	}
+
+	return results
+}
+
+// Returns every non-discarded match entry stored for an event, ordered by match and driverstation
+func GetEventTeamData(event string) []StoredTeamData {
+	return queryTeamData(
+		"select file, raw from matches where event = ? and discarded = 0 order by match, is_blue, ds_number, stored_at",
+		event,
+	)
+}
+
+// Returns every non-discarded match entry for one team at an event, ordered by match
+func GetTeamEntries(event string, team int) []StoredTeamData {
+	return queryTeamData(
+		"select file, raw from matches where event = ? and team = ? and discarded = 0 order by match, stored_at",
+		event, team,
+	)
+}
+
+// Returns every non-discarded entry for one driverstation of one match at an event
+func GetMatchEntries(event string, match int, isBlue bool, dsNumber int) []StoredTeamData {
+	return queryTeamData(
+		"select file, raw from matches where event = ? and match = ? and is_blue = ? and ds_number = ? and discarded = 0 order by stored_at",
+		event, match, isBlue, dsNumber,
+	)
+}
+
+// Returns the pit scouting data stored for an event
+func GetEventPitData(event string) []lib.PitScoutingData {
+	var results []lib.PitScoutingData
+
+	rows, queryErr := matchDB.Query("select raw from pits where event = ? order by team", event)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT raw FROM pits WHERE event = ? with arg: %v", event)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var raw string
+		if scanErr := rows.Scan(&raw); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT raw FROM pits")
+			continue
+		}
+
+		var pit lib.PitScoutingData
+		if unmarshalErr := json.Unmarshal([]byte(raw), &pit); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling stored pit entry %v", raw)
+			continue
+		}
+
+		results = append(results, pit)
+	}
+
+	return results
+}
+
+// Stores every file already in Written and PitWritten, so data scouted before matches.db existed is not lost.
+func backfillFromWritten() {
+	stored := 0
+
+	written, readErr := os.ReadDir(constants.JsonWrittenDirectory)
+	if readErr != nil && !errors.Is(readErr, os.ErrNotExist) {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", constants.JsonWrittenDirectory)
+	}
+
+	for _, file := range written {
+		team, hadErrs := lib.Parse(file.Name(), true)
+		if !hadErrs && StoreTeamData(file.Name(), team) {
+			stored++
+		}
+	}
+
+	pitWritten, readErr := os.ReadDir(constants.JsonPitWrittenDirectory)
+	if readErr != nil && !errors.Is(readErr, os.ErrNotExist) {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", constants.JsonPitWrittenDirectory)
+	}
+
+	for _, file := range pitWritten {
+		pit, hadErrs := lib.ParsePitScout(file.Name(), true)
+		if !hadErrs && StorePitData(file.Name(), pit) {
+			stored++
+		}
+	}
+
+	if stored > 0 {
+		greenlogger.LogMessagef("Backfilled %v entries into %v", stored, DatabaseName)
+	}
+}
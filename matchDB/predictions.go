@@ -0,0 +1,61 @@
+package matchDB
+
+// Utilities for storing match predictions, and how they turned out, in matches.db
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"encoding/json"
+)
+
+// Stores the prediction of a match, replacing any earlier one unless that one was already checked against the match's result.
+// Returns if it was successfully stored.
+func StorePrediction(prediction lib.Prediction) bool {
+	raw, marshalErr := json.Marshal(prediction)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", prediction)
+		return false
+	}
+
+	_, execErr := matchDB.Exec(
+		`insert into predictions values(?,?,?,?,?)
+		on conflict(event, match) do update set played = excluded.played, raw = excluded.raw, predicted_at = excluded.predicted_at
+		where predictions.played = 0`,
+		prediction.Event, prediction.Match, prediction.Played, string(raw), prediction.PredictedAt.UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT INTO predictions ... with args: %v, %v", prediction.Event, prediction.Match)
+		return false
+	}
+
+	return true
+}
+
+// Returns every stored prediction of an event, ordered by match
+func GetPredictions(event string) []lib.Prediction {
+	var results []lib.Prediction
+
+	rows, queryErr := matchDB.Query("select raw from predictions where event = ? order by match", event)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT raw FROM predictions WHERE event = ? with arg: %v", event)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var raw string
+		if scanErr := rows.Scan(&raw); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT raw FROM predictions")
+			continue
+		}
+
+		var prediction lib.Prediction
+		if unmarshalErr := json.Unmarshal([]byte(raw), &prediction); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", raw)
+			continue
+		}
+		results = append(results, prediction)
+	}
+
+	return results
+}
@@ -0,0 +1,140 @@
+package matchDB
+
+// Utilities for persisting the ingestion queue and its failures in matches.db, so submissions survive a restart
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"time"
+)
+
+// A file in In waiting to be processed
+type QueuedFile struct {
+	File       string    // The name of the file in In
+	Key        string    // The match and driverstation (or pit team) it's for; files with the same key are processed in order
+	EnqueuedAt time.Time // When it was first queued
+	Attempts   int       // How many times processing it has been started
+	RetryAt    time.Time // When it may next be tried, if its last attempt failed
+	LastError  string    // Why its last attempt failed, if it did
+}
+
+// A submission that was given up on, kept in Errored or Mangled until an admin requeues it
+type Failure struct {
+	File      string    // The name of the file
+	Directory string    // The directory it's in, Errored or Mangled
+	Reason    string    // Why it failed
+	Attempts  int       // How many times it was tried
+	FailedAt  time.Time // When it was given up on
+}
+
+// Records a file as queued, keeping its original position if it already was. Returns if it was successfully recorded.
+func EnqueueFile(fileName string, key string) bool {
+	_, execErr := matchDB.Exec("insert or ignore into queue(file, key, enqueued_at) values(?,?,?)", fileName, key, time.Now().UnixMilli())
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT OR IGNORE INTO queue(file, key, enqueued_at) VALUES (?,?,?) with args: %v, %v", fileName, key)
+		return false
+	}
+
+	return true
+}
+
+// Records that processing a queued file has started, returning how many times it has been attempted including this one
+func RecordAttempt(fileName string) int {
+	var attempts int
+	scanErr := matchDB.QueryRow("update queue set attempts = attempts + 1 where file = ? returning attempts", fileName).Scan(&attempts)
+	if scanErr != nil {
+		greenlogger.LogErrorf(scanErr, "Problem executing sql query UPDATE queue SET attempts = attempts + 1 WHERE file = ? with arg: %v", fileName)
+	}
+
+	return attempts
+}
+
+// Records that the last attempt at a queued file failed, and when it may be tried again
+func ScheduleRetry(fileName string, retryAt time.Time, reason string) {
+	_, execErr := matchDB.Exec("update queue set retry_at = ?, last_error = ? where file = ?", retryAt.UnixMilli(), reason, fileName)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE queue SET retry_at = ?, last_error = ? WHERE file = ? with args: %v, %v, %v", retryAt, reason, fileName)
+	}
+}
+
+// Removes a file from the queue once it has left In
+func DequeueFile(fileName string) {
+	_, execErr := matchDB.Exec("delete from queue where file = ?", fileName)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query DELETE FROM queue WHERE file = ? with arg: %v", fileName)
+	}
+}
+
+// Returns every queued file, oldest first
+func GetQueuedFiles() []QueuedFile {
+	var results []QueuedFile
+
+	rows, queryErr := matchDB.Query("select file, key, enqueued_at, attempts, retry_at, coalesce(last_error, '') from queue order by enqueued_at, file")
+	if queryErr != nil {
+		greenlogger.LogError(queryErr, "Problem executing sql query SELECT ... FROM queue")
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var queued QueuedFile
+		var enqueuedAt, retryAt int64
+		if scanErr := rows.Scan(&queued.File, &queued.Key, &enqueuedAt, &queued.Attempts, &retryAt, &queued.LastError); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM queue")
+			continue
+		}
+
+		queued.EnqueuedAt = time.UnixMilli(enqueuedAt)
+		queued.RetryAt = time.UnixMilli(retryAt)
+		results = append(results, queued)
+	}
+
+	return results
+}
+
+// Records why a submission was given up on, replacing any earlier record for the same file. Returns if it was successfully recorded.
+func RecordFailure(failure Failure) bool {
+	_, execErr := matchDB.Exec(
+		"insert or replace into failures values(?,?,?,?,?)",
+		failure.File, failure.Directory, failure.Reason, failure.Attempts, failure.FailedAt.UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT OR REPLACE INTO failures VALUES (?,?,?,?,?) with args: %v", failure)
+		return false
+	}
+
+	return true
+}
+
+// Returns the recorded failure of every submission in a directory, by file name
+func GetFailures(directory string) map[string]Failure {
+	results := make(map[string]Failure)
+
+	rows, queryErr := matchDB.Query("select file, directory, reason, attempts, failed_at from failures where directory = ?", directory)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM failures WHERE directory = ? with arg: %v", directory)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var failure Failure
+		var failedAt int64
+		if scanErr := rows.Scan(&failure.File, &failure.Directory, &failure.Reason, &failure.Attempts, &failedAt); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM failures")
+			continue
+		}
+
+		failure.FailedAt = time.UnixMilli(failedAt)
+		results[failure.File] = failure
+	}
+
+	return results
+}
+
+// Removes the failure record of a submission once it has been requeued
+func ClearFailure(fileName string) {
+	_, execErr := matchDB.Exec("delete from failures where file = ?", fileName)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query DELETE FROM failures WHERE file = ? with arg: %v", fileName)
+	}
+}
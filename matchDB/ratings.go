@@ -0,0 +1,83 @@
+package matchDB
+
+// Utilities for storing the OPR, DPR and CCWM of every team at an event in matches.db
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"encoding/json"
+	"time"
+)
+
+// Replaces every stored rating of an event with newly computed ones. Returns if they were successfully stored.
+func StoreTeamRatings(event string, ratings []lib.TeamRating) bool {
+	tx, beginErr := matchDB.Begin()
+	if beginErr != nil {
+		greenlogger.LogError(beginErr, "Problem beginning transaction on matches.db")
+		return false
+	}
+	defer tx.Rollback()
+
+	if _, execErr := tx.Exec("delete from team_ratings where event = ?", event); execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem clearing the ratings of %v", event)
+		return false
+	}
+
+	for _, rating := range ratings {
+		components, marshalErr := json.Marshal(rating.Components)
+		if marshalErr != nil {
+			greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", rating.Components)
+			return false
+		}
+
+		_, execErr := tx.Exec(
+			"insert into team_ratings values(?,?,?,?,?,?,?,?)",
+			event, rating.Team, rating.Matches, rating.OPR, rating.DPR, rating.CCWM, string(components), rating.ComputedAt.UnixMilli(),
+		)
+		if execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem inserting the ratings of %v at %v", rating.Team, event)
+			return false
+		}
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		greenlogger.LogErrorf(commitErr, "Problem committing the ratings of %v to matches.db", event)
+		return false
+	}
+
+	return true
+}
+
+// Returns the stored ratings of every team at an event, ordered by OPR, best first
+func GetTeamRatings(event string) []lib.TeamRating {
+	var results []lib.TeamRating
+
+	rows, queryErr := matchDB.Query(
+		"select event, team, matches, opr, dpr, ccwm, components, computed_at from team_ratings where event = ? order by opr desc",
+		event,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM team_ratings WHERE event = ? with arg: %v", event)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rating lib.TeamRating
+		var components string
+		var computedAt int64
+		scanErr := rows.Scan(&rating.Event, &rating.Team, &rating.Matches, &rating.OPR, &rating.DPR, &rating.CCWM, &components, &computedAt)
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM team_ratings")
+			continue
+		}
+
+		if unmarshalErr := json.Unmarshal([]byte(components), &rating.Components); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", components)
+		}
+		rating.ComputedAt = time.UnixMilli(computedAt)
+		results = append(results, rating)
+	}
+
+	return results
+}
@@ -0,0 +1,101 @@
+package matchDB
+
+// Utilities for remembering client submission IDs in matches.db, so a retried submission isn't stored twice
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// How long a submission can be pending before it's assumed its handling was cut off, such as by a crash, and a retry takes it over
+const PendingSubmissionTimeout = 2 * time.Minute
+
+// A submission made with a client-generated ID, and the response it was given
+type Submission struct {
+	ID          string    // The ID the client generated
+	Endpoint    string    // The endpoint it was posted to
+	Pending     bool      // If it is still being handled, so has no response yet
+	Status      int       // The HTTP status code it was responded to with
+	ContentType string    // The content type of the response
+	Response    string    // The body of the response
+	SubmittedAt time.Time // When it was first received
+}
+
+// Returns the key a submission is stored under. IDs belong to the user who submitted them, so one user can't claim another's.
+func submissionKey(owner string, id string) string {
+	return owner + "/" + id
+}
+
+// Claims a user's submission ID for an endpoint before handling it. If the ID was already claimed, returns the submission it was claimed by and false.
+// A claim that has been pending for longer than PendingSubmissionTimeout is taken over.
+func ReserveSubmission(owner string, id string, endpoint string) (Submission, bool) {
+	key := submissionKey(owner, id)
+	now := time.Now()
+
+	result, execErr := matchDB.Exec("insert or ignore into submissions(id, endpoint, submitted_at) values(?,?,?)", key, endpoint, now.UnixMilli())
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT OR IGNORE INTO submissions(id, endpoint, submitted_at) VALUES (?,?,?) with args: %v, %v", key, endpoint)
+		// Handling it again is better than losing it
+		return Submission{}, true
+	}
+
+	if inserted, _ := result.RowsAffected(); inserted > 0 {
+		return Submission{}, true
+	}
+
+	result, execErr = matchDB.Exec(
+		"update submissions set submitted_at = ? where id = ? and endpoint = ? and pending = 1 and submitted_at < ?",
+		now.UnixMilli(), key, endpoint, now.Add(-PendingSubmissionTimeout).UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE submissions SET submitted_at = ? WHERE id = ? AND pending = 1 ... with args: %v, %v", key, endpoint)
+	} else if takenOver, _ := result.RowsAffected(); takenOver > 0 {
+		greenlogger.LogMessagef("Submission %v was pending for over %v, handling it again", key, PendingSubmissionTimeout)
+		return Submission{}, true
+	}
+
+	existing, found := GetSubmission(owner, id)
+	return existing, !found
+}
+
+// Returns a user's submission by its ID, and if it exists
+func GetSubmission(owner string, id string) (Submission, bool) {
+	var submission Submission
+	var submittedAt int64
+
+	scanErr := matchDB.QueryRow(
+		"select endpoint, pending, status, content_type, response, submitted_at from submissions where id = ?", submissionKey(owner, id),
+	).Scan(&submission.Endpoint, &submission.Pending, &submission.Status, &submission.ContentType, &submission.Response, &submittedAt)
+
+	if scanErr != nil {
+		if !errors.Is(scanErr, sql.ErrNoRows) {
+			greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT ... FROM submissions WHERE id = ? with arg: %v", submissionKey(owner, id))
+		}
+		return Submission{}, false
+	}
+
+	submission.ID = id
+	submission.SubmittedAt = time.UnixMilli(submittedAt)
+	return submission, true
+}
+
+// Records the response a reserved submission was given, which is repeated to any retry of it
+func CompleteSubmission(owner string, id string, status int, contentType string, response string) {
+	_, execErr := matchDB.Exec(
+		"update submissions set pending = 0, status = ?, content_type = ?, response = ? where id = ?",
+		status, contentType, response, submissionKey(owner, id),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE submissions SET pending = 0 ... WHERE id = ? with arg: %v", submissionKey(owner, id))
+	}
+}
+
+// Gives up a reserved submission ID, so a retry is handled as if it were new. Used when handling it failed on the server's end.
+func ReleaseSubmission(owner string, id string) {
+	_, execErr := matchDB.Exec("delete from submissions where id = ?", submissionKey(owner, id))
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query DELETE FROM submissions WHERE id = ? with arg: %v", submissionKey(owner, id))
+	}
+}
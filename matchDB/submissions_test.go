@@ -0,0 +1,97 @@
+package matchDB
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Opens an empty matches.db in a temporary directory
+func setupSubmissionsTest(t *testing.T) {
+	t.Helper()
+
+	dbRef, openErr := sql.Open("sqlite3", filepath.Join(t.TempDir(), DatabaseName))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	t.Cleanup(func() { dbRef.Close() })
+
+	for _, statement := range Schema {
+		if _, execErr := dbRef.Exec(statement); execErr != nil {
+			t.Fatal(execErr)
+		}
+	}
+	matchDB = dbRef
+}
+
+// Backdates when a submission was received, as if its handling started that long ago
+func backdateSubmission(t *testing.T, owner string, id string, age time.Duration) {
+	t.Helper()
+
+	submittedAt := time.Now().Add(-age).UnixMilli()
+	if _, execErr := matchDB.Exec("update submissions set submitted_at = ? where id = ?", submittedAt, submissionKey(owner, id)); execErr != nil {
+		t.Fatal(execErr)
+	}
+}
+
+func TestReserveSubmissionOnce(t *testing.T) {
+	setupSubmissionsTest(t)
+
+	if _, reserved := ReserveSubmission("alice", "abc", "/dataEntry"); !reserved {
+		t.Fatal("a new submission ID wasn't reserved")
+	}
+
+	existing, reserved := ReserveSubmission("alice", "abc", "/dataEntry")
+	if reserved {
+		t.Fatal("a pending submission ID was reserved again")
+	}
+	if !existing.Pending || existing.ID != "abc" {
+		t.Errorf("retry got %+v, want the pending submission abc", existing)
+	}
+
+	CompleteSubmission("alice", "abc", 200, "text/plain", "saved")
+	existing, reserved = ReserveSubmission("alice", "abc", "/dataEntry")
+	if reserved || existing.Pending || existing.Response != "saved" {
+		t.Errorf("retry after completing got %+v, %v; want the saved response", existing, reserved)
+	}
+}
+
+func TestReserveStaleSubmission(t *testing.T) {
+	setupSubmissionsTest(t)
+
+	ReserveSubmission("alice", "abc", "/dataEntry")
+	backdateSubmission(t, "alice", "abc", PendingSubmissionTimeout/2)
+	if _, reserved := ReserveSubmission("alice", "abc", "/dataEntry"); reserved {
+		t.Fatal("a submission that only just started was taken over")
+	}
+
+	backdateSubmission(t, "alice", "abc", PendingSubmissionTimeout+time.Second)
+	if _, reserved := ReserveSubmission("alice", "abc", "/dataEntry"); !reserved {
+		t.Fatal("a submission pending for longer than the timeout wasn't taken over")
+	}
+	if _, reserved := ReserveSubmission("alice", "abc", "/dataEntry"); reserved {
+		t.Error("a taken over submission was taken over again straight away")
+	}
+
+	// Only pending submissions are taken over, so finished ones keep their response however old they are
+	CompleteSubmission("alice", "abc", 200, "text/plain", "saved")
+	backdateSubmission(t, "alice", "abc", PendingSubmissionTimeout+time.Second)
+	if _, reserved := ReserveSubmission("alice", "abc", "/dataEntry"); reserved {
+		t.Error("an old completed submission was reserved again")
+	}
+}
+
+func TestSubmissionsPerUser(t *testing.T) {
+	setupSubmissionsTest(t)
+
+	ReserveSubmission("alice", "abc", "/dataEntry")
+	CompleteSubmission("alice", "abc", 200, "text/plain", "alice's")
+
+	if _, reserved := ReserveSubmission("bob", "abc", "/dataEntry"); !reserved {
+		t.Fatal("another user's submission ID wasn't reserved")
+	}
+	if existing, found := GetSubmission("alice", "abc"); !found || existing.Response != "alice's" {
+		t.Errorf("alice's submission became %+v, %v", existing, found)
+	}
+}
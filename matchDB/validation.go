@@ -0,0 +1,74 @@
+package matchDB
+
+// Utilities for recording submissions that didn't match the schedule in matches.db, for the admin report
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"encoding/json"
+	"time"
+)
+
+// A submission that didn't match the schedule, and what was done about it
+type ValidationIssue struct {
+	File          string    // The name it was saved as, or empty if it was rejected
+	Event         string    // The event it was submitted for
+	Match         int       // The match number
+	IsBlue        bool      // If the driver station is on the blue alliance
+	DSNumber      int       // The driver station number
+	Team          int       // The team number as it was submitted
+	ScheduledTeam int       // The team the schedule has at that match and driver station, or 0 if it isn't known
+	Scouter       string    // Who submitted it
+	Action        string    // The validation mode it was handled with: "reject", "warn" or "correct"
+	Warnings      []string  // Everything that didn't match
+	RecordedAt    time.Time // When it was submitted
+}
+
+// Records a submission that didn't match the schedule
+func RecordValidationIssue(issue ValidationIssue) bool {
+	warnings, _ := json.Marshal(issue.Warnings)
+
+	_, execErr := matchDB.Exec(
+		"insert into validation_issues(file, event, match, is_blue, ds_number, team, scheduled_team, scouter, action, warnings, recorded_at) values(?,?,?,?,?,?,?,?,?,?,?)",
+		issue.File, issue.Event, issue.Match, issue.IsBlue, issue.DSNumber, issue.Team, issue.ScheduledTeam, issue.Scouter, issue.Action, string(warnings), issue.RecordedAt.UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT INTO validation_issues ... with args: %v", issue)
+		return false
+	}
+
+	return true
+}
+
+// Returns every submission to an event that didn't match the schedule, most recent first
+func GetValidationIssues(event string) []ValidationIssue {
+	var results []ValidationIssue
+
+	rows, queryErr := matchDB.Query(
+		"select file, event, match, is_blue, ds_number, team, scheduled_team, scouter, action, warnings, recorded_at from validation_issues where event = ? order by recorded_at desc, id desc",
+		event,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM validation_issues WHERE event = ? with arg: %v", event)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var issue ValidationIssue
+		var warnings string
+		var recordedAt int64
+		scanErr := rows.Scan(&issue.File, &issue.Event, &issue.Match, &issue.IsBlue, &issue.DSNumber, &issue.Team, &issue.ScheduledTeam, &issue.Scouter, &issue.Action, &warnings, &recordedAt)
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM validation_issues")
+			continue
+		}
+
+		if unmarshalErr := json.Unmarshal([]byte(warnings), &issue.Warnings); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", warnings)
+		}
+		issue.RecordedAt = time.UnixMilli(recordedAt)
+		results = append(results, issue)
+	}
+
+	return results
+}
@@ -0,0 +1,534 @@
+package pickList
+
+// Utilities for building pick lists collaboratively in picklists.db
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The reference to picklists.db
+var pickListDB *sql.DB
+
+// The name of the pick list database, stored next to matches.db in the runtime directory
+const DatabaseName = "picklists.db"
+
+// The statements that create every table of picklists.db if they do not already exist
+var Schema = []string{
+	`create table if not exists lists(
+		id integer primary key autoincrement,
+		event text not null,
+		name text not null,
+		created_by text not null,
+		created_at integer not null,
+		updated_at integer not null,
+		version integer not null default 0,
+		unique(event, name))`,
+	`create table if not exists list_teams(
+		list_id integer not null,
+		team integer not null,
+		rank integer not null,
+		tags text not null default '[]',
+		notes text not null default '',
+		do_not_pick boolean not null default 0,
+		updated_by text not null,
+		updated_at integer not null,
+		primary key(list_id, team))`,
+	`create table if not exists history(
+		id integer primary key autoincrement,
+		list_id integer not null,
+		username text not null,
+		action text not null,
+		team integer not null default 0,
+		detail text not null default '',
+		at integer not null)`,
+	`create table if not exists selections(
+		event text not null,
+		team integer not null,
+		status text not null,
+		alliance integer not null default 0,
+		marked_by text not null,
+		marked_at integer not null,
+		primary key(event, team))`,
+	`create index if not exists history_by_list on history(list_id, at)`,
+}
+
+// Opens the reference to picklists.db
+func InitPickListDB() {
+	dbPath := filepath.Join(constants.CachedConfigs.RuntimeDirectory, DatabaseName)
+	dbRef, dbOpenErr := sql.Open(constants.CachedConfigs.SqliteDriver, dbPath)
+
+	pickListDB = dbRef
+
+	if dbOpenErr != nil {
+		greenlogger.FatalError(dbOpenErr, "Problem opening database "+dbPath)
+	}
+}
+
+// Errors returned when a pick list can't be changed
+var (
+	ErrListExists    = errors.New("a pick list with that name already exists at this event")
+	ErrListNotFound  = errors.New("no pick list with that id exists")
+	ErrInvalidName   = errors.New("pick lists need a name")
+	ErrTeamNotListed = errors.New("that team isn't on the pick list")
+	ErrStaleList     = errors.New("the pick list was changed by someone else since it was loaded")
+	ErrInvalidStatus = errors.New("teams can only be marked picked or declined")
+	ErrNoVersion     = errors.New("changes need the version of the pick list they were made from")
+	ErrInvalidTeam   = errors.New("team numbers must be positive")
+)
+
+// What happened in one entry of a pick list's history
+const (
+	ActionCreate  = "create"  // The list was created
+	ActionReorder = "reorder" // The list was reordered
+	ActionEdit    = "edit"    // A team was added to the list, or its tags, notes or do not pick flag changed
+	ActionRemove  = "remove"  // A team was taken off the list
+	ActionDelete  = "delete"  // The list was deleted
+)
+
+// One named pick list at an event
+type PickList struct {
+	ID        int64        // The identifier of the list
+	Event     string       // The event key
+	Name      string       // The name of the list, unique at its event
+	CreatedBy string       // The strategist who created it
+	CreatedAt time.Time    // When it was created
+	UpdatedAt time.Time    // When it was last changed
+	Version   int          // Incremented on every change, so edits made from an outdated copy can be refused
+	Teams     []ListedTeam // Every team on the list, best first. Left empty when listing every list.
+}
+
+// One team on a pick list
+type ListedTeam struct {
+	Team      int       // The team number
+	Rank      int       // Its position on the list, starting at 1
+	Tags      []string  // Short labels, such as "defense" or "fast climb"
+	Notes     string    // Notes from the strategists
+	DoNotPick bool      // If the strategists agreed not to pick it
+	UpdatedBy string    // The strategist who last changed it
+	UpdatedAt time.Time // When it was last changed
+	Selection string    // SelectionPicked or SelectionDeclined if it has been during alliance selection, otherwise empty
+	Available bool      // If it can still be picked: it hasn't been picked or declined, and isn't flagged do not pick
+}
+
+// A change to one team on a pick list. Teams that aren't on the list yet are added at the bottom.
+type TeamEdit struct {
+	List      int64    // The identifier of the list
+	Team      int      // The team number
+	Tags      []string // Short labels, replacing any it had
+	Notes     string   // Notes, replacing any it had
+	DoNotPick bool     // If the strategists agreed not to pick it
+	Version   *int     // The version of the list the edit was made from
+}
+
+// A new order for a pick list, made from a copy of the list at Version
+type ListOrder struct {
+	List    int64 // The identifier of the list
+	Version *int  // The version of the list the order was made from
+	Teams   []int // The team numbers, best first. Teams on the list but missing from here keep their order below them.
+}
+
+// One change made to a pick list
+type HistoryEntry struct {
+	ListID   int64     // The identifier of the list
+	Username string    // The strategist who made the change
+	Action   string    // What was done; one of the Action constants
+	Team     int       // The team changed, or 0 if the change wasn't about one team
+	Detail   string    // A description of the change
+	At       time.Time // When it was made
+}
+
+// Creates an empty pick list at an event
+func CreateList(event string, name string, username string) (PickList, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return PickList{}, ErrInvalidName
+	}
+
+	now := time.Now()
+	result, execErr := pickListDB.Exec(
+		"insert into lists(event, name, created_by, created_at, updated_at) values(?,?,?,?,?) on conflict(event, name) do nothing",
+		event, name, username, now.UnixMilli(), now.UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT INTO lists ... with args: %v, %v, %v", event, name, username)
+		return PickList{}, execErr
+	}
+
+	if created, _ := result.RowsAffected(); created == 0 {
+		return PickList{}, ErrListExists
+	}
+
+	id, _ := result.LastInsertId()
+	recordHistory(pickListDB, HistoryEntry{ListID: id, Username: username, Action: ActionCreate, Detail: name, At: now})
+	publish(Update{Event: event, List: id, Kind: UpdateList, Username: username, At: now})
+
+	return GetList(id)
+}
+
+// Returns every pick list of an event, without their teams, ordered by name
+func GetLists(event string) []PickList {
+	var results []PickList
+
+	rows, queryErr := pickListDB.Query(
+		"select id, event, name, created_by, created_at, updated_at, version from lists where event = ? order by name",
+		event,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM lists WHERE event = ? with arg: %v", event)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		list, scanErr := scanList(rows)
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM lists")
+			continue
+		}
+		results = append(results, list)
+	}
+
+	return results
+}
+
+// Scans one row of lists
+func scanList(row interface{ Scan(...any) error }) (PickList, error) {
+	var list PickList
+	var createdAt, updatedAt int64
+	scanErr := row.Scan(&list.ID, &list.Event, &list.Name, &list.CreatedBy, &createdAt, &updatedAt, &list.Version)
+	list.CreatedAt, list.UpdatedAt = time.UnixMilli(createdAt), time.UnixMilli(updatedAt)
+	return list, scanErr
+}
+
+// Returns a pick list with every team on it, best first, along with how alliance selection has gone for them
+func GetList(id int64) (PickList, error) {
+	list, scanErr := scanList(pickListDB.QueryRow(
+		"select id, event, name, created_by, created_at, updated_at, version from lists where id = ?",
+		id,
+	))
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return list, ErrListNotFound
+	}
+	if scanErr != nil {
+		greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT ... FROM lists WHERE id = ? with arg: %v", id)
+		return list, scanErr
+	}
+
+	rows, queryErr := pickListDB.Query(
+		`select list_teams.team, rank, tags, notes, do_not_pick, updated_by, updated_at, coalesce(selections.status, '')
+		from list_teams left join selections on selections.event = ? and selections.team = list_teams.team
+		where list_id = ? order by rank`,
+		list.Event, id,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM list_teams WHERE list_id = ? with arg: %v", id)
+		return list, queryErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var team ListedTeam
+		var tags string
+		var updatedAt int64
+		scanErr := rows.Scan(&team.Team, &team.Rank, &tags, &team.Notes, &team.DoNotPick, &team.UpdatedBy, &updatedAt, &team.Selection)
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM list_teams")
+			continue
+		}
+
+		if unmarshalErr := json.Unmarshal([]byte(tags), &team.Tags); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", tags)
+		}
+		team.UpdatedAt = time.UnixMilli(updatedAt)
+		team.Available = team.Selection == "" && !team.DoNotPick
+		list.Teams = append(list.Teams, team)
+	}
+
+	return list, nil
+}
+
+// Runs a change to a pick list in a transaction, bumping its version and recording it in the history.
+// The change is refused with ErrNoVersion if version is missing, and with ErrStaleList unless the list is still at that version.
+func changeList(id int64, version *int, entry HistoryEntry, change func(tx *sql.Tx, now time.Time) error) (PickList, error) {
+	if version == nil {
+		return PickList{}, ErrNoVersion
+	}
+
+	tx, beginErr := pickListDB.Begin()
+	if beginErr != nil {
+		greenlogger.LogError(beginErr, "Problem beginning transaction on picklists.db")
+		return PickList{}, beginErr
+	}
+	defer tx.Rollback()
+
+	var event string
+	var current int
+	scanErr := tx.QueryRow("select event, version from lists where id = ?", id).Scan(&event, &current)
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return PickList{}, ErrListNotFound
+	}
+	if scanErr != nil {
+		greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT event, version FROM lists WHERE id = ? with arg: %v", id)
+		return PickList{}, scanErr
+	}
+
+	if *version != current {
+		return PickList{}, ErrStaleList
+	}
+
+	now := time.Now()
+	if changeErr := change(tx, now); changeErr != nil {
+		return PickList{}, changeErr
+	}
+
+	if _, execErr := tx.Exec("update lists set version = version + 1, updated_at = ? where id = ?", now.UnixMilli(), id); execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE lists SET version = version + 1 WHERE id = ? with arg: %v", id)
+		return PickList{}, execErr
+	}
+
+	entry.ListID, entry.At = id, now
+	if historyErr := recordHistory(tx, entry); historyErr != nil {
+		return PickList{}, historyErr
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		greenlogger.LogErrorf(commitErr, "Problem committing a change to pick list %v", id)
+		return PickList{}, commitErr
+	}
+
+	publish(Update{Event: event, List: id, Kind: UpdateList, Team: entry.Team, Username: entry.Username, Version: current + 1, At: now})
+	return GetList(id)
+}
+
+// Reorders a pick list. Teams in the order that aren't on the list yet are added.
+func ReorderList(order ListOrder, username string) (PickList, error) {
+	if slices.ContainsFunc(order.Teams, func(team int) bool { return team <= 0 }) {
+		return PickList{}, ErrInvalidTeam
+	}
+
+	entry := HistoryEntry{Username: username, Action: ActionReorder, Detail: joinTeams(order.Teams)}
+
+	return changeList(order.List, order.Version, entry, func(tx *sql.Tx, now time.Time) error {
+		existing, listErr := listedTeams(tx, order.List)
+		if listErr != nil {
+			return listErr
+		}
+
+		var ranked []int
+		for _, team := range order.Teams {
+			if !slices.Contains(ranked, team) {
+				ranked = append(ranked, team)
+			}
+		}
+		for _, team := range existing {
+			if !slices.Contains(ranked, team) {
+				ranked = append(ranked, team)
+			}
+		}
+
+		for i, team := range ranked {
+			_, execErr := tx.Exec(
+				`insert into list_teams(list_id, team, rank, updated_by, updated_at) values(?,?,?,?,?)
+				on conflict(list_id, team) do update set rank = excluded.rank`,
+				order.List, team, i+1, username, now.UnixMilli(),
+			)
+			if execErr != nil {
+				greenlogger.LogErrorf(execErr, "Problem ranking %v at %v on pick list %v", team, i+1, order.List)
+				return execErr
+			}
+		}
+		return nil
+	})
+}
+
+// Adds a team to a pick list, or changes its tags, notes and do not pick flag if it's already on it
+func EditTeam(edit TeamEdit, username string) (PickList, error) {
+	if edit.Team <= 0 {
+		return PickList{}, ErrInvalidTeam
+	}
+
+	if edit.Tags == nil {
+		edit.Tags = []string{}
+	}
+	tags, marshalErr := json.Marshal(edit.Tags)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", edit.Tags)
+		return PickList{}, marshalErr
+	}
+
+	entry := HistoryEntry{Username: username, Action: ActionEdit, Team: edit.Team, Detail: describeEdit(edit)}
+
+	return changeList(edit.List, edit.Version, entry, func(tx *sql.Tx, now time.Time) error {
+		_, execErr := tx.Exec(
+			`insert into list_teams values(?,?,(select coalesce(max(rank), 0) + 1 from list_teams where list_id = ?),?,?,?,?,?)
+			on conflict(list_id, team) do update set tags = excluded.tags, notes = excluded.notes, do_not_pick = excluded.do_not_pick,
+			updated_by = excluded.updated_by, updated_at = excluded.updated_at`,
+			edit.List, edit.Team, edit.List, string(tags), edit.Notes, edit.DoNotPick, username, now.UnixMilli(),
+		)
+		if execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem editing %v on pick list %v", edit.Team, edit.List)
+		}
+		return execErr
+	})
+}
+
+// Takes a team off a pick list at a version, moving everyone below it up
+func RemoveTeam(id int64, team int, version *int, username string) (PickList, error) {
+	entry := HistoryEntry{Username: username, Action: ActionRemove, Team: team}
+
+	return changeList(id, version, entry, func(tx *sql.Tx, now time.Time) error {
+		var rank int
+		scanErr := tx.QueryRow("select rank from list_teams where list_id = ? and team = ?", id, team).Scan(&rank)
+		if errors.Is(scanErr, sql.ErrNoRows) {
+			return ErrTeamNotListed
+		}
+		if scanErr != nil {
+			greenlogger.LogErrorf(scanErr, "Problem finding %v on pick list %v", team, id)
+			return scanErr
+		}
+
+		if _, execErr := tx.Exec("delete from list_teams where list_id = ? and team = ?", id, team); execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem removing %v from pick list %v", team, id)
+			return execErr
+		}
+
+		_, execErr := tx.Exec("update list_teams set rank = rank - 1 where list_id = ? and rank > ?", id, rank)
+		if execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem reranking pick list %v", id)
+		}
+		return execErr
+	})
+}
+
+// Deletes a pick list and every team on it. Its history is kept.
+func DeleteList(id int64, username string) error {
+	list, getErr := GetList(id)
+	if getErr != nil {
+		return getErr
+	}
+
+	tx, beginErr := pickListDB.Begin()
+	if beginErr != nil {
+		greenlogger.LogError(beginErr, "Problem beginning transaction on picklists.db")
+		return beginErr
+	}
+	defer tx.Rollback()
+
+	for _, statement := range []string{"delete from list_teams where list_id = ?", "delete from lists where id = ?"} {
+		if _, execErr := tx.Exec(statement, id); execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem executing sql query %v with arg: %v", statement, id)
+			return execErr
+		}
+	}
+
+	now := time.Now()
+	if historyErr := recordHistory(tx, HistoryEntry{ListID: id, Username: username, Action: ActionDelete, Detail: list.Name, At: now}); historyErr != nil {
+		return historyErr
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		greenlogger.LogErrorf(commitErr, "Problem committing the deletion of pick list %v", id)
+		return commitErr
+	}
+
+	publish(Update{Event: list.Event, List: id, Kind: UpdateDeleted, Username: username, At: now})
+	return nil
+}
+
+// Returns the teams on a pick list, best first
+func listedTeams(tx *sql.Tx, id int64) ([]int, error) {
+	var teams []int
+
+	rows, queryErr := tx.Query("select team from list_teams where list_id = ? order by rank", id)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT team FROM list_teams WHERE list_id = ? with arg: %v", id)
+		return teams, queryErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var team int
+		if scanErr := rows.Scan(&team); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT team FROM list_teams")
+			return teams, scanErr
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+// Something a history entry can be recorded with, either the database or a transaction on it
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// Records one change to a pick list
+func recordHistory(db execer, entry HistoryEntry) error {
+	_, execErr := db.Exec(
+		"insert into history(list_id, username, action, team, detail, at) values(?,?,?,?,?,?)",
+		entry.ListID, entry.Username, entry.Action, entry.Team, entry.Detail, entry.At.UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem recording %v in the history of pick list %v", entry.Action, entry.ListID)
+	}
+	return execErr
+}
+
+// Returns the history of a pick list, newest first. If username isn't empty, only that strategist's changes are returned.
+func GetHistory(id int64, username string) []HistoryEntry {
+	var results []HistoryEntry
+
+	rows, queryErr := pickListDB.Query(
+		"select list_id, username, action, team, detail, at from history where list_id = ? and (? = '' or username = ?) order by at desc, id desc",
+		id, username, username,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM history WHERE list_id = ? with args: %v, %v", id, username)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry HistoryEntry
+		var at int64
+		if scanErr := rows.Scan(&entry.ListID, &entry.Username, &entry.Action, &entry.Team, &entry.Detail, &at); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM history")
+			continue
+		}
+		entry.At = time.UnixMilli(at)
+		results = append(results, entry)
+	}
+
+	return results
+}
+
+// Describes a list of teams for the history
+func joinTeams(teams []int) string {
+	var numbers []string
+	for _, team := range teams {
+		numbers = append(numbers, strconv.Itoa(team))
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// Describes an edit for the history
+func describeEdit(edit TeamEdit) string {
+	description := fmt.Sprintf("tags: %v; notes: %v", strings.Join(edit.Tags, ", "), edit.Notes)
+	if edit.DoNotPick {
+		description += "; do not pick"
+	}
+	return description
+}
@@ -0,0 +1,82 @@
+package pickList
+
+// Utilities for tracking alliance selection, so every pick list shows who can still be picked
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"time"
+)
+
+// What happened to a team during alliance selection
+const (
+	SelectionPicked   = "picked"   // It joined an alliance
+	SelectionDeclined = "declined" // It declined an invitation, so it can't be picked by anyone else
+)
+
+// What happened to one team during alliance selection
+type Selection struct {
+	Event    string    // The event key
+	Team     int       // The team number
+	Status   string    // SelectionPicked or SelectionDeclined
+	Alliance int       // The alliance it joined, or 0 if it isn't known or it declined
+	MarkedBy string    // The admin who marked it
+	MarkedAt time.Time // When it was marked
+}
+
+// Marks a team as picked or declined during alliance selection at an event. An empty status clears its mark, such as to undo a mistake.
+func MarkSelection(selection Selection) error {
+	selection.MarkedAt = time.Now()
+
+	switch selection.Status {
+	case SelectionPicked, SelectionDeclined:
+		_, execErr := pickListDB.Exec(
+			"insert or replace into selections values(?,?,?,?,?,?)",
+			selection.Event, selection.Team, selection.Status, selection.Alliance, selection.MarkedBy, selection.MarkedAt.UnixMilli(),
+		)
+		if execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT OR REPLACE INTO selections VALUES (?,?,?,?,?,?) with args: %v", selection)
+			return execErr
+		}
+
+	case "":
+		_, execErr := pickListDB.Exec("delete from selections where event = ? and team = ?", selection.Event, selection.Team)
+		if execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem executing sql query DELETE FROM selections WHERE event = ? AND team = ? with args: %v, %v", selection.Event, selection.Team)
+			return execErr
+		}
+
+	default:
+		return ErrInvalidStatus
+	}
+
+	publish(Update{Event: selection.Event, Kind: UpdateSelection, Team: selection.Team, Status: selection.Status, Username: selection.MarkedBy, At: selection.MarkedAt})
+	return nil
+}
+
+// Returns every team marked during alliance selection at an event, in the order they were marked
+func GetSelections(event string) []Selection {
+	var results []Selection
+
+	rows, queryErr := pickListDB.Query(
+		"select event, team, status, alliance, marked_by, marked_at from selections where event = ? order by marked_at",
+		event,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM selections WHERE event = ? with arg: %v", event)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var selection Selection
+		var markedAt int64
+		if scanErr := rows.Scan(&selection.Event, &selection.Team, &selection.Status, &selection.Alliance, &selection.MarkedBy, &markedAt); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM selections")
+			continue
+		}
+		selection.MarkedAt = time.UnixMilli(markedAt)
+		results = append(results, selection)
+	}
+
+	return results
+}
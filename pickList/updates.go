@@ -0,0 +1,66 @@
+package pickList
+
+// Notifying connected clients whenever a pick list or alliance selection changes
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"sync"
+	"time"
+)
+
+// The kinds of update sent to subscribers
+const (
+	UpdateList      = "list"      // A pick list was created or changed
+	UpdateDeleted   = "deleted"   // A pick list was deleted
+	UpdateSelection = "selection" // A team was marked picked or declined, or had its mark cleared
+)
+
+// How many updates can wait for a slow subscriber before newer ones are dropped for it
+const kUpdateBuffer = 32
+
+// One change, sent to every subscriber
+type Update struct {
+	Event    string    // The event key
+	Kind     string    // What changed; one of the Update constants
+	List     int64     `json:",omitempty"` // The identifier of the list that changed, for list updates
+	Version  int       `json:",omitempty"` // The new version of the list, for list changes
+	Team     int       `json:",omitempty"` // The team that changed, if only one did
+	Status   string    `json:",omitempty"` // The new selection status of the team, for selection updates
+	Username string    // Who made the change
+	At       time.Time // When it was made
+}
+
+// Every channel currently subscribed to updates
+var subscribers = make(map[chan Update]struct{})
+
+// Guards subscribers
+var subscribersMutex sync.Mutex
+
+// Subscribes to every update, returning the channel they arrive on and a function to call once done with it
+func Subscribe() (<-chan Update, func()) {
+	updates := make(chan Update, kUpdateBuffer)
+
+	subscribersMutex.Lock()
+	subscribers[updates] = struct{}{}
+	subscribersMutex.Unlock()
+
+	return updates, func() {
+		subscribersMutex.Lock()
+		delete(subscribers, updates)
+		subscribersMutex.Unlock()
+	}
+}
+
+// Sends an update to every subscriber without waiting on any of them
+func publish(update Update) {
+	subscribersMutex.Lock()
+	defer subscribersMutex.Unlock()
+
+	for updates := range subscribers {
+		select {
+		case updates <- update:
+		default:
+			greenlogger.LogMessagef("Dropped a pick list update for a subscriber that isn't keeping up: %v", update)
+		}
+	}
+}
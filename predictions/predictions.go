@@ -0,0 +1,130 @@
+// Predicting the outcome of upcoming matches in schedule.json.
+//
+// Every upcoming match is simulated many times, drawing each robot's points from what it was scouted scoring in its earlier
+// matches (estimated with the points in the game definition). Once a match has been played, its last prediction is checked
+// against TBA's result and kept, so predictions can be calibrated.
+package predictions
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"GreenScoutBackend/tba"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Held while predicting, so a slow run is never overlapped by the next one
+var running sync.Mutex
+
+// The actual scores of a played match
+type result struct {
+	red  int // The red alliance's score
+	blue int // The blue alliance's score
+}
+
+// Predicts every upcoming match in schedule.json and checks the predictions of matches played since the last run.
+// At custom events, which TBA knows nothing about, matches count as played once they've been scouted, and aren't checked.
+func Run() {
+	if !running.TryLock() {
+		greenlogger.LogMessage("Skipping predicting matches, the last run is still going")
+		return
+	}
+	defer running.Unlock()
+
+	configs := constants.CachedConfigs
+	event := lib.GetCurrentEvent()
+
+	schedule, found := lib.ReadSchedule()
+	if !found || len(schedule) == 0 {
+		return
+	}
+
+	var entries []lib.TeamData
+	for _, stored := range matchDB.GetEventTeamData(event) {
+		entries = append(entries, stored.Data)
+	}
+
+	results, played, resultsFound := playedMatches(entries)
+	if !resultsFound {
+		return
+	}
+
+	var oprs map[int]float64
+	if configs.PredictionConfigs.UseOPR && configs.RatingConfigs.Enabled {
+		oprs = make(map[int]float64)
+		for _, rating := range matchDB.GetTeamRatings(event) {
+			oprs[rating.Team] = rating.OPR
+		}
+	}
+
+	stored := make(map[int]lib.Prediction)
+	for _, prediction := range matchDB.GetPredictions(event) {
+		stored[prediction.Match] = prediction
+	}
+
+	samples := lib.TeamPointSamples(entries)
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	var matches []int
+	for match := range schedule {
+		matches = append(matches, match)
+	}
+	sort.Ints(matches)
+
+	predicted, resolved := 0, 0
+	for _, match := range matches {
+		if played[match] {
+			prediction, wasPredicted := stored[match]
+			actual, hasResult := results[match]
+			if wasPredicted && !prediction.Played && hasResult {
+				lib.ResolvePrediction(&prediction, actual.red, actual.blue)
+				if matchDB.StorePrediction(prediction) {
+					resolved++
+				}
+			}
+			continue
+		}
+
+		alliances := schedule[match]
+		prediction := lib.PredictMatch(event, match, alliances["Red"], alliances["Blue"], samples, oprs, configs.PredictionConfigs.Simulations, random)
+		if matchDB.StorePrediction(prediction) {
+			predicted++
+		}
+	}
+
+	greenlogger.LogMessagef("Predicted %v upcoming matches and checked %v played ones at %v", predicted, resolved, event)
+}
+
+// Returns the results of every played qualification match, which matches have been played, and if they could be found.
+// Played matches at custom events have no results.
+func playedMatches(entries []lib.TeamData) (map[int]result, map[int]bool, bool) {
+	results := make(map[int]result)
+	played := make(map[int]bool)
+
+	if constants.CustomEventKey {
+		for _, entry := range entries {
+			played[int(entry.Match.Number)] = true
+		}
+		return results, played, true
+	}
+
+	configs := constants.CachedConfigs
+	matches, err := tba.NewClient(configs.TBAKey).EventMatches(configs.EventKey)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem requesting the matches of %v from TBA", configs.EventKey)
+		return results, played, false
+	}
+
+	for _, match := range matches {
+		if match.CompLevel == tba.Qualification && match.IsPlayed() {
+			results[match.MatchNumber] = result{red: match.Alliances.Red.Score, blue: match.Alliances.Blue.Score}
+			played[match.MatchNumber] = true
+		}
+	}
+
+	return results, played, true
+}
@@ -0,0 +1,241 @@
+// A compact, checksummed encoding of scouting data for QR codes, so tablets can hand off data with no network at all.
+//
+// A message is the JSON of one lib.TeamData, compressed with DEFLATE and encoded as unpadded base64url. It is split into
+// one or more parts, each small enough for one QR code, and each formatted as
+//
+//	GS<version>:<part>/<total>:<checksum>:<chunk>
+//
+// where part counts from 1, checksum is the CRC-32 (IEEE) of the whole encoded message as 8 lowercase hex digits, and
+// chunk is that part's slice of the encoded message. Parts can be scanned in any order.
+package qr
+
+import (
+	"GreenScoutBackend/lib"
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The version of the encoding written by Encode, and the only one Decode accepts
+const Version = 1
+
+// A part length that fits comfortably in one QR code at low error correction
+const DefaultPartLength = 800
+
+// The most parts a message may be split into
+const maxParts = 99
+
+// The most a message may decompress to, so a malicious code can't exhaust memory
+const maxDecodedBytes = 1 << 20
+
+var (
+	ErrNotQR          = errors.New("qr: not a GreenScout QR code")
+	ErrVersion        = errors.New("qr: unsupported encoding version")
+	ErrMalformed      = errors.New("qr: malformed part")
+	ErrMixedMessages  = errors.New("qr: parts are from different messages")
+	ErrIncomplete     = errors.New("qr: parts are missing")
+	ErrChecksum       = errors.New("qr: checksum mismatch")
+	ErrPartTooShort   = errors.New("qr: part length is too short for the message")
+	ErrDecompression  = errors.New("qr: message could not be decompressed")
+	ErrMessageTooLong = errors.New("qr: message decompresses to more than 1MB")
+)
+
+// One decoded message
+type Message struct {
+	ID   string       // A stable ID of the message's contents, the same every time the same codes are scanned
+	Team lib.TeamData // The scouting data
+}
+
+// One parsed part of a message
+type part struct {
+	index    int    // The position of the part, from 1
+	total    int    // How many parts the message has
+	checksum string // The checksum of the whole message
+	chunk    string // This part's slice of the message
+}
+
+// Returns the header of a part, everything before its chunk
+func header(index int, total int, checksum string) string {
+	return fmt.Sprintf("GS%d:%d/%d:%s:", Version, index, total, checksum)
+}
+
+// Encodes scouting data as one or more parts, none longer than maxPartLength characters
+func Encode(team lib.TeamData, maxPartLength int) ([]string, error) {
+	raw, marshalErr := json.Marshal(team)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+
+	var compressed bytes.Buffer
+	compressor, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	if _, writeErr := compressor.Write(raw); writeErr != nil {
+		return nil, writeErr
+	}
+	if closeErr := compressor.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(compressed.Bytes())
+	checksum := fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(payload)))
+
+	// More parts means longer headers, so find the fewest parts that fit
+	for total := 1; ; total++ {
+		chunkLength := maxPartLength - len(header(total, total, checksum))
+		if chunkLength <= 0 || total > maxParts {
+			return nil, ErrPartTooShort
+		}
+
+		if total*chunkLength < len(payload) {
+			continue
+		}
+
+		parts := make([]string, 0, total)
+		for i := 0; i < total; i++ {
+			start := min(i*chunkLength, len(payload))
+			end := min(start+chunkLength, len(payload))
+			parts = append(parts, header(i+1, total, checksum)+payload[start:end])
+		}
+
+		return parts, nil
+	}
+}
+
+// Parses one scanned part
+func parsePart(scanned string) (part, error) {
+	fields := strings.SplitN(strings.TrimSpace(scanned), ":", 4)
+	if len(fields) != 4 || !strings.HasPrefix(fields[0], "GS") {
+		return part{}, ErrNotQR
+	}
+
+	if version, versionErr := strconv.Atoi(strings.TrimPrefix(fields[0], "GS")); versionErr != nil || version != Version {
+		return part{}, fmt.Errorf("%w: %v", ErrVersion, fields[0])
+	}
+
+	position := strings.SplitN(fields[1], "/", 2)
+	if len(position) != 2 {
+		return part{}, fmt.Errorf("%w: %v", ErrMalformed, fields[1])
+	}
+
+	index, indexErr := strconv.Atoi(position[0])
+	total, totalErr := strconv.Atoi(position[1])
+	if indexErr != nil || totalErr != nil || index < 1 || index > total || total > maxParts || len(fields[2]) != 8 {
+		return part{}, fmt.Errorf("%w: %v", ErrMalformed, scanned)
+	}
+
+	return part{index: index, total: total, checksum: fields[2], chunk: fields[3]}, nil
+}
+
+// Returns the checksum identifying the message a scanned part belongs to
+func MessageChecksum(scanned string) (string, error) {
+	parsed, parseErr := parsePart(scanned)
+	return parsed.checksum, parseErr
+}
+
+// Groups scanned parts by the message they belong to, in the order each message was first seen.
+// Blank lines are skipped; anything else that isn't a part is returned as its own group, so decoding it reports why.
+func Group(scanned []string) [][]string {
+	var groups [][]string
+	positions := make(map[string]int)
+
+	for _, line := range scanned {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		checksum, parseErr := MessageChecksum(line)
+		if parseErr != nil {
+			groups = append(groups, []string{line})
+			continue
+		}
+
+		position, seen := positions[checksum]
+		if !seen {
+			position = len(groups)
+			positions[checksum] = position
+			groups = append(groups, nil)
+		}
+		groups[position] = append(groups[position], line)
+	}
+
+	return groups
+}
+
+// Decodes every part of one message, in any order. Duplicate scans of the same part are ignored.
+func Decode(scanned []string) (Message, error) {
+	chunks := make(map[int]string)
+	var first part
+
+	for _, line := range scanned {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		parsed, parseErr := parsePart(line)
+		if parseErr != nil {
+			return Message{}, parseErr
+		}
+
+		if len(chunks) == 0 {
+			first = parsed
+		} else if parsed.checksum != first.checksum || parsed.total != first.total {
+			return Message{}, ErrMixedMessages
+		}
+
+		chunks[parsed.index] = parsed.chunk
+	}
+
+	if len(chunks) == 0 {
+		return Message{}, ErrNotQR
+	}
+
+	var missing []string
+	var payload strings.Builder
+	for i := 1; i <= first.total; i++ {
+		chunk, found := chunks[i]
+		if !found {
+			missing = append(missing, strconv.Itoa(i))
+		}
+		payload.WriteString(chunk)
+	}
+
+	if len(missing) > 0 {
+		return Message{}, fmt.Errorf("%w: %v of %v", ErrIncomplete, strings.Join(missing, ", "), first.total)
+	}
+
+	if fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(payload.String()))) != first.checksum {
+		return Message{}, ErrChecksum
+	}
+
+	compressed, base64Err := base64.RawURLEncoding.DecodeString(payload.String())
+	if base64Err != nil {
+		return Message{}, fmt.Errorf("%w: %v", ErrMalformed, base64Err)
+	}
+
+	raw, readErr := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), maxDecodedBytes+1))
+	if readErr != nil {
+		return Message{}, fmt.Errorf("%w: %v", ErrDecompression, readErr)
+	}
+	if len(raw) > maxDecodedBytes {
+		return Message{}, ErrMessageTooLong
+	}
+
+	var message Message
+	if unmarshalErr := json.Unmarshal(raw, &message.Team); unmarshalErr != nil {
+		return Message{}, fmt.Errorf("%w: %v", ErrMalformed, unmarshalErr)
+	}
+
+	digest := sha256.Sum256([]byte(payload.String()))
+	message.ID = hex.EncodeToString(digest[:16])
+
+	return message, nil
+}
@@ -0,0 +1,190 @@
+package qr
+
+import (
+	"GreenScoutBackend/lib"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// A submission long enough to be split into several parts at the lengths used here
+func sampleTeam() lib.TeamData {
+	team := lib.TeamData{
+		TeamNumber: 1816,
+		Match:      lib.MatchInfo{Number: 42},
+		Scouter:    "alice",
+		Cycles: []lib.Cycle{
+			{Time: 4.5, Type: "Speaker", Success: true},
+			{Time: 7.25, Type: "Amp", Success: false},
+			{Time: 5, Type: "Speaker", Success: true},
+		},
+		Penalties: []string{"Tech Foul"},
+		Notes:     "Fast intake, struggled under defense from 2502, dropped a note at the source near the end",
+	}
+	team.DriverStation.IsBlue = true
+	team.DriverStation.Number = 2
+	team.Climb.Succeeded = true
+	team.Climb.Time = 6.5
+	return team
+}
+
+// Encodes the sample submission, failing the test if it can't be
+func encodeSample(t *testing.T, maxPartLength int) []string {
+	t.Helper()
+
+	parts, err := Encode(sampleTeam(), maxPartLength)
+	if err != nil {
+		t.Fatalf("Encode(%v) returned %v", maxPartLength, err)
+	}
+	return parts
+}
+
+// Fails the test unless a decoded submission is the same as the sample
+func checkSample(t *testing.T, message Message) {
+	t.Helper()
+
+	want, _ := json.Marshal(sampleTeam())
+	got, _ := json.Marshal(message.Team)
+	if string(got) != string(want) {
+		t.Errorf("decoded %s, want %s", got, want)
+	}
+	if message.ID == "" {
+		t.Error("decoded message has no ID")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, maxPartLength := range []int{DefaultPartLength, 60} {
+		parts := encodeSample(t, maxPartLength)
+		for _, scanned := range parts {
+			if len(scanned) > maxPartLength {
+				t.Errorf("part %q is longer than %v", scanned, maxPartLength)
+			}
+		}
+
+		message, err := Decode(parts)
+		if err != nil {
+			t.Fatalf("Decode of %v parts returned %v", len(parts), err)
+		}
+		checkSample(t, message)
+	}
+}
+
+func TestSinglePart(t *testing.T) {
+	if parts := encodeSample(t, DefaultPartLength); len(parts) != 1 {
+		t.Errorf("Encode at the default length gave %v parts, want 1", len(parts))
+	}
+}
+
+func TestOutOfOrder(t *testing.T) {
+	parts := encodeSample(t, 60)
+	if len(parts) < 3 {
+		t.Fatalf("want at least 3 parts to reorder, got %v", len(parts))
+	}
+
+	reversed := make([]string, 0, len(parts))
+	for i := len(parts) - 1; i >= 0; i-- {
+		reversed = append(reversed, parts[i])
+	}
+
+	ordered, err := Decode(parts)
+	if err != nil {
+		t.Fatalf("Decode of ordered parts returned %v", err)
+	}
+	message, err := Decode(reversed)
+	if err != nil {
+		t.Fatalf("Decode of reversed parts returned %v", err)
+	}
+	checkSample(t, message)
+
+	if message.ID != ordered.ID {
+		t.Errorf("reversed parts gave ID %v, ordered parts gave %v", message.ID, ordered.ID)
+	}
+}
+
+func TestDuplicateParts(t *testing.T) {
+	parts := encodeSample(t, 60)
+	scanned := append([]string{parts[1], "", parts[0]}, parts...)
+
+	message, err := Decode(scanned)
+	if err != nil {
+		t.Fatalf("Decode with duplicate parts returned %v", err)
+	}
+	checkSample(t, message)
+}
+
+func TestMissingPart(t *testing.T) {
+	parts := encodeSample(t, 60)
+	scanned := append(append([]string{}, parts[:1]...), parts[2:]...)
+
+	_, err := Decode(scanned)
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("Decode without part 2 returned %v, want %v", err, ErrIncomplete)
+	}
+	if !strings.Contains(err.Error(), "2 of") {
+		t.Errorf("error %q doesn't name the missing part", err)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	parts := encodeSample(t, 60)
+
+	// Change one character of the last part's chunk to another valid base64url character
+	last := parts[len(parts)-1]
+	changed := 'A'
+	if last[len(last)-1] == 'A' {
+		changed = 'B'
+	}
+	parts[len(parts)-1] = last[:len(last)-1] + string(changed)
+
+	if _, err := Decode(parts); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("Decode of a changed part returned %v, want %v", err, ErrChecksum)
+	}
+}
+
+func TestMixedMessages(t *testing.T) {
+	parts := encodeSample(t, 60)
+
+	other := sampleTeam()
+	other.Match.Number = 43
+	otherParts, err := Encode(other, 60)
+	if err != nil {
+		t.Fatalf("Encode returned %v", err)
+	}
+
+	if _, err := Decode([]string{parts[0], otherParts[1]}); !errors.Is(err, ErrMixedMessages) {
+		t.Fatalf("Decode of parts from two messages returned %v, want %v", err, ErrMixedMessages)
+	}
+
+	groups := Group(append(append([]string{}, otherParts...), parts...))
+	if len(groups) != 2 {
+		t.Fatalf("Group gave %v groups, want 2", len(groups))
+	}
+	for _, group := range groups {
+		if _, err := Decode(group); err != nil {
+			t.Errorf("Decode of a group returned %v", err)
+		}
+	}
+}
+
+func TestTooShort(t *testing.T) {
+	// Only room for the header
+	headerLength := len(header(1, 1, "00000000"))
+	if _, err := Encode(sampleTeam(), headerLength); !errors.Is(err, ErrPartTooShort) {
+		t.Errorf("Encode with no room for a chunk returned %v, want %v", err, ErrPartTooShort)
+	}
+
+	// Room for a chunk, but it would take more than maxParts parts
+	if _, err := Encode(sampleTeam(), headerLength+2); !errors.Is(err, ErrPartTooShort) {
+		t.Errorf("Encode needing more than %v parts returned %v, want %v", maxParts, err, ErrPartTooShort)
+	}
+}
+
+func TestNotQR(t *testing.T) {
+	for _, scanned := range [][]string{nil, {"hello"}, {"GS2:1/1:00000000:abc"}} {
+		if _, err := Decode(scanned); err == nil {
+			t.Errorf("Decode(%q) succeeded", scanned)
+		}
+	}
+}
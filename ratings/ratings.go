@@ -0,0 +1,59 @@
+// Computing OPR, DPR and CCWM for the current event from TBA match results.
+//
+// Every played qualification match gives two equations, one per alliance, saying the alliance's score is the sum of its
+// teams' contributions. Solving them with least squares gives each team's OPR. DPR is the same using the opposing alliance's
+// score, and CCWM using the winning margin. Every score breakdown field listed in the game definition's RatingFields gets a
+// component OPR the same way.
+package ratings
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"GreenScoutBackend/sheet"
+	"GreenScoutBackend/tba"
+	"sync"
+)
+
+// Held while computing, so a slow run is never overlapped by the next one
+var running sync.Mutex
+
+// Recomputes the ratings of every team at the current event, storing them and writing them to the sheet if configured.
+// Does nothing at custom events, which TBA doesn't know about.
+func Run() {
+	if !running.TryLock() {
+		greenlogger.LogMessage("Skipping computing ratings, the last run is still going")
+		return
+	}
+	defer running.Unlock()
+
+	configs := constants.CachedConfigs
+	if constants.CustomEventKey {
+		return
+	}
+
+	matches, err := tba.NewClient(configs.TBAKey).EventMatches(configs.EventKey)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem requesting the matches of %v from TBA", configs.EventKey)
+		return
+	}
+
+	components := constants.CachedGameConfig.RatingFields
+	ratings := lib.ComputeRatings(configs.EventKey, matches, components)
+	if len(ratings) == 0 {
+		return
+	}
+
+	if !matchDB.StoreTeamRatings(configs.EventKey, ratings) {
+		return
+	}
+
+	if configs.RatingConfigs.WriteSheet {
+		if writeErr := sheet.WriteRatings(ratings, components); writeErr != nil {
+			greenlogger.LogErrorf(writeErr, "Problem writing the ratings of %v to the sheet", configs.EventKey)
+		}
+	}
+
+	greenlogger.LogMessagef("Computed the ratings of %v teams at %v", len(ratings), configs.EventKey)
+}
@@ -0,0 +1,270 @@
+package schedule
+
+// Utility for generating a balanced rotation of scouters over every match in schedule.json
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/userDB"
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+// The number of driverstations every match has
+const kDriverStations = 6
+
+// The name of every driverstation, by its offset
+var stationNames = []string{"red1", "red2", "red3", "blue1", "blue2", "blue3"}
+
+// Errors returned when a rotation can't be generated
+var (
+	ErrNoScouters       = errors.New("no scouters were given")
+	ErrNoMatches        = errors.New("there are no matches in schedule.json")
+	ErrInvalidShift     = errors.New("shift lengths, breaks and redundancy can't be negative")
+	ErrDuplicateScouter = errors.New("a scouter was given more than once")
+)
+
+// Everything a rotation is generated from
+type AssignmentRequest struct {
+	Scouters    []ScouterAvailability // Everyone who can scout
+	ShiftLength int                   // The most matches in a row anyone scouts; 0 for no limit
+	BreakLength int                   // The fewest matches anyone gets off after a shift
+	Redundancy  int                   // How many scouters every driverstation gets, for multi-scouting; 1 if 0
+	DryRun      bool                  // If the rotation is only returned, without being written to anyone's schedule
+}
+
+// One scouter who can scout
+type ScouterAvailability struct {
+	Username  string   // Their username
+	Available [][2]int // The matches they can scout, as inclusive [first, last] windows; every match if empty
+}
+
+// A slot that nobody could be assigned to
+type UncoveredSlot struct {
+	Match    int // The match number
+	DSOffset int // The driverstation, from 0 (red1) to 5 (blue3)
+	Missing  int // How many more scouters it needed
+}
+
+// A generated rotation
+type Assignment struct {
+	Schedules map[string]ScoutRanges // Every scouter's schedule, by username
+	Matches   map[string]int         // How many matches every scouter was given, by username
+	Uncovered []UncoveredSlot        // Every slot that couldn't be filled, by match and driverstation
+	Unknown   []string               // Scouters that don't have an account, who were left out
+	Written   bool                   // If the schedules were written to scout.db
+}
+
+// The scouter currently filling one slot
+type slotState struct {
+	scouter   int // The index of the scouter, or -1 if it's empty
+	remaining int // How many more matches their shift lasts
+}
+
+// Generates a balanced rotation covering every driverstation of every match in schedule.json and, unless it's a dry run,
+// replaces the schedule of every scouter in it. Scouters not in the request keep their schedules.
+func GenerateAssignments(request AssignmentRequest) (Assignment, error) {
+	result := Assignment{Schedules: make(map[string]ScoutRanges), Matches: make(map[string]int)}
+
+	if request.ShiftLength < 0 || request.BreakLength < 0 || request.Redundancy < 0 {
+		return result, ErrInvalidShift
+	}
+	if request.Redundancy == 0 {
+		request.Redundancy = 1
+	}
+
+	var scouters []ScouterAvailability
+	seen := make(map[string]bool)
+	for _, scouter := range request.Scouters {
+		if seen[scouter.Username] {
+			return result, ErrDuplicateScouter
+		}
+		seen[scouter.Username] = true
+
+		if _, exists := userDB.GetUUID(scouter.Username, false); !exists {
+			result.Unknown = append(result.Unknown, scouter.Username)
+			continue
+		}
+		scouters = append(scouters, scouter)
+	}
+	if len(scouters) == 0 {
+		return result, ErrNoScouters
+	}
+
+	matchSchedule, found := lib.ReadSchedule()
+	if !found || len(matchSchedule) == 0 {
+		return result, ErrNoMatches
+	}
+
+	var matches []int
+	for match := range matchSchedule {
+		matches = append(matches, match)
+	}
+	sort.Ints(matches)
+
+	rotation := rotate(scouters, matches, request)
+
+	for i, scouter := range scouters {
+		result.Schedules[scouter.Username] = ScoutRanges{Ranges: rotation.ranges[i]}
+		result.Matches[scouter.Username] = rotation.load[i]
+	}
+	result.Uncovered = rotation.uncovered
+
+	if request.DryRun {
+		return result, nil
+	}
+
+	if writeErr := setSchedules(result.Schedules); writeErr != nil {
+		return result, writeErr
+	}
+	result.Written = true
+
+	greenlogger.LogMessagef("Generated a rotation of %v scouters over %v matches, leaving %v slots uncovered", len(scouters), len(matches), len(result.Uncovered))
+	return result, nil
+}
+
+// The raw result of a rotation
+type rotation struct {
+	ranges    [][][3]int      // The ranges of every scouter, by index
+	load      []int           // The number of matches of every scouter, by index
+	uncovered []UncoveredSlot // Every slot that couldn't be filled
+}
+
+// Fills every slot of every match in order. Whoever fills a slot keeps it until their shift ends or they stop being available;
+// it then goes to the available scouter with the fewest matches so far who isn't on a break or in another slot.
+func rotate(scouters []ScouterAvailability, matches []int, request AssignmentRequest) rotation {
+	result := rotation{ranges: make([][][3]int, len(scouters)), load: make([]int, len(scouters))}
+
+	slots := make([]slotState, kDriverStations*request.Redundancy)
+	for i := range slots {
+		slots[i].scouter = -1
+	}
+
+	restingUntil := make([]int, len(scouters)) // The index in matches each scouter's break ends at
+	lastWorked := make([]int, len(scouters))   // The index in matches each scouter last scouted at, or -1
+	for i := range lastWorked {
+		lastWorked[i] = -1
+	}
+
+	for index, match := range matches {
+		busy := make(map[int]bool)
+
+		// Keep everyone whose shift continues
+		for i := range slots {
+			scouter := slots[i].scouter
+			if scouter >= 0 && (slots[i].remaining == 0 || !isAvailable(scouters[scouter], match)) {
+				restingUntil[scouter] = index + request.BreakLength
+				slots[i].scouter = -1
+			}
+			if slots[i].scouter >= 0 {
+				busy[slots[i].scouter] = true
+			}
+		}
+
+		// Fill the rest
+		for i := range slots {
+			if slots[i].scouter >= 0 {
+				continue
+			}
+
+			best := -1
+			for candidate, scouter := range scouters {
+				if busy[candidate] || restingUntil[candidate] > index || !isAvailable(scouter, match) {
+					continue
+				}
+				if best < 0 || result.load[candidate] < result.load[best] ||
+					(result.load[candidate] == result.load[best] && lastWorked[candidate] < lastWorked[best]) {
+					best = candidate
+				}
+			}
+
+			if best < 0 {
+				continue
+			}
+
+			busy[best] = true
+			slots[i] = slotState{scouter: best, remaining: firstShiftLength(request, i, len(slots), index)}
+		}
+
+		// Record this match
+		missing := make(map[int]int)
+		for i := range slots {
+			dsOffset := i % kDriverStations
+			scouter := slots[i].scouter
+			if scouter < 0 {
+				missing[dsOffset]++
+				continue
+			}
+
+			result.load[scouter]++
+			if slots[i].remaining > 0 {
+				slots[i].remaining--
+			}
+			extendRange(&result.ranges[scouter], dsOffset, match, lastWorked[scouter] == index-1)
+			lastWorked[scouter] = index
+		}
+
+		for dsOffset := 0; dsOffset < kDriverStations; dsOffset++ {
+			if missing[dsOffset] > 0 {
+				result.uncovered = append(result.uncovered, UncoveredSlot{Match: match, DSOffset: dsOffset, Missing: missing[dsOffset]})
+			}
+		}
+	}
+
+	return result
+}
+
+// Returns how long a new shift in a slot lasts, or -1 for no limit. Shifts starting at the first match are staggered across the slots,
+// so everyone doesn't change over at once.
+func firstShiftLength(request AssignmentRequest, slot int, slots int, index int) int {
+	if request.ShiftLength == 0 {
+		return -1
+	}
+	if index > 0 {
+		return request.ShiftLength
+	}
+	return request.ShiftLength - slot*request.ShiftLength/slots
+}
+
+// Adds a match to a scouter's ranges, extending their last range if it was at the same driverstation and they scouted the match before
+func extendRange(ranges *[][3]int, dsOffset int, match int, continuing bool) {
+	if last := len(*ranges) - 1; continuing && last >= 0 && (*ranges)[last][0] == dsOffset {
+		(*ranges)[last][2] = match
+		return
+	}
+	*ranges = append(*ranges, [3]int{dsOffset, match, match})
+}
+
+// Returns if a scouter can scout a match
+func isAvailable(scouter ScouterAvailability, match int) bool {
+	if len(scouter.Available) == 0 {
+		return true
+	}
+	for _, window := range scouter.Available {
+		if match >= window[0] && match <= window[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces the whole schedule of every scouter in a map of usernames to their ranges, in one transaction so either
+// every schedule is written or none are. Scouters missing from the map keep theirs.
+func setSchedules(schedules map[string]ScoutRanges) error {
+	uuids := make(map[string]string)
+	for name := range schedules {
+		uuids[name], _ = userDB.GetUUID(name, true)
+	}
+
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
+
+	return inTransaction(func(tx *sql.Tx) error {
+		var replaced []scouterSchedule
+		for name, ranges := range schedules {
+			replaced = append(replaced, scouterSchedule{UUID: uuids[name], Name: name, Ranges: ranges})
+		}
+		return writeSchedules(tx, replaced)
+	})
+}
@@ -0,0 +1,577 @@
+package schedule
+
+// Utility for storing every scouter's schedule as individual ranges in scout.db
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/userDB"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Returned when a range that doesn't exist is changed
+var ErrRangeNotFound = errors.New("no range with that id exists")
+
+// Held while schedules change, so two changes can't move the same matches at once
+var scheduleLock sync.Mutex
+
+// One range of one scouter's schedule, as stored in scout.db
+type ScheduledRange struct {
+	ID       int64  // The identifier of the range
+	Scouter  string // The username of the scouter
+	DSOffset int    // The driverstation, from 0 (red1) to 5 (blue3)
+	First    int    // The first match
+	Last     int    // The last match
+}
+
+// Something sql statements can be executed on, either scout.db itself or a transaction on it
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// Something sql queries can be run on, either scout.db itself or a transaction on it
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// Runs a change to scout.db in a transaction, committing it if the change doesn't return an error
+func inTransaction(change func(tx *sql.Tx) error) error {
+	tx, beginErr := scoutDB.Begin()
+	if beginErr != nil {
+		greenlogger.LogError(beginErr, "Problem beginning transaction on scout.db")
+		return beginErr
+	}
+	defer tx.Rollback()
+
+	if changeErr := change(tx); changeErr != nil {
+		return changeErr
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		greenlogger.LogError(commitErr, "Problem committing transaction on scout.db")
+	}
+	return commitErr
+}
+
+// Makes sure a scouter has a row in individuals, keeping their username up to date
+func ensureIndividual(database execer, uuid string, name string) error {
+	_, execErr := database.Exec(
+		"insert into individuals values(?, ?, '') on conflict(uuid) do update set username = excluded.username",
+		uuid, name,
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql command %v with args %v", "insert into individuals ... on conflict(uuid) do update", []any{uuid, name})
+	}
+	return execErr
+}
+
+// Adds one range to a scouter's schedule at the current event, returning its id
+func insertRange(database execer, uuid string, scoutRange [3]int) (int64, error) {
+	result, execErr := database.Exec(
+		"insert into ranges(event, uuid, ds_offset, first, last) values(?,?,?,?,?)",
+		lib.GetCurrentEvent(), uuid, scoutRange[0], scoutRange[1], scoutRange[2],
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql command INSERT INTO ranges ... with args: %v, %v", uuid, scoutRange)
+		return 0, execErr
+	}
+
+	id, _ := result.LastInsertId()
+	return id, nil
+}
+
+// Reads one scouter's schedule at the current event, ordered by match
+func scheduleOf(database queryer, uuid string) (ScoutRanges, error) {
+	var ranges ScoutRanges
+
+	rows, queryErr := database.Query(
+		"select ds_offset, first, last from ranges where event = ? and uuid = ? order by first, ds_offset, id",
+		lib.GetCurrentEvent(), uuid,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM ranges WHERE uuid = ? with arg: %v", uuid)
+		return ranges, queryErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scoutRange [3]int
+		if scanErr := rows.Scan(&scoutRange[0], &scoutRange[1], &scoutRange[2]); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM ranges")
+			return ranges, scanErr
+		}
+		ranges.Ranges = append(ranges.Ranges, scoutRange)
+	}
+
+	return ranges, rows.Err()
+}
+
+// One scouter's whole schedule at the current event, as replaced by writeSchedules
+type scouterSchedule struct {
+	UUID   string      // The uuid of the scouter
+	Name   string      // The username of the scouter
+	Ranges ScoutRanges // Every range they should have
+}
+
+// A range already stored for a scouter whose schedule is being replaced
+type storedRange struct {
+	id         int64  // The identifier of the range
+	uuid       string // The uuid of its scouter
+	scoutRange [3]int // The range, as [dsoffset, first, last]
+	kept       bool   // If it was kept for one of the new ranges
+}
+
+// Reads the ranges one scouter has at the current event, with their ids
+func storedRangesOf(tx *sql.Tx, uuid string) ([]storedRange, error) {
+	var stored []storedRange
+
+	rows, queryErr := tx.Query("select id, ds_offset, first, last from ranges where event = ? and uuid = ? order by first, ds_offset, id", lib.GetCurrentEvent(), uuid)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT id, ... FROM ranges WHERE uuid = ? with arg: %v", uuid)
+		return stored, queryErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		existing := storedRange{uuid: uuid}
+		if scanErr := rows.Scan(&existing.id, &existing.scoutRange[0], &existing.scoutRange[1], &existing.scoutRange[2]); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT id, ... FROM ranges")
+			return stored, scanErr
+		}
+		stored = append(stored, existing)
+	}
+
+	return stored, rows.Err()
+}
+
+// Replaces the whole schedules of scouters at the current event. Ranges that didn't change keep their ids, so anything
+// referring to them, like an open swap or a client editing them, still can. A range that moved to another of the
+// scouters keeps its id, and one that was shortened or split keeps its id for the first piece that overlaps it.
+func writeSchedules(tx *sql.Tx, schedules []scouterSchedule) error {
+	var stored []storedRange
+	for _, schedule := range schedules {
+		// Scouters whose ranges are all being removed don't need a row in individuals
+		if len(schedule.Ranges.Ranges) > 0 {
+			if individualErr := ensureIndividual(tx, schedule.UUID, schedule.Name); individualErr != nil {
+				return individualErr
+			}
+		}
+
+		scoutersRanges, storedErr := storedRangesOf(tx, schedule.UUID)
+		if storedErr != nil {
+			return storedErr
+		}
+		stored = append(stored, scoutersRanges...)
+	}
+
+	// Each new range keeps the first stored one that fits it best: the same range of the same scouter, then the same
+	// range of another scouter, then an overlapping range of the same scouter at the same driverstation
+	matchers := []func(existing storedRange, uuid string, scoutRange [3]int) bool{
+		func(existing storedRange, uuid string, scoutRange [3]int) bool {
+			return existing.uuid == uuid && existing.scoutRange == scoutRange
+		},
+		func(existing storedRange, uuid string, scoutRange [3]int) bool {
+			return existing.scoutRange == scoutRange
+		},
+		func(existing storedRange, uuid string, scoutRange [3]int) bool {
+			return existing.uuid == uuid && existing.scoutRange[0] == scoutRange[0] &&
+				existing.scoutRange[1] <= scoutRange[2] && scoutRange[1] <= existing.scoutRange[2]
+		},
+	}
+
+	placed := make([][]bool, len(schedules))
+	for i, schedule := range schedules {
+		placed[i] = make([]bool, len(schedule.Ranges.Ranges))
+	}
+
+	for _, matches := range matchers {
+		for i, schedule := range schedules {
+			for j, scoutRange := range schedule.Ranges.Ranges {
+				if placed[i][j] {
+					continue
+				}
+
+				for k := range stored {
+					if stored[k].kept || !matches(stored[k], schedule.UUID, scoutRange) {
+						continue
+					}
+
+					if stored[k].uuid != schedule.UUID || stored[k].scoutRange != scoutRange {
+						_, execErr := tx.Exec(
+							"update ranges set uuid = ?, ds_offset = ?, first = ?, last = ? where id = ?",
+							schedule.UUID, scoutRange[0], scoutRange[1], scoutRange[2], stored[k].id,
+						)
+						if execErr != nil {
+							greenlogger.LogErrorf(execErr, "Problem executing sql command UPDATE ranges ... WHERE id = ? with args: %v, %v, %v", schedule.UUID, scoutRange, stored[k].id)
+							return execErr
+						}
+					}
+
+					stored[k].kept = true
+					placed[i][j] = true
+					break
+				}
+			}
+		}
+	}
+
+	for i, schedule := range schedules {
+		for j, scoutRange := range schedule.Ranges.Ranges {
+			if placed[i][j] {
+				continue
+			}
+			if _, insertErr := insertRange(tx, schedule.UUID, scoutRange); insertErr != nil {
+				return insertErr
+			}
+		}
+	}
+
+	for _, existing := range stored {
+		if existing.kept {
+			continue
+		}
+		if _, execErr := tx.Exec("delete from ranges where id = ?", existing.id); execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem executing sql command DELETE FROM ranges WHERE id = ? with arg: %v", existing.id)
+			return execErr
+		}
+	}
+
+	return nil
+}
+
+// The columns of a range and its scouter's username, in the order scanRange reads them
+const rangeColumns = `select ranges.id, coalesce(individuals.username, ''), ranges.uuid, ds_offset, first, last
+	from ranges left join individuals on individuals.uuid = ranges.uuid`
+
+// Scans one range and its scouter's username
+func scanRange(row interface{ Scan(...any) error }) (ScheduledRange, string, error) {
+	var scheduled ScheduledRange
+	var uuid string
+
+	scanErr := row.Scan(&scheduled.ID, &scheduled.Scouter, &uuid, &scheduled.DSOffset, &scheduled.First, &scheduled.Last)
+	if scanErr == nil && scheduled.Scouter == "" {
+		scheduled.Scouter = userDB.UUIDToUser(uuid)
+	}
+	return scheduled, uuid, scanErr
+}
+
+// Returns every range at the current event, ordered by scouter and match. If username isn't empty, only their ranges are returned.
+func GetRanges(username string) []ScheduledRange {
+	var results []ScheduledRange
+
+	rows, queryErr := scoutDB.Query(
+		rangeColumns+" where event = ? and (? = '' or individuals.username = ?) order by individuals.username, first, ds_offset",
+		lib.GetCurrentEvent(), username, username,
+	)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM ranges with arg: %v", username)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		scheduled, _, scanErr := scanRange(rows)
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM ranges")
+			continue
+		}
+		results = append(results, scheduled)
+	}
+
+	return results
+}
+
+// Returns one range at the current event
+func GetRange(id int64) (ScheduledRange, error) {
+	scheduled, _, scanErr := scanRange(scoutDB.QueryRow(rangeColumns+" where ranges.id = ? and event = ?", id, lib.GetCurrentEvent()))
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return scheduled, ErrRangeNotFound
+	}
+	if scanErr != nil {
+		greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT ... FROM ranges WHERE id = ? AND event = ? with arg: %v", id)
+	}
+	return scheduled, scanErr
+}
+
+// Returns the schedule of every scouter at the current event, by username
+func GetAllSchedules() map[string]ScoutRanges {
+	schedules := make(map[string]ScoutRanges)
+
+	for _, scheduled := range GetRanges("") {
+		ranges := schedules[scheduled.Scouter]
+		ranges.Ranges = append(ranges.Ranges, [3]int{scheduled.DSOffset, scheduled.First, scheduled.Last})
+		schedules[scheduled.Scouter] = ranges
+	}
+
+	return schedules
+}
+
+// Returns the uuid of a scouter who has an account, after checking a range is valid
+func validateRange(scheduled ScheduledRange) (string, error) {
+	if !validRange([3]int{scheduled.DSOffset, scheduled.First, scheduled.Last}) {
+		return "", ErrInvalidRange
+	}
+
+	uuid, exists := userDB.GetUUID(scheduled.Scouter, false)
+	if !exists {
+		return "", ErrUnknownScouter
+	}
+	return uuid, nil
+}
+
+// Adds one range to a scouter's schedule at the current event
+func CreateRange(scheduled ScheduledRange) (ScheduledRange, error) {
+	uuid, validationErr := validateRange(scheduled)
+	if validationErr != nil {
+		return ScheduledRange{}, validationErr
+	}
+
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
+
+	var id int64
+	transactionErr := inTransaction(func(tx *sql.Tx) error {
+		if individualErr := ensureIndividual(tx, uuid, scheduled.Scouter); individualErr != nil {
+			return individualErr
+		}
+
+		var insertErr error
+		id, insertErr = insertRange(tx, uuid, [3]int{scheduled.DSOffset, scheduled.First, scheduled.Last})
+		return insertErr
+	})
+	if transactionErr != nil {
+		return ScheduledRange{}, transactionErr
+	}
+
+	return GetRange(id)
+}
+
+// Changes one range at the current event, which can also move it to another scouter
+func UpdateRange(scheduled ScheduledRange) (ScheduledRange, error) {
+	uuid, validationErr := validateRange(scheduled)
+	if validationErr != nil {
+		return ScheduledRange{}, validationErr
+	}
+
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
+
+	transactionErr := inTransaction(func(tx *sql.Tx) error {
+		if individualErr := ensureIndividual(tx, uuid, scheduled.Scouter); individualErr != nil {
+			return individualErr
+		}
+
+		result, execErr := tx.Exec(
+			"update ranges set uuid = ?, ds_offset = ?, first = ?, last = ? where id = ? and event = ?",
+			uuid, scheduled.DSOffset, scheduled.First, scheduled.Last, scheduled.ID, lib.GetCurrentEvent(),
+		)
+		if execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem executing sql command UPDATE ranges ... WHERE id = ? AND event = ? with args: %v", scheduled)
+			return execErr
+		}
+
+		if updated, _ := result.RowsAffected(); updated == 0 {
+			return ErrRangeNotFound
+		}
+		return nil
+	})
+	if transactionErr != nil {
+		return ScheduledRange{}, transactionErr
+	}
+
+	return GetRange(scheduled.ID)
+}
+
+// Removes one range at the current event from its scouter's schedule
+func DeleteRange(id int64) error {
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
+
+	result, execErr := scoutDB.Exec("delete from ranges where id = ? and event = ?", id, lib.GetCurrentEvent())
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql command DELETE FROM ranges WHERE id = ? AND event = ? with arg: %v", id)
+		return execErr
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return ErrRangeNotFound
+	}
+	return nil
+}
+
+// Replaces every schedule at the current event with the passed in ones, by username. Nothing changes unless every
+// range is valid and every scouter has an account.
+func ReplaceAllSchedules(schedules map[string]ScoutRanges) error {
+	uuids := make(map[string]string)
+	for name, ranges := range schedules {
+		uuid, exists := userDB.GetUUID(name, false)
+		if !exists {
+			return ErrUnknownScouter
+		}
+		for _, scoutRange := range ranges.Ranges {
+			if !validRange(scoutRange) {
+				return ErrInvalidRange
+			}
+		}
+		uuids[name] = uuid
+	}
+
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
+
+	transactionErr := inTransaction(func(tx *sql.Tx) error {
+		// Scouters left out of the new schedules have every range removed
+		written := make(map[string]bool)
+		var replaced []scouterSchedule
+		for name, ranges := range schedules {
+			written[uuids[name]] = true
+			replaced = append(replaced, scouterSchedule{UUID: uuids[name], Name: name, Ranges: ranges})
+		}
+
+		rows, queryErr := tx.Query("select distinct uuid from ranges where event = ?", lib.GetCurrentEvent())
+		if queryErr != nil {
+			greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT DISTINCT uuid FROM ranges WHERE event = ? with arg: %v", lib.GetCurrentEvent())
+			return queryErr
+		}
+		for rows.Next() {
+			var uuid string
+			if scanErr := rows.Scan(&uuid); scanErr != nil {
+				rows.Close()
+				greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT DISTINCT uuid FROM ranges")
+				return scanErr
+			}
+			if !written[uuid] {
+				replaced = append(replaced, scouterSchedule{UUID: uuid})
+			}
+		}
+		rows.Close()
+
+		return writeSchedules(tx, replaced)
+	})
+
+	if transactionErr == nil {
+		greenlogger.LogMessagef("Replaced every schedule at %v with those of %v scouters", lib.GetCurrentEvent(), len(schedules))
+	}
+	return transactionErr
+}
+
+// Removes every range of every scouter at every event, and cancels every swap that hasn't been completed, as what they'd
+// trade is gone. Both happen in one transaction, with each cancellation recorded in the audit trail as done by username.
+// Returns if they were successfully removed.
+func ClearAllSchedules(username string) bool {
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
+
+	now := time.Now()
+	transactionErr := inTransaction(func(tx *sql.Tx) error {
+		if _, execErr := tx.Exec("delete from ranges"); execErr != nil {
+			greenlogger.LogError(execErr, "Problem executing sql command DELETE FROM ranges")
+			return execErr
+		}
+
+		rows, queryErr := tx.Query("select id from swaps where status in (?, ?)", SwapPending, SwapAccepted)
+		if queryErr != nil {
+			greenlogger.LogError(queryErr, "Problem executing sql query SELECT id FROM swaps WHERE status IN (?, ?)")
+			return queryErr
+		}
+
+		var open []int64
+		for rows.Next() {
+			var id int64
+			if scanErr := rows.Scan(&id); scanErr != nil {
+				rows.Close()
+				greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT id FROM swaps")
+				return scanErr
+			}
+			open = append(open, id)
+		}
+		rows.Close()
+
+		for _, id := range open {
+			if _, execErr := tx.Exec("update swaps set status = ?, updated_at = ? where id = ?", SwapCancelled, now.UnixMilli(), id); execErr != nil {
+				greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE swaps SET status = ? WHERE id = ? with args: %v, %v", SwapCancelled, id)
+				return execErr
+			}
+
+			entry := SwapAuditEntry{SwapID: id, Username: username, Action: SwapActionCancel, Detail: "every schedule was cleared", At: now}
+			if auditErr := recordSwapAudit(tx, entry); auditErr != nil {
+				return auditErr
+			}
+		}
+		return nil
+	})
+	if transactionErr != nil {
+		return false
+	}
+
+	greenlogger.LogMessage("Cleared every scouter's schedule and cancelled every open swap")
+	return true
+}
+
+// Moves schedules stored the old way, as JSON in the schedule column of individuals, into ranges at the passed in event.
+// Run by setup before anything else opens scout.db.
+func MigrateSchedules(database *sql.DB, event string) {
+	rows, queryErr := database.Query("select uuid, schedule from individuals where schedule is not null and schedule != ''")
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query %v", "SELECT uuid, schedule FROM individuals")
+		return
+	}
+
+	legacy := make(map[string]ScoutRanges)
+	for rows.Next() {
+		var uuid, schedule string
+		if scanErr := rows.Scan(&uuid, &schedule); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT uuid, schedule FROM individuals")
+			continue
+		}
+
+		var ranges ScoutRanges
+		if unmarshalErr := json.Unmarshal([]byte(schedule), &ranges); unmarshalErr != nil {
+			greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling the schedule of %v, leaving it unmigrated", uuid)
+			continue
+		}
+		legacy[uuid] = ranges
+	}
+	rows.Close()
+
+	if len(legacy) == 0 {
+		return
+	}
+
+	tx, beginErr := database.Begin()
+	if beginErr != nil {
+		greenlogger.LogError(beginErr, "Problem beginning transaction on scout.db")
+		return
+	}
+	defer tx.Rollback()
+
+	for uuid, ranges := range legacy {
+		for _, scoutRange := range ranges.Ranges {
+			_, execErr := tx.Exec(
+				"insert into ranges(event, uuid, ds_offset, first, last) values(?,?,?,?,?)",
+				event, uuid, scoutRange[0], scoutRange[1], scoutRange[2],
+			)
+			if execErr != nil {
+				greenlogger.LogErrorf(execErr, "Problem migrating the schedule of %v", uuid)
+				return
+			}
+		}
+
+		if _, execErr := tx.Exec("update individuals set schedule = '' where uuid = ?", uuid); execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem migrating the schedule of %v", uuid)
+			return
+		}
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		greenlogger.LogError(commitErr, "Problem committing migrated schedules")
+		return
+	}
+
+	greenlogger.LogMessagef("Migrated the schedules of %v scouters to %v", len(legacy), event)
+}
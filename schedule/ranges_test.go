@@ -0,0 +1,137 @@
+package schedule
+
+import (
+	"GreenScoutBackend/constants"
+	"GreenScoutBackend/userDB"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// Sets up users.db and scout.db in a temporary directory, with the scouters alice and bob
+func setupRangesTest(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	constants.CachedConfigs.SqliteDriver = "sqlite3"
+	constants.CachedConfigs.PathToDatabases = dir
+	constants.CachedConfigs.RuntimeDirectory = dir
+	constants.CachedConfigs.EventKey = "2024test"
+	constants.CachedConfigs.SwapConfigs.RequireApproval = false
+
+	// users and individuals are made by hand or by setup on real servers, so they're made here the same way
+	users, openErr := sql.Open("sqlite3", filepath.Join(dir, "users.db"))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer users.Close()
+	if _, execErr := users.Exec("create table users(uuid, username, displayname, certificate, badges, score, pfp, lifescore, highscore, accolades, color)"); execErr != nil {
+		t.Fatal(execErr)
+	}
+
+	userDB.InitUserDB()
+	userDB.NewUser("alice", "alice-uuid")
+	userDB.NewUser("bob", "bob-uuid")
+
+	InitScoutDB()
+	t.Cleanup(func() { scoutDB.Close() })
+	statements := append([]string{"create table individuals(uuid string not null primary key, username string, schedule string)"}, Schema...)
+	for _, statement := range statements {
+		if _, execErr := scoutDB.Exec(statement); execErr != nil {
+			t.Fatal(execErr)
+		}
+	}
+}
+
+// Adds a range to a scouter's schedule, failing the test if it can't be
+func mustCreateRange(t *testing.T, scouter string, scoutRange [3]int) ScheduledRange {
+	t.Helper()
+
+	created, err := CreateRange(ScheduledRange{Scouter: scouter, DSOffset: scoutRange[0], First: scoutRange[1], Last: scoutRange[2]})
+	if err != nil {
+		t.Fatalf("CreateRange(%v, %v) returned %v", scouter, scoutRange, err)
+	}
+	return created
+}
+
+// Fails the test unless the range with an id belongs to a scouter and covers scoutRange
+func checkRange(t *testing.T, id int64, scouter string, scoutRange [3]int) {
+	t.Helper()
+
+	scheduled, err := GetRange(id)
+	if err != nil {
+		t.Errorf("GetRange(%v) returned %v, want %v's %v", id, err, scouter, scoutRange)
+		return
+	}
+	if scheduled.Scouter != scouter || [3]int{scheduled.DSOffset, scheduled.First, scheduled.Last} != scoutRange {
+		t.Errorf("range %v is %+v, want %v's %v", id, scheduled, scouter, scoutRange)
+	}
+}
+
+// Proposes a giveaway of a range from alice to bob and has bob accept it, failing the test if it isn't completed
+func giveToBob(t *testing.T, give [3]int) {
+	t.Helper()
+
+	swap, err := ProposeSwap(SwapProposal{Target: "bob", Give: give}, "alice")
+	if err != nil {
+		t.Fatalf("ProposeSwap returned %v", err)
+	}
+	if swap, err = AcceptSwap(swap.ID, "bob"); err != nil || swap.Status != SwapCompleted {
+		t.Fatalf("AcceptSwap returned %+v, %v; want a completed swap", swap, err)
+	}
+}
+
+func TestSwapKeepsRangeIDs(t *testing.T) {
+	setupRangesTest(t)
+
+	split := mustCreateRange(t, "alice", [3]int{0, 1, 10})
+	untouched := mustCreateRange(t, "alice", [3]int{1, 20, 30})
+	bobs := mustCreateRange(t, "bob", [3]int{2, 40, 50})
+
+	giveToBob(t, [3]int{0, 1, 5})
+
+	checkRange(t, split.ID, "alice", [3]int{0, 6, 10})
+	checkRange(t, untouched.ID, "alice", [3]int{1, 20, 30})
+	checkRange(t, bobs.ID, "bob", [3]int{2, 40, 50})
+
+	// A range can still be changed by the id it had before the swap
+	if _, err := UpdateRange(ScheduledRange{ID: untouched.ID, Scouter: "alice", DSOffset: 1, First: 20, Last: 25}); err != nil {
+		t.Errorf("UpdateRange(%v) after a swap returned %v", untouched.ID, err)
+	}
+}
+
+func TestSwapMovesWholeRange(t *testing.T) {
+	setupRangesTest(t)
+
+	given := mustCreateRange(t, "alice", [3]int{3, 1, 10})
+	giveToBob(t, [3]int{3, 1, 10})
+
+	checkRange(t, given.ID, "bob", [3]int{3, 1, 10})
+	if ranges := GetRanges("alice"); len(ranges) != 0 {
+		t.Errorf("alice still has %+v after giving away their only range", ranges)
+	}
+}
+
+func TestReplaceAllSchedulesKeepsRangeIDs(t *testing.T) {
+	setupRangesTest(t)
+
+	kept := mustCreateRange(t, "alice", [3]int{0, 1, 10})
+	removed := mustCreateRange(t, "alice", [3]int{1, 20, 30})
+	bobs := mustCreateRange(t, "bob", [3]int{2, 1, 10})
+
+	err := ReplaceAllSchedules(map[string]ScoutRanges{"alice": {Ranges: [][3]int{{0, 1, 10}, {4, 40, 50}}}})
+	if err != nil {
+		t.Fatalf("ReplaceAllSchedules returned %v", err)
+	}
+
+	checkRange(t, kept.ID, "alice", [3]int{0, 1, 10})
+	for _, id := range []int64{removed.ID, bobs.ID} {
+		if _, err := GetRange(id); !errors.Is(err, ErrRangeNotFound) {
+			t.Errorf("GetRange(%v) of a removed range returned %v, want %v", id, err, ErrRangeNotFound)
+		}
+	}
+	if ranges := GetRanges("alice"); len(ranges) != 2 {
+		t.Errorf("alice has %+v, want 2 ranges", ranges)
+	}
+}
@@ -0,0 +1,322 @@
+package schedule
+
+// Utility for checking the stored schedules against the match schedule and what was actually scouted
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/userDB"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The most matches in a row anyone can be scheduled for before their shift is reported as too long, unless another limit is passed in
+const DefaultMaxShiftLength = 10
+
+// Every problem found with the stored schedules of the current event
+type CoverageReport struct {
+	Event          string            // The event key
+	Matches        int               // The number of matches in schedule.json
+	MaxShiftLength int               // The longest shift allowed before it's reported
+	Uncovered      []UncoveredSlot   // Every driverstation of every match nobody is scheduled for
+	DoubleBookings []DoubleBooking   // Every match a scouter is scheduled at more than one driverstation in
+	LongShifts     []LongShift       // Every shift longer than MaxShiftLength
+	InvalidRanges  []InvalidRange    // Every range that isn't a driverstation from 0 to 5 over matches in schedule.json
+	Unassigned     []string          // Every user without any scheduled matches
+	Scouters       []ScouterCoverage // How every scheduled scouter's schedule compares to what they submitted, by username
+	Missed         []ScheduledSlot   // Every slot a scouter was scheduled for but didn't submit, in matches that have been scouted
+	Unscheduled    []SubmittedSlot   // Every submission in Written from a scouter who wasn't scheduled for it
+}
+
+// A match a scouter is scheduled at more than one driverstation in
+type DoubleBooking struct {
+	Scouter   string // The username of the scouter
+	Match     int    // The match number
+	DSOffsets []int  // The driverstations they're scheduled at, from 0 (red1) to 5 (blue3)
+}
+
+// A run of matches in a row that one scouter is scheduled for
+type LongShift struct {
+	Scouter string // The username of the scouter
+	First   int    // The first match of the shift
+	Last    int    // The last match of the shift
+	Length  int    // The number of matches in the shift
+}
+
+// A stored range that can't be scouted as written
+type InvalidRange struct {
+	Scouter string // The username of the scouter
+	Range   [3]int // The range, as [dsoffset, starting, ending]
+}
+
+// One driverstation of one match a scouter is scheduled for
+type ScheduledSlot struct {
+	Scouter  string // The username of the scouter
+	Match    int    // The match number
+	DSOffset int    // The driverstation, from 0 (red1) to 5 (blue3)
+}
+
+// One submission in Written
+type SubmittedSlot struct {
+	Scouter  string // The scouter the submission was recorded by
+	Match    int    // The match number
+	DSOffset int    // The driverstation, from 0 (red1) to 5 (blue3)
+	File     string // The name of the file in Written
+}
+
+// How one scouter's schedule compares to what they submitted
+type ScouterCoverage struct {
+	Scouter   string // The username of the scouter
+	Scheduled int    // The number of slots they're scheduled for
+	Due       int    // The number of those slots in matches that have been scouted
+	Submitted int    // The number of those slots they submitted
+	Missed    int    // The number of those slots they didn't submit
+}
+
+// One driverstation of one match
+type slotKey struct {
+	match    int
+	dsOffset int
+}
+
+// Checks every stored schedule against schedule.json and the current event's submissions in Written.
+// Shifts longer than maxShiftLength matches in a row are reported; DefaultMaxShiftLength is used if it isn't positive.
+func GenerateCoverageReport(maxShiftLength int) CoverageReport {
+	if maxShiftLength <= 0 {
+		maxShiftLength = DefaultMaxShiftLength
+	}
+
+	report := CoverageReport{Event: lib.GetCurrentEvent(), Matches: lib.GetNumMatches(), MaxShiftLength: maxShiftLength}
+
+	schedules := GetAllSchedules()
+	scheduled := make(map[slotKey][]string)
+
+	var scouters []string
+	for scouter := range schedules {
+		scouters = append(scouters, scouter)
+	}
+	sort.Strings(scouters)
+
+	slotsByScouter := make(map[string][]slotKey)
+	for _, scouter := range scouters {
+		slots := report.expandSchedule(scouter, schedules[scouter])
+		for _, slot := range slots {
+			scheduled[slot] = append(scheduled[slot], scouter)
+		}
+		slotsByScouter[scouter] = slots
+		report.checkShifts(scouter, slots)
+	}
+
+	for match := 1; match <= report.Matches; match++ {
+		for dsOffset := 0; dsOffset < kDriverStations; dsOffset++ {
+			if len(scheduled[slotKey{match, dsOffset}]) == 0 {
+				report.Uncovered = append(report.Uncovered, UncoveredSlot{Match: match, DSOffset: dsOffset, Missing: 1})
+			}
+		}
+	}
+
+	for _, user := range userDB.GetAllUsers() {
+		if len(schedules[user.Name].Ranges) == 0 {
+			report.Unassigned = append(report.Unassigned, user.Name)
+		}
+	}
+	sort.Strings(report.Unassigned)
+
+	report.compareSubmissions(scouters, slotsByScouter, scheduled)
+
+	return report
+}
+
+// Expands one scouter's ranges into every slot they're scheduled for, ordered by match, recording invalid ranges and double bookings
+func (report *CoverageReport) expandSchedule(scouter string, ranges ScoutRanges) []slotKey {
+	stations := make(map[int][]int)
+
+	for _, scoutRange := range ranges.Ranges {
+		dsOffset, first, last := scoutRange[0], scoutRange[1], scoutRange[2]
+		if dsOffset < 0 || dsOffset >= kDriverStations || first < 1 || first > last || last > report.Matches {
+			report.InvalidRanges = append(report.InvalidRanges, InvalidRange{Scouter: scouter, Range: scoutRange})
+			continue
+		}
+
+		for match := first; match <= last; match++ {
+			if !slices.Contains(stations[match], dsOffset) {
+				stations[match] = append(stations[match], dsOffset)
+			}
+		}
+	}
+
+	var slots []slotKey
+	for match := 1; match <= report.Matches; match++ {
+		offsets := stations[match]
+		if len(offsets) == 0 {
+			continue
+		}
+
+		sort.Ints(offsets)
+		if len(offsets) > 1 {
+			report.DoubleBookings = append(report.DoubleBookings, DoubleBooking{Scouter: scouter, Match: match, DSOffsets: offsets})
+		}
+		for _, dsOffset := range offsets {
+			slots = append(slots, slotKey{match, dsOffset})
+		}
+	}
+
+	return slots
+}
+
+// Records every run of matches in a row in a scouter's slots that is longer than the maximum shift length
+func (report *CoverageReport) checkShifts(scouter string, slots []slotKey) {
+	var matches []int
+	for _, slot := range slots {
+		if len(matches) == 0 || matches[len(matches)-1] != slot.match {
+			matches = append(matches, slot.match)
+		}
+	}
+
+	for start := 0; start < len(matches); {
+		end := start
+		for end+1 < len(matches) && matches[end+1] == matches[end]+1 {
+			end++
+		}
+
+		if length := end - start + 1; length > report.MaxShiftLength {
+			report.LongShifts = append(report.LongShifts, LongShift{Scouter: scouter, First: matches[start], Last: matches[end], Length: length})
+		}
+		start = end + 1
+	}
+}
+
+// Compares every scheduled slot with the current event's submissions in Written. Slots are only due once their match has been
+// scouted by anyone, so matches that haven't been played yet aren't reported as missed.
+func (report *CoverageReport) compareSubmissions(scouters []string, slotsByScouter map[string][]slotKey, scheduled map[slotKey][]string) {
+	submitted := make(map[slotKey][]string)
+	scouted := make(map[int]bool)
+
+	for _, submission := range writtenSubmissions(report.Event) {
+		slot := slotKey{submission.Match, submission.DSOffset}
+		submitted[slot] = append(submitted[slot], submission.Scouter)
+		scouted[submission.Match] = true
+
+		if !slices.ContainsFunc(scheduled[slot], func(scouter string) bool { return sameScouter(submission.Scouter, scouter) }) {
+			report.Unscheduled = append(report.Unscheduled, submission)
+		}
+	}
+
+	for _, scouter := range scouters {
+		coverage := ScouterCoverage{Scouter: scouter, Scheduled: len(slotsByScouter[scouter])}
+
+		for _, slot := range slotsByScouter[scouter] {
+			if !scouted[slot.match] {
+				continue
+			}
+
+			coverage.Due++
+			if slices.ContainsFunc(submitted[slot], func(recorded string) bool { return sameScouter(recorded, scouter) }) {
+				coverage.Submitted++
+			} else {
+				coverage.Missed++
+				report.Missed = append(report.Missed, ScheduledSlot{Scouter: scouter, Match: slot.match, DSOffset: slot.dsOffset})
+			}
+		}
+
+		report.Scouters = append(report.Scouters, coverage)
+	}
+}
+
+// Returns if the scouter recorded on a submission is a username, ignoring case and surrounding spaces
+func sameScouter(recorded string, username string) bool {
+	return strings.EqualFold(strings.TrimSpace(recorded), strings.TrimSpace(username))
+}
+
+// Returns every match scouting submission in Written for an event, read from the files themselves
+func writtenSubmissions(event string) []SubmittedSlot {
+	var submissions []SubmittedSlot
+
+	written, readErr := os.ReadDir(constants.JsonWrittenDirectory)
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", constants.JsonWrittenDirectory)
+		return submissions
+	}
+
+	for _, file := range written {
+		// EVENT_MATCH_DRIVERSTATION_TIME.json
+		splitByUnder := strings.Split(file.Name(), "_")
+		if len(splitByUnder) < 4 || splitByUnder[0] != event || !slices.Contains(stationNames, splitByUnder[2]) {
+			continue
+		}
+
+		match, atoiErr := strconv.Atoi(splitByUnder[1])
+		if atoiErr != nil {
+			continue
+		}
+
+		team, hadErrs := lib.Parse(file.Name(), true)
+		if hadErrs {
+			continue
+		}
+
+		submissions = append(submissions, SubmittedSlot{
+			Scouter:  team.Scouter,
+			Match:    match,
+			DSOffset: lib.GetDSOffset(splitByUnder[2]),
+			File:     file.Name(),
+		})
+	}
+
+	sort.Slice(submissions, func(i, j int) bool {
+		if submissions[i].Match != submissions[j].Match {
+			return submissions[i].Match < submissions[j].Match
+		}
+		return submissions[i].DSOffset < submissions[j].DSOffset
+	})
+
+	return submissions
+}
+
+// Logs a coverage report in a readable form, for the report command
+func LogCoverageReport(report CoverageReport) {
+	greenlogger.LogMessagef("Schedule coverage of %v over %v matches", report.Event, report.Matches)
+
+	greenlogger.LogMessagef("%v uncovered driverstations", len(report.Uncovered))
+	for _, slot := range report.Uncovered {
+		greenlogger.LogMessagef("  match %v %v", slot.Match, stationNames[slot.DSOffset])
+	}
+
+	greenlogger.LogMessagef("%v double bookings", len(report.DoubleBookings))
+	for _, booking := range report.DoubleBookings {
+		var booked []string
+		for _, dsOffset := range booking.DSOffsets {
+			booked = append(booked, stationNames[dsOffset])
+		}
+		greenlogger.LogMessagef("  %v in match %v at %v", booking.Scouter, booking.Match, strings.Join(booked, ", "))
+	}
+
+	greenlogger.LogMessagef("%v shifts longer than %v matches", len(report.LongShifts), report.MaxShiftLength)
+	for _, shift := range report.LongShifts {
+		greenlogger.LogMessagef("  %v from match %v to %v (%v matches)", shift.Scouter, shift.First, shift.Last, shift.Length)
+	}
+
+	greenlogger.LogMessagef("%v invalid ranges", len(report.InvalidRanges))
+	for _, invalid := range report.InvalidRanges {
+		greenlogger.LogMessagef("  %v: %v", invalid.Scouter, invalid.Range)
+	}
+
+	greenlogger.LogMessagef("%v users with no assignment: %v", len(report.Unassigned), strings.Join(report.Unassigned, ", "))
+
+	greenlogger.LogMessage("Submitted against scheduled, in matches that have been scouted")
+	for _, coverage := range report.Scouters {
+		greenlogger.LogMessagef("  %v: %v of %v submitted, %v missed, %v scheduled overall", coverage.Scouter, coverage.Submitted, coverage.Due, coverage.Missed, coverage.Scheduled)
+	}
+	for _, slot := range report.Missed {
+		greenlogger.LogMessagef("  %v missed match %v %v", slot.Scouter, slot.Match, stationNames[slot.DSOffset])
+	}
+
+	greenlogger.LogMessagef("%v submissions from scouters who weren't scheduled for them", len(report.Unscheduled))
+	for _, submission := range report.Unscheduled {
+		greenlogger.LogMessagef("  %v scouted match %v %v (%v)", submission.Scouter, submission.Match, stationNames[submission.DSOffset], submission.File)
+	}
+}
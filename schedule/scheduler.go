@@ -9,13 +9,46 @@ import (
 	"GreenScoutBackend/userDB"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"path/filepath"
 )
 
 // Reference to the SQL scouting database
 var scoutDB *sql.DB
 
+// The statements that create every table of scout.db besides individuals if they do not already exist
+var Schema = []string{
+	`create table if not exists ranges(
+		id integer primary key autoincrement,
+		event text not null,
+		uuid text not null,
+		ds_offset integer not null,
+		first integer not null,
+		last integer not null)`,
+	`create index if not exists ranges_by_scouter on ranges(event, uuid)`,
+	`create table if not exists swaps(
+		id integer primary key autoincrement,
+		event text not null,
+		proposer text not null,
+		target text not null default '',
+		give text not null,
+		take text not null default 'null',
+		note text not null default '',
+		status text not null,
+		accepter text not null default '',
+		approver text not null default '',
+		created_at integer not null,
+		updated_at integer not null)`,
+	`create table if not exists swap_audit(
+		id integer primary key autoincrement,
+		swap_id integer not null,
+		username text not null,
+		action text not null,
+		detail text not null default '',
+		at integer not null)`,
+	`create index if not exists swaps_by_event on swaps(event, created_at)`,
+	`create index if not exists swap_audit_by_swap on swap_audit(swap_id, at)`,
+}
+
 // Opens the reference to the scouting database
 func InitScoutDB() {
 	dbPath := filepath.Join(constants.CachedConfigs.RuntimeDirectory, "scout.db")
@@ -33,103 +66,66 @@ type ScoutRanges struct {
 	Ranges [][3]int `json:"Ranges"` // A an array of arrays of ints of length 3, [dsoffset, starting, ending]
 }
 
-// Gets the schedule of one scouter
+// Gets the schedule of one scouter at the current event
 func RetrieveSingleScouter(name string, isUUID bool) string {
-	var uuid string
-	if isUUID {
-		uuid = name
-	} else {
-		uuid, _ = userDB.GetUUID(name, true)
-	}
-
-	response := scoutDB.QueryRow("select schedule from individuals where uuid = ?", uuid)
-
-	var ranges string
-
-	scanErr := response.Scan(&ranges)
-	if scanErr != nil && !errors.Is(scanErr, sql.ErrNoRows) {
-		greenlogger.LogErrorf(scanErr, "Problem scanning response %v", response)
+	ranges := retrieveScouterAsObject(name, isUUID)
+	if len(ranges.Ranges) == 0 {
+		return `{"Ranges":null}`
 	}
 
-	if ranges == "" {
+	rangeBytes, marshalErr := json.Marshal(ranges)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", ranges)
 		return `{"Ranges":null}`
-	} else {
-		return ranges
 	}
+
+	return string(rangeBytes)
 }
 
-// Gets the schedule of one scouter, marshalled as a ScoutRanges object
+// Gets the schedule of one scouter at the current event, as a ScoutRanges object
 func retrieveScouterAsObject(name string, isUUID bool) ScoutRanges {
-
-	scheduleString := RetrieveSingleScouter(name, isUUID)
-
-	var ranges ScoutRanges
-
-	unmarshalErr := json.Unmarshal([]byte(scheduleString), &ranges)
-	if unmarshalErr != nil {
-		greenlogger.LogErrorf(unmarshalErr, "Problem Unmarshalling %v", []byte(scheduleString))
+	var uuid string
+	if isUUID {
+		uuid = name
+	} else {
+		uuid, _ = userDB.GetUUID(name, true)
 	}
 
+	ranges, _ := scheduleOf(scoutDB, uuid)
 	return ranges
 }
 
-// Adds a schedule update to an individual
-func AddIndividualSchedule(name string, nameIsUUID bool, ranges ScoutRanges) {
+// Adds ranges to the schedule of one scouter at the current event, keeping the ones they already have
+func AddIndividualSchedule(name string, nameIsUUID bool, ranges ScoutRanges) error {
+	for _, scoutRange := range ranges.Ranges {
+		if !validRange(scoutRange) {
+			return ErrInvalidRange
+		}
+	}
 
-	var uuid string
+	var uuid, username string
 	if nameIsUUID {
-		uuid = name
+		uuid, username = name, userDB.UUIDToUser(name)
 	} else {
 		uuid, _ = userDB.GetUUID(name, true)
+		username = name
 	}
 
-	rangeBytes, marshalErr := json.Marshal(ranges)
-	if marshalErr != nil {
-		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", ranges)
-	}
-
-	rangeString := string(rangeBytes)
-
-	if userInSchedule(scoutDB, uuid) { //If doesn't exist
-		cachedRanges := retrieveScouterAsObject(name, nameIsUUID)
-
-		var newRanges ScoutRanges
-		newRanges.Ranges = append(cachedRanges.Ranges, ranges.Ranges...)
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
 
-		newRangeBytes, err := json.Marshal(newRanges)
-		if err != nil {
-			greenlogger.LogErrorf(err, "Problem marshalling %v", ranges)
+	return inTransaction(func(tx *sql.Tx) error {
+		if individualErr := ensureIndividual(tx, uuid, username); individualErr != nil {
+			return individualErr
 		}
 
-		rangeString = string(newRangeBytes)
-
-		_, resultErr := scoutDB.Exec("update individuals set ranges = ? where uuid = ?", rangeString, uuid)
-		if resultErr != nil {
-			greenlogger.LogErrorf(resultErr, "Problem executing sql command %v with args %v", "update individuals set ranges = ? where uuid = ?", []any{rangeString, uuid})
-		}
-
-	} else {
-		user := userDB.UUIDToUser(uuid)
-		_, resultErr := scoutDB.Exec("insert into individuals values(?, ?, ?)", uuid, user, rangeString)
-		if resultErr != nil {
-			greenlogger.LogErrorf(resultErr, "Problem executing sql command %v with args %v", "insert into individuals values(?, ?, ?)", []any{uuid, user, rangeString})
+		for _, scoutRange := range ranges.Ranges {
+			if _, insertErr := insertRange(tx, uuid, scoutRange); insertErr != nil {
+				return insertErr
+			}
 		}
-	}
-
-}
-
-// Returns if an individual has any schedule entries
-func userInSchedule(database *sql.DB, uuid string) bool {
-	result := database.QueryRow("select count(1) from individuals where uuid = ?", uuid)
-
-	var resultstore int
-	err := result.Scan(&resultstore)
-
-	if err != nil {
-		greenlogger.LogErrorf(err, "Problem scanning response %v", result)
-	}
-
-	return resultstore == 1
+		return nil
+	})
 }
 
 // Wipes the schedule.json file
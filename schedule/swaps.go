@@ -0,0 +1,497 @@
+package schedule
+
+// Utility for scouters trading and giving away parts of their schedules, with an audit trail of every step
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/userDB"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors returned when a swap can't be proposed or moved along
+var (
+	ErrSwapNotFound     = errors.New("no swap with that id exists")
+	ErrSwapClosed       = errors.New("the swap can't be changed in its current status")
+	ErrInvalidRange     = errors.New("ranges need a driverstation from 0 to 5 and a first match no later than their last")
+	ErrSelfSwap         = errors.New("scouters can't swap with themselves")
+	ErrTradeNeedsTarget = errors.New("trades need to name the scouter they're offered to")
+	ErrUnknownScouter   = errors.New("that scouter doesn't have an account")
+	ErrNotSwapParty     = errors.New("only the scouters in a swap can do that")
+	ErrRangeNotOwned    = errors.New("the scouter isn't scheduled for every match of that range")
+	ErrScheduleConflict = errors.New("the swap would schedule a scouter for two driverstations in the same match")
+)
+
+// The status of a swap
+const (
+	SwapPending   = "pending"   // Waiting for a scouter to accept it
+	SwapAccepted  = "accepted"  // Accepted, waiting for an admin to approve it
+	SwapCompleted = "completed" // The schedules were changed
+	SwapDeclined  = "declined"  // Turned down by the scouter it was offered to
+	SwapRejected  = "rejected"  // Turned down by an admin
+	SwapCancelled = "cancelled" // Withdrawn by the scouter who proposed it, or an admin
+)
+
+// What happened in one entry of a swap's audit trail
+const (
+	SwapActionPropose = "propose" // The swap was proposed
+	SwapActionAccept  = "accept"  // A scouter accepted the swap
+	SwapActionApprove = "approve" // An admin approved the swap
+	SwapActionReject  = "reject"  // An admin turned the swap down
+	SwapActionDecline = "decline" // The scouter it was offered to turned it down
+	SwapActionCancel  = "cancel"  // The swap was withdrawn
+	SwapActionApply   = "apply"   // The schedules were changed
+)
+
+// A scouter's offer to give away a range of their schedule, or trade it for one of someone else's
+type SwapProposal struct {
+	Target string  // The scouter it's offered to; anyone can accept it if empty
+	Give   [3]int  // The range given away, as [dsoffset, starting, ending]
+	Take   *[3]int // The range of the target's taken in return, for a trade; nil to give Give away
+	Note   string  // A note for the other scouter
+}
+
+// One swap and how far it has gone
+type Swap struct {
+	ID        int64     // The identifier of the swap
+	Event     string    // The event key
+	Proposer  string    // The scouter giving away Give
+	Target    string    // The scouter it's offered to, or empty if anyone can accept it
+	Give      [3]int    // The range the proposer gives away, as [dsoffset, starting, ending]
+	Take      *[3]int   // The range the proposer takes in return, or nil if it's a giveaway
+	Note      string    // The proposer's note
+	Status    string    // One of the Swap status constants
+	Accepter  string    // The scouter who accepted it
+	Approver  string    // The admin who approved or rejected it
+	CreatedAt time.Time // When it was proposed
+	UpdatedAt time.Time // When its status last changed
+}
+
+// One step of a swap
+type SwapAuditEntry struct {
+	SwapID   int64     // The identifier of the swap
+	Username string    // Who took the step
+	Action   string    // What was done; one of the SwapAction constants
+	Detail   string    // A description of the step
+	At       time.Time // When it was taken
+}
+
+// Proposes a swap at the current event. The proposer has to be scheduled for all of Give, and for trades the target for all of Take.
+func ProposeSwap(proposal SwapProposal, proposer string) (Swap, error) {
+	if !validRange(proposal.Give) || (proposal.Take != nil && !validRange(*proposal.Take)) {
+		return Swap{}, ErrInvalidRange
+	}
+	if proposal.Target == proposer {
+		return Swap{}, ErrSelfSwap
+	}
+	if proposal.Take != nil && proposal.Target == "" {
+		return Swap{}, ErrTradeNeedsTarget
+	}
+	if proposal.Target != "" {
+		if _, exists := userDB.GetUUID(proposal.Target, false); !exists {
+			return Swap{}, ErrUnknownScouter
+		}
+	}
+
+	if !ownsRange(retrieveScouterAsObject(proposer, false), proposal.Give) {
+		return Swap{}, ErrRangeNotOwned
+	}
+	if proposal.Take != nil && !ownsRange(retrieveScouterAsObject(proposal.Target, false), *proposal.Take) {
+		return Swap{}, ErrRangeNotOwned
+	}
+
+	give, _ := json.Marshal(proposal.Give)
+	take, _ := json.Marshal(proposal.Take)
+
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
+
+	tx, beginErr := scoutDB.Begin()
+	if beginErr != nil {
+		greenlogger.LogError(beginErr, "Problem beginning transaction on scout.db")
+		return Swap{}, beginErr
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, execErr := tx.Exec(
+		"insert into swaps(event, proposer, target, give, take, note, status, created_at, updated_at) values(?,?,?,?,?,?,?,?,?)",
+		lib.GetCurrentEvent(), proposer, proposal.Target, string(give), string(take), proposal.Note, SwapPending, now.UnixMilli(), now.UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT INTO swaps ... with args: %v, %v", proposer, proposal)
+		return Swap{}, execErr
+	}
+
+	id, _ := result.LastInsertId()
+	swap := Swap{ID: id, Proposer: proposer, Target: proposal.Target, Give: proposal.Give, Take: proposal.Take}
+	if auditErr := recordSwapAudit(tx, SwapAuditEntry{SwapID: id, Username: proposer, Action: SwapActionPropose, Detail: describeSwap(swap), At: now}); auditErr != nil {
+		return Swap{}, auditErr
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		greenlogger.LogErrorf(commitErr, "Problem committing swap proposed by %v", proposer)
+		return Swap{}, commitErr
+	}
+
+	return GetSwap(id)
+}
+
+// Accepts a pending swap. Without SwapConfigs.RequireApproval the schedules are changed straight away; otherwise it waits for an admin.
+func AcceptSwap(id int64, username string) (Swap, error) {
+	return changeSwap(id, username, func(tx *sql.Tx, swap *Swap, now time.Time) error {
+		if swap.Status != SwapPending {
+			return ErrSwapClosed
+		}
+		if swap.Proposer == username {
+			return ErrSelfSwap
+		}
+		if swap.Target != "" && swap.Target != username {
+			return ErrNotSwapParty
+		}
+		if _, exists := userDB.GetUUID(username, false); !exists {
+			return ErrUnknownScouter
+		}
+
+		swap.Accepter, swap.Status = username, SwapAccepted
+		if auditErr := recordSwapAudit(tx, SwapAuditEntry{SwapID: id, Username: username, Action: SwapActionAccept, At: now}); auditErr != nil {
+			return auditErr
+		}
+
+		if constants.CachedConfigs.SwapConfigs.RequireApproval {
+			return nil
+		}
+		return applySwap(tx, swap, username, now)
+	})
+}
+
+// Approves or rejects an accepted swap, changing the schedules if it's approved
+func ReviewSwap(id int64, admin string, approve bool) (Swap, error) {
+	return changeSwap(id, admin, func(tx *sql.Tx, swap *Swap, now time.Time) error {
+		if swap.Status != SwapAccepted {
+			return ErrSwapClosed
+		}
+
+		swap.Approver = admin
+		if !approve {
+			swap.Status = SwapRejected
+			return recordSwapAudit(tx, SwapAuditEntry{SwapID: id, Username: admin, Action: SwapActionReject, At: now})
+		}
+
+		if auditErr := recordSwapAudit(tx, SwapAuditEntry{SwapID: id, Username: admin, Action: SwapActionApprove, At: now}); auditErr != nil {
+			return auditErr
+		}
+		return applySwap(tx, swap, admin, now)
+	})
+}
+
+// Turns down a pending swap offered to a scouter
+func DeclineSwap(id int64, username string) (Swap, error) {
+	return changeSwap(id, username, func(tx *sql.Tx, swap *Swap, now time.Time) error {
+		if swap.Status != SwapPending {
+			return ErrSwapClosed
+		}
+		if swap.Target != username {
+			return ErrNotSwapParty
+		}
+
+		swap.Status = SwapDeclined
+		return recordSwapAudit(tx, SwapAuditEntry{SwapID: id, Username: username, Action: SwapActionDecline, At: now})
+	})
+}
+
+// Withdraws a swap that hasn't been completed. Only its proposer or an admin can.
+func CancelSwap(id int64, username string, isAdmin bool) (Swap, error) {
+	return changeSwap(id, username, func(tx *sql.Tx, swap *Swap, now time.Time) error {
+		if swap.Status != SwapPending && swap.Status != SwapAccepted {
+			return ErrSwapClosed
+		}
+		if swap.Proposer != username && !isAdmin {
+			return ErrNotSwapParty
+		}
+
+		swap.Status = SwapCancelled
+		return recordSwapAudit(tx, SwapAuditEntry{SwapID: id, Username: username, Action: SwapActionCancel, At: now})
+	})
+}
+
+// Runs a change to a swap in a transaction, then saves its accepter, approver and status.
+// Nothing is saved if the change returns an error.
+func changeSwap(id int64, username string, change func(tx *sql.Tx, swap *Swap, now time.Time) error) (Swap, error) {
+	scheduleLock.Lock()
+	defer scheduleLock.Unlock()
+
+	tx, beginErr := scoutDB.Begin()
+	if beginErr != nil {
+		greenlogger.LogError(beginErr, "Problem beginning transaction on scout.db")
+		return Swap{}, beginErr
+	}
+	defer tx.Rollback()
+
+	swap, scanErr := scanSwap(tx.QueryRow(swapColumns+" where id = ?", id))
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return Swap{}, ErrSwapNotFound
+	}
+	if scanErr != nil {
+		greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT ... FROM swaps WHERE id = ? with arg: %v", id)
+		return Swap{}, scanErr
+	}
+
+	now := time.Now()
+	if changeErr := change(tx, &swap, now); changeErr != nil {
+		return Swap{}, changeErr
+	}
+
+	_, execErr := tx.Exec(
+		"update swaps set status = ?, accepter = ?, approver = ?, updated_at = ? where id = ?",
+		swap.Status, swap.Accepter, swap.Approver, now.UnixMilli(), id,
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE swaps SET status = ? WHERE id = ? with args: %v, %v", swap.Status, id)
+		return Swap{}, execErr
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		greenlogger.LogErrorf(commitErr, "Problem committing a change to swap %v by %v", id, username)
+		return Swap{}, commitErr
+	}
+
+	return GetSwap(id)
+}
+
+// Moves the ranges of an accepted swap between the proposer's and accepter's schedules, marking it completed.
+// Both have to still be scheduled for what they give, and neither can end up at two driverstations in one match.
+func applySwap(tx *sql.Tx, swap *Swap, username string, now time.Time) error {
+	proposerUUID, _ := userDB.GetUUID(swap.Proposer, false)
+	accepterUUID, _ := userDB.GetUUID(swap.Accepter, false)
+
+	proposerRanges, proposerErr := scheduleOf(tx, proposerUUID)
+	if proposerErr != nil {
+		return proposerErr
+	}
+	accepterRanges, accepterErr := scheduleOf(tx, accepterUUID)
+	if accepterErr != nil {
+		return accepterErr
+	}
+
+	if !ownsRange(proposerRanges, swap.Give) || (swap.Take != nil && !ownsRange(accepterRanges, *swap.Take)) {
+		return ErrRangeNotOwned
+	}
+
+	proposerRanges.Ranges = subtractRange(proposerRanges.Ranges, swap.Give)
+	if swap.Take != nil {
+		accepterRanges.Ranges = subtractRange(accepterRanges.Ranges, *swap.Take)
+	}
+
+	if overlapsMatches(accepterRanges, swap.Give) || (swap.Take != nil && overlapsMatches(proposerRanges, *swap.Take)) {
+		return ErrScheduleConflict
+	}
+
+	accepterRanges.Ranges = append(accepterRanges.Ranges, swap.Give)
+	if swap.Take != nil {
+		proposerRanges.Ranges = append(proposerRanges.Ranges, *swap.Take)
+	}
+
+	// Written together, so a range given whole keeps its id as it moves to the other scouter
+	writeErr := writeSchedules(tx, []scouterSchedule{
+		{UUID: proposerUUID, Name: swap.Proposer, Ranges: proposerRanges},
+		{UUID: accepterUUID, Name: swap.Accepter, Ranges: accepterRanges},
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+
+	swap.Status = SwapCompleted
+	return recordSwapAudit(tx, SwapAuditEntry{SwapID: swap.ID, Username: username, Action: SwapActionApply, Detail: describeSwap(*swap), At: now})
+}
+
+// Returns if a range has a real driverstation and isn't backwards
+func validRange(scoutRange [3]int) bool {
+	return scoutRange[0] >= 0 && scoutRange[0] < kDriverStations && scoutRange[1] >= 1 && scoutRange[1] <= scoutRange[2]
+}
+
+// Returns if a schedule has every match of a range at its driverstation
+func ownsRange(ranges ScoutRanges, scoutRange [3]int) bool {
+	for match := scoutRange[1]; match <= scoutRange[2]; match++ {
+		owned := false
+		for _, existing := range ranges.Ranges {
+			if existing[0] == scoutRange[0] && match >= existing[1] && match <= existing[2] {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns if a schedule has any of a range's matches, at any driverstation
+func overlapsMatches(ranges ScoutRanges, scoutRange [3]int) bool {
+	for _, existing := range ranges.Ranges {
+		if existing[1] <= scoutRange[2] && scoutRange[1] <= existing[2] {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes the matches of a range from every range at its driverstation, splitting ranges it falls in the middle of
+func subtractRange(ranges [][3]int, scoutRange [3]int) [][3]int {
+	var results [][3]int
+	for _, existing := range ranges {
+		if existing[0] != scoutRange[0] || existing[2] < scoutRange[1] || existing[1] > scoutRange[2] {
+			results = append(results, existing)
+			continue
+		}
+
+		if existing[1] < scoutRange[1] {
+			results = append(results, [3]int{existing[0], existing[1], scoutRange[1] - 1})
+		}
+		if existing[2] > scoutRange[2] {
+			results = append(results, [3]int{existing[0], scoutRange[2] + 1, existing[2]})
+		}
+	}
+	return results
+}
+
+// The columns of swaps, in the order scanSwap reads them
+const swapColumns = "select id, event, proposer, target, give, take, note, status, accepter, approver, created_at, updated_at from swaps"
+
+// Scans one row of swaps
+func scanSwap(row interface{ Scan(...any) error }) (Swap, error) {
+	var swap Swap
+	var give, take string
+	var createdAt, updatedAt int64
+
+	scanErr := row.Scan(&swap.ID, &swap.Event, &swap.Proposer, &swap.Target, &give, &take, &swap.Note, &swap.Status, &swap.Accepter, &swap.Approver, &createdAt, &updatedAt)
+	if scanErr != nil {
+		return swap, scanErr
+	}
+
+	if unmarshalErr := json.Unmarshal([]byte(give), &swap.Give); unmarshalErr != nil {
+		greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", give)
+	}
+	if unmarshalErr := json.Unmarshal([]byte(take), &swap.Take); unmarshalErr != nil {
+		greenlogger.LogErrorf(unmarshalErr, "Problem unmarshalling %v", take)
+	}
+	swap.CreatedAt, swap.UpdatedAt = time.UnixMilli(createdAt), time.UnixMilli(updatedAt)
+
+	return swap, nil
+}
+
+// Returns one swap
+func GetSwap(id int64) (Swap, error) {
+	swap, scanErr := scanSwap(scoutDB.QueryRow(swapColumns+" where id = ?", id))
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return swap, ErrSwapNotFound
+	}
+	if scanErr != nil {
+		greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT ... FROM swaps WHERE id = ? with arg: %v", id)
+	}
+	return swap, scanErr
+}
+
+// Returns the swaps of an event, newest first. If username isn't empty, only swaps they're part of and pending ones anyone
+// can accept are returned.
+func GetSwaps(event string, username string) []Swap {
+	var results []Swap
+
+	query := swapColumns + " where event = ? order by created_at desc"
+	args := []any{event}
+	if username != "" {
+		query = swapColumns + ` where event = ? and (proposer = ? or target = ? or accepter = ? or (target = '' and status = ?))
+			order by created_at desc`
+		args = append(args, username, username, username, SwapPending)
+	}
+
+	rows, queryErr := scoutDB.Query(query, args...)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM swaps WHERE event = ? with args: %v", args)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		swap, scanErr := scanSwap(rows)
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM swaps")
+			continue
+		}
+		results = append(results, swap)
+	}
+
+	return results
+}
+
+// Records one step of a swap in its audit trail
+func recordSwapAudit(database execer, entry SwapAuditEntry) error {
+	_, execErr := database.Exec(
+		"insert into swap_audit(swap_id, username, action, detail, at) values(?,?,?,?,?)",
+		entry.SwapID, entry.Username, entry.Action, entry.Detail, entry.At.UnixMilli(),
+	)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT INTO swap_audit ... with args: %v", entry)
+	}
+	return execErr
+}
+
+// Returns the audit trail of the swaps of an event, newest first. If id isn't 0, only that swap's is returned.
+func GetSwapAudit(event string, id int64) []SwapAuditEntry {
+	var results []SwapAuditEntry
+
+	query := `select swap_id, username, action, detail, at from swap_audit
+		where swap_id in (select id from swaps where event = ?) and (? = 0 or swap_id = ?) order by at desc, id desc`
+
+	rows, queryErr := scoutDB.Query(query, event, id, id)
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM swap_audit with args: %v, %v", event, id)
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry SwapAuditEntry
+		var at int64
+		if scanErr := rows.Scan(&entry.SwapID, &entry.Username, &entry.Action, &entry.Detail, &at); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM swap_audit")
+			continue
+		}
+		entry.At = time.UnixMilli(at)
+		results = append(results, entry)
+	}
+
+	return results
+}
+
+// Describes what a swap moves, for its audit trail
+func describeSwap(swap Swap) string {
+	receiver := swap.Accepter
+	if receiver == "" {
+		receiver = swap.Target
+	}
+	if receiver == "" {
+		receiver = "anyone"
+	}
+
+	description := fmt.Sprintf("%v gives %v to %v", swap.Proposer, describeRange(swap.Give), receiver)
+	if swap.Take != nil {
+		description += fmt.Sprintf(" for %v", describeRange(*swap.Take))
+	}
+	return description
+}
+
+// Describes a range, as in "red1 matches 5-10"
+func describeRange(scoutRange [3]int) string {
+	station := "unknown"
+	if validRange(scoutRange) {
+		station = stationNames[scoutRange[0]]
+	}
+	return fmt.Sprintf("%v matches %v-%v", station, scoutRange[1], scoutRange[2])
+}
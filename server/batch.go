@@ -0,0 +1,179 @@
+package server
+
+// Uploading many submissions at once, for scouters that collected data offline
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/matchDB"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The most a batch may be, before and after decompressing
+const maxBatchBytes = 32 << 20
+
+// The most items a batch may hold
+const maxBatchItems = 500
+
+// One submission in a batch upload
+type BatchItem struct {
+	Kind         string          // "match" for lib.TeamData (the default) or "pit" for lib.PitScoutingData
+	SubmissionID string          // The ID the client generated for the submission, making it safe to retry like the Submission-ID header
+	Data         json.RawMessage // The submission itself, exactly as it would be posted on its own
+}
+
+// What happened to one item of a batch upload
+type BatchResult struct {
+	Index        int    // The position of the item in the batch
+	SubmissionID string // The ID the client generated for the item, if it did
+	Status       int    // The HTTP status code the item would have gotten if it was posted on its own
+	Message      string // What happened to it
+	File         string // The name it was saved as, if it was saved by this request
+	Duplicate    bool   // If it had already been submitted, so wasn't saved again
+}
+
+// The endpoints each kind of submission is posted to on its own, which submission IDs are shared with
+var submissionEndpoints = map[string]string{
+	matchSubmission: "/dataEntry",
+	pitSubmission:   "/pitScout",
+}
+
+// Handles a batch upload: a JSON array of BatchItems, optionally gzipped with a Content-Encoding header.
+// Every item is handled like it was posted on its own, and the response lists what happened to each.
+func postBatch(writer http.ResponseWriter, request *http.Request) {
+	body := http.MaxBytesReader(writer, request.Body, maxBatchBytes)
+
+	var reader io.Reader = body
+	if strings.EqualFold(request.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, gzipErr := gzip.NewReader(body)
+		if gzipErr != nil {
+			httpError(writer, request, http.StatusBadRequest, "Could not decompress the batch: %v", gzipErr)
+			return
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	}
+
+	// Read one byte past the limit to tell a batch that's too big from one that's exactly the limit
+	requestBytes, readErr := io.ReadAll(io.LimitReader(reader, maxBatchBytes+1))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(readErr, &maxBytesErr) || len(requestBytes) > maxBatchBytes {
+		httpError(writer, request, http.StatusRequestEntityTooLarge, "Batches can't be larger than %v bytes", maxBatchBytes)
+		return
+	} else if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the batch: %v", readErr)
+		return
+	}
+
+	var items []BatchItem
+	if unmarshalErr := json.Unmarshal(requestBytes, &items); unmarshalErr != nil {
+		httpError(writer, request, http.StatusBadRequest, "Could not parse the batch: %v", unmarshalErr)
+		return
+	}
+
+	if len(items) > maxBatchItems {
+		httpError(writer, request, http.StatusRequestEntityTooLarge, "Batches can't hold more than %v items", maxBatchItems)
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		results[i] = handleBatchItem(requestIDFromRequest(request), session.Username, i, item)
+	}
+
+	encodeErr := json.NewEncoder(writer).Encode(results)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", results)
+	}
+}
+
+// Handles one item of a batch the same way as posting it on its own, including deduplicating it by its submission ID.
+// The request ID is only used for the responses remembered for retries, and submission IDs belong to the user who sent the batch.
+func handleBatchItem(requestID string, username string, index int, item BatchItem) BatchResult {
+	result := BatchResult{Index: index, SubmissionID: item.SubmissionID}
+
+	if item.Kind == "" {
+		item.Kind = matchSubmission
+	}
+
+	endpoint, validKind := submissionEndpoints[item.Kind]
+	if !validKind {
+		result.Status, result.Message = http.StatusBadRequest, fmt.Sprintf("%v isn't a kind of submission", item.Kind)
+		return result
+	}
+
+	if len(item.SubmissionID) > maxSubmissionIDLength {
+		result.Status, result.Message = http.StatusBadRequest, fmt.Sprintf("Submission IDs can't be longer than %v characters", maxSubmissionIDLength)
+		return result
+	}
+
+	if item.SubmissionID != "" {
+		if existing, reserved := matchDB.ReserveSubmission(username, item.SubmissionID, endpoint); !reserved {
+			return duplicateBatchResult(result, existing, endpoint)
+		}
+	}
+
+	if ingest.full() {
+		result.Status, result.Message = http.StatusServiceUnavailable, "The server is busy processing other submissions. Please try again shortly."
+	} else if fileName, storeErr := storeSubmission(item.Kind, item.Data); storeErr != nil {
+		result.Status, result.Message = http.StatusInternalServerError, storeErr.Error()
+
+		var subErr *submissionError
+		if errors.As(storeErr, &subErr) {
+			result.Status = subErr.Status
+		}
+	} else {
+		result.Status, result.Message, result.File = http.StatusOK, "Processed "+fileName, fileName
+	}
+
+	if item.SubmissionID != "" {
+		if result.Status >= 500 {
+			matchDB.ReleaseSubmission(username, item.SubmissionID)
+		} else {
+			// Remember it as the response it would have gotten on its own, so retrying it on its own gets the same answer
+			contentType, response := standaloneResponse(requestID, result)
+			matchDB.CompleteSubmission(username, item.SubmissionID, result.Status, contentType, response)
+		}
+	}
+
+	return result
+}
+
+// Returns the content type and body a batch item would have been responded to with if it was posted on its own
+func standaloneResponse(requestID string, result BatchResult) (string, string) {
+	if result.Status == http.StatusOK {
+		return "text/plain; charset=utf-8", fmt.Sprintf("Processed %v\n", result.File)
+	}
+
+	encoded, _ := json.Marshal(ErrorResponse{Code: result.Status, Message: result.Message, RequestID: requestID})
+	return "application/json", string(encoded) + "\n"
+}
+
+// Fills in the result of a batch item that had already been submitted, from the response it was given then
+func duplicateBatchResult(result BatchResult, existing matchDB.Submission, endpoint string) BatchResult {
+	result.Duplicate = true
+
+	switch {
+	case existing.Endpoint != endpoint:
+		result.Status, result.Message = http.StatusConflict, fmt.Sprintf("Submission %v was already used for %v", existing.ID, existing.Endpoint)
+	case existing.Pending:
+		result.Status, result.Message = http.StatusConflict, fmt.Sprintf("Submission %v is still being handled. Please try again shortly.", existing.ID)
+	default:
+		result.Status, result.Message = existing.Status, strings.TrimSpace(existing.Response)
+
+		var errorResponse ErrorResponse
+		if strings.HasPrefix(existing.ContentType, "application/json") && json.Unmarshal([]byte(existing.Response), &errorResponse) == nil {
+			result.Message = errorResponse.Message
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,99 @@
+package server
+
+// Reporting what the scouters of multi-scouted matches disagreed on
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"GreenScoutBackend/sheet"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How long changes to the Discrepancies tab are collected before it's rewritten, so a burst of reports rewrites it once
+const discrepancyTabDelay = 15 * time.Second
+
+// Changes to the Discrepancies tab that haven't been written yet
+var discrepancyTab struct {
+	mutex    sync.Mutex
+	event    string      // The event whose reports the tab will be rewritten with
+	timer    *time.Timer // The pending rewrite, if there is one
+	failures int         // How many rewrites in a row have failed
+}
+
+// Stores the discrepancy report of a multi-scouted match and marks the Discrepancies tab to be rewritten if anything changed
+func reportDiscrepancies(match lib.MultiMatch, entries []lib.TeamData) {
+	report := lib.CompileDiscrepancyReport(match, entries)
+
+	if len(report.Discrepancies) == 0 {
+		// Only an earlier report of this match being cleared changes the tab
+		if !matchDB.ClearDiscrepancyReport(report.Event, report.Match, report.IsBlue, report.DSNumber) {
+			return
+		}
+	} else {
+		if !matchDB.StoreDiscrepancyReport(report) {
+			return
+		}
+		greenlogger.LogMessagef("Scouters of match %v %v disagreed on %v fields", report.Match, lib.GetDSString(report.IsBlue, uint(report.DSNumber)), len(report.Discrepancies))
+	}
+
+	markDiscrepancyTab(report.Event)
+}
+
+// Clears the discrepancy report of a match and driverstation that was rescouted, marking the Discrepancies tab to be rewritten if it had one
+func clearDiscrepancies(team lib.TeamData) {
+	event := lib.GetCurrentEvent()
+	if matchDB.ClearDiscrepancyReport(event, int(team.Match.Number), team.DriverStation.IsBlue, team.DriverStation.Number) {
+		markDiscrepancyTab(event)
+	}
+}
+
+// Marks the Discrepancies tab to be rewritten with every report of an event, after discrepancyTabDelay unless a rewrite is already pending
+func markDiscrepancyTab(event string) {
+	discrepancyTab.mutex.Lock()
+	defer discrepancyTab.mutex.Unlock()
+
+	discrepancyTab.event = event
+	if discrepancyTab.timer == nil {
+		discrepancyTab.timer = time.AfterFunc(discrepancyTabDelay, writeDiscrepancyTab)
+	}
+}
+
+// Rewrites the Discrepancies tab with every report of the marked event. If it fails, it's tried again after waiting
+// as long as a failed submission would, so it backs off while the Sheets API is out of quota.
+func writeDiscrepancyTab() {
+	discrepancyTab.mutex.Lock()
+	discrepancyTab.timer = nil
+	event := discrepancyTab.event
+	discrepancyTab.mutex.Unlock()
+
+	writeErr := sheet.WriteDiscrepancies(matchDB.GetDiscrepancyReports(event))
+
+	discrepancyTab.mutex.Lock()
+	defer discrepancyTab.mutex.Unlock()
+
+	if writeErr == nil {
+		discrepancyTab.failures = 0
+		return
+	}
+
+	discrepancyTab.failures++
+	if discrepancyTab.timer == nil {
+		delay := retryDelay(discrepancyTab.failures)
+		greenlogger.LogErrorf(writeErr, "Problem writing the %v tab, retrying in %v", sheet.DiscrepanciesTab, delay.Round(time.Second))
+		discrepancyTab.timer = time.AfterFunc(delay, writeDiscrepancyTab)
+	}
+}
+
+// Serves every discrepancy report of the current event, ordered by match and driverstation
+func serveDiscrepancies(writer http.ResponseWriter, request *http.Request) {
+	reports := matchDB.GetDiscrepancyReports(lib.GetCurrentEvent())
+
+	encodeErr := json.NewEncoder(writer).Encode(reports)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", reports)
+	}
+}
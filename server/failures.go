@@ -0,0 +1,253 @@
+package server
+
+// Submissions that were given up on, and the admin endpoints for inspecting and requeueing them
+
+import (
+	"GreenScoutBackend/constants"
+	filemanager "GreenScoutBackend/fileManager"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// The directories failed submissions are kept in, as named in requests and in matches.db
+const (
+	erroredDirectory = "Errored" // Submissions that parsed but failed to write, or couldn't be parsed from In
+	mangledDirectory = "Mangled" // Submissions whose body wasn't valid JSON when they were posted
+)
+
+// A failed submission along with its contents, for admins to inspect
+type FailureDetails struct {
+	Failure  matchDB.Failure // Where the submission is and why it failed
+	Contents string          // The contents of the file, which may not be valid JSON
+}
+
+// Returns the path of a directory failed submissions are kept in, and if it is one
+func failureDirectoryPath(directory string) (string, bool) {
+	switch directory {
+	case erroredDirectory:
+		return constants.JsonErroredDirectory, true
+	case mangledDirectory:
+		return constants.JsonMangledDirectory, true
+	}
+
+	return "", false
+}
+
+// Returns the path of a failed submission, and if the directory and file name are valid
+func failurePath(directory string, fileName string) (string, bool) {
+	directoryPath, valid := failureDirectoryPath(directory)
+	if !valid || fileName == "" || filepath.Base(fileName) != fileName {
+		return "", false
+	}
+
+	return filepath.Join(directoryPath, fileName), true
+}
+
+// Records why a submission failed, letting slack know if it's in use
+func recordFailure(fileName string, directory string, reason error, attempts int) {
+	matchDB.RecordFailure(matchDB.Failure{
+		File:      fileName,
+		Directory: directory,
+		Reason:    reason.Error(),
+		Attempts:  attempts,
+		FailedAt:  time.Now(),
+	})
+
+	message := fmt.Sprintf("Moved %v to %v after %v attempts: %v", fileName, directory, attempts, reason)
+	greenlogger.LogMessage(message)
+	if constants.CachedConfigs.SlackConfigs.UsingSlack {
+		greenlogger.NotifyMessage(message)
+	}
+}
+
+// Moves a submission that can't be processed from In to Errored, where it waits for an admin
+func deadLetter(fileName string, attempts int, reason error) {
+	if !lib.MoveFile(filepath.Join(constants.JsonInDirectory, fileName), filepath.Join(constants.JsonErroredDirectory, fileName)) {
+		greenlogger.LogMessagef("Unable to move %v to %v, it will be retried", fileName, erroredDirectory)
+		return
+	}
+
+	recordFailure(fileName, erroredDirectory, reason, attempts)
+}
+
+// Saves the body of a submission that couldn't be parsed to Mangled, so it can be fixed and requeued
+func saveMangled(requestBytes []byte, reason error) {
+	fileName := fmt.Sprintf("%v.json", time.Now().UnixNano())
+
+	writeErr := filemanager.WriteFileWithPermissions(filepath.Join(constants.JsonMangledDirectory, fileName), requestBytes)
+	if writeErr != nil {
+		greenlogger.LogErrorf(writeErr, "Problem creating %v", filepath.Join(constants.JsonMangledDirectory, fileName))
+		return
+	}
+
+	recordFailure(fileName, mangledDirectory, reason, 0)
+}
+
+// Returns every submission in Errored and Mangled, with why they failed if it was recorded
+func getFailures() []matchDB.Failure {
+	var failures []matchDB.Failure
+
+	for _, directory := range []string{erroredDirectory, mangledDirectory} {
+		directoryPath, _ := failureDirectoryPath(directory)
+		files, readErr := os.ReadDir(directoryPath)
+		if readErr != nil {
+			greenlogger.LogErrorf(readErr, "Problem reading %v", directoryPath)
+			continue
+		}
+
+		recorded := matchDB.GetFailures(directory)
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+
+			failure, ok := recorded[file.Name()]
+			if !ok {
+				failure = matchDB.Failure{File: file.Name(), Directory: directory}
+			}
+			failures = append(failures, failure)
+		}
+	}
+
+	slices.SortStableFunc(failures, func(a, b matchDB.Failure) int {
+		return b.FailedAt.Compare(a.FailedAt)
+	})
+
+	return failures
+}
+
+// Serves every failed submission, most recent first
+func serveFailures(writer http.ResponseWriter, request *http.Request) {
+	failures := getFailures()
+
+	encodeErr := json.NewEncoder(writer).Encode(failures)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", failures)
+	}
+}
+
+// Serves one failed submission, named by the directory and file headers, along with its contents
+func serveFailure(writer http.ResponseWriter, request *http.Request) {
+	directory := request.Header.Get("directory")
+	fileName := request.Header.Get("file")
+
+	path, valid := failurePath(directory, fileName)
+	if !valid {
+		httpError(writer, request, http.StatusBadRequest, "%v/%v isn't a valid failed submission", directory, fileName)
+		return
+	}
+
+	contents, readErr := os.ReadFile(path)
+	if readErr != nil {
+		if os.IsNotExist(readErr) {
+			httpError(writer, request, http.StatusNotFound, "%v/%v doesn't exist", directory, fileName)
+		} else {
+			greenlogger.LogErrorf(readErr, "Problem reading %v", path)
+			httpError(writer, request, http.StatusInternalServerError, "Could not read %v/%v", directory, fileName)
+		}
+		return
+	}
+
+	failure, recorded := matchDB.GetFailures(directory)[fileName]
+	if !recorded {
+		failure = matchDB.Failure{File: fileName, Directory: directory}
+	}
+
+	details := FailureDetails{Failure: failure, Contents: string(contents)}
+	encodeErr := json.NewEncoder(writer).Encode(details)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", details)
+	}
+}
+
+// Puts a failed submission, named by the directory and file headers, back in the queue.
+// A body replaces the submission's contents, and is required for Mangled submissions; send a kind header of "pit" if it's pit scouting.
+func handleRequeue(writer http.ResponseWriter, request *http.Request) {
+	if ingest.full() {
+		serveQueueFull(writer, request)
+		return
+	}
+
+	directory := request.Header.Get("directory")
+	fileName := request.Header.Get("file")
+
+	path, valid := failurePath(directory, fileName)
+	if !valid {
+		httpError(writer, request, http.StatusBadRequest, "%v/%v isn't a valid failed submission", directory, fileName)
+		return
+	}
+
+	if _, statErr := os.Stat(path); statErr != nil {
+		httpError(writer, request, http.StatusNotFound, "%v/%v doesn't exist", directory, fileName)
+		return
+	}
+
+	requestBytes, readErr := io.ReadAll(request.Body)
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
+	}
+
+	requeuedAs := fileName
+	switch {
+	case len(requestBytes) == 0 && directory == mangledDirectory:
+		httpError(writer, request, http.StatusBadRequest, "Mangled submissions need corrected contents to be requeued")
+		return
+
+	case len(requestBytes) == 0:
+		if !lib.MoveFile(path, filepath.Join(constants.JsonInDirectory, fileName)) {
+			httpError(writer, request, http.StatusInternalServerError, "Could not move %v back to In", fileName)
+			return
+		}
+		ingest.enqueue(fileName)
+
+	default:
+		var submission any
+		if request.Header.Get("kind") == "pit" {
+			var pit lib.PitScoutingData
+			submission = &pit
+		} else {
+			var team lib.TeamData
+			submission = &team
+		}
+
+		if unmarshalErr := json.Unmarshal(requestBytes, submission); unmarshalErr != nil {
+			httpError(writer, request, http.StatusBadRequest, "Could not parse the corrected submission: %v", unmarshalErr)
+			return
+		}
+
+		// Mangled files were never named, so they're named like a new submission
+		if directory == mangledDirectory {
+			switch submission := submission.(type) {
+			case *lib.PitScoutingData:
+				requeuedAs = pitFileName(*submission)
+			case *lib.TeamData:
+				requeuedAs = matchFileName(*submission)
+			}
+		}
+
+		if submitErr := submitFile(requeuedAs, submission); submitErr != nil {
+			httpError(writer, request, http.StatusInternalServerError, "Could not save the corrected submission")
+			return
+		}
+
+		if removeErr := os.Remove(path); removeErr != nil {
+			greenlogger.LogErrorf(removeErr, "Problem removing %v", path)
+		}
+	}
+
+	matchDB.ClearFailure(fileName)
+	greenlogger.LogMessagef("Requeued %v/%v as %v", directory, fileName, requeuedAs)
+
+	httpResponsef(writer, "Problem writing http response to requeue request", "Requeued %v as %v\n", fileName, requeuedAs)
+}
@@ -0,0 +1,218 @@
+package server
+
+// The permissions required by every endpoint, and the middleware enforcing them
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/userDB"
+	"context"
+	"net/http"
+)
+
+// A level of access an endpoint can require
+type Permission int
+
+const (
+	Public        Permission = iota // Anyone, logged in or not
+	Authenticated                   // Anyone with a valid session
+	Verified                        // Members of the team, admins and supers
+	Admin                           // Admins and supers
+	SelfOrAdmin                     // The user named by the username header, admins and supers
+)
+
+// The permission required by every endpoint. Endpoints missing from here reject every request.
+var endpointPermissions = map[string]Permission{
+	"/":                 Public,
+	"/pub":              Public,
+	"/schedule":         Public,
+	"/leaderboard":      Public,
+	"/scouterLookup":    Public,
+	"/userInfo":         Public,
+	"/certificateValid": Public,
+	"/getPfp":           Public,
+	"/generalInfo":      Public,
+	"/allEvents":        Public,
+	"/gallery":          Public,
+	"/login":            Public,
+	"/changePassword":   Public, // Authenticated by the current password instead of a session
+
+	"/dataEntry":       Authenticated,
+	"/dataEntry/batch": Authenticated,
+	"/qrEntry":         Authenticated,
+	"/pitScout":        Authenticated,
+	"/singleSchedule":  Authenticated,
+	"/swaps":           Authenticated,
+	"/swap/propose":    Authenticated,
+	"/swap/accept":     Authenticated,
+	"/swap/decline":    Authenticated,
+	"/swap/cancel":     Authenticated,
+	"/sessions":        Authenticated,
+	"/logout":          Authenticated,
+
+	"/setDisplayName":   SelfOrAdmin,
+	"/setUserPfp":       SelfOrAdmin,
+	"/provideAdditions": SelfOrAdmin,
+	"/setColor":         SelfOrAdmin,
+
+	"/spreadsheet": Verified,
+	"/teamStats":   Verified,
+	"/teamRatings": Verified,
+	"/predictions": Verified,
+
+	"/pickLists":           Verified,
+	"/pickList":            Verified,
+	"/pickList/create":     Verified,
+	"/pickList/order":      Verified,
+	"/pickList/team":       Verified,
+	"/pickList/removeTeam": Verified,
+	"/pickList/history":    Verified,
+	"/pickList/updates":    Verified,
+	"/allianceSelection":   Verified,
+
+	"/adminUserInfo":          Admin,
+	"/scheduleReport":         Admin,
+	"/swap/review":            Admin,
+	"/swap/audit":             Admin,
+	"/generateSchedule":       Admin,
+	"/addSchedule":            Admin,
+	"/ranges":                 Admin,
+	"/range/create":           Admin,
+	"/range/update":           Admin,
+	"/range/delete":           Admin,
+	"/replaceSchedules":       Admin,
+	"/clearSchedules":         Admin,
+	"/modScore":               Admin,
+	"/allUsers":               Admin,
+	"/addBadge":               Admin,
+	"/badgeConfig":            Admin,
+	"/keyChange":              Admin,
+	"/sheetChange":            Admin,
+	"/accounts":               Admin,
+	"/addAccount":             Admin,
+	"/resetPassword":          Admin,
+	"/setAccountDisabled":     Admin,
+	"/revokeSessions":         Admin,
+	"/failures":               Admin,
+	"/failure":                Admin,
+	"/requeue":                Admin,
+	"/validationReport":       Admin,
+	"/scouterAccuracy":        Admin,
+	"/discrepancies":          Admin,
+	"/pickList/delete":        Admin,
+	"/allianceSelection/mark": Admin,
+}
+
+// The key the verified session of a request is stored under in its context
+type sessionContextKey struct{}
+
+// Returns the session a request was made with, if it had a valid one
+func sessionFromRequest(request *http.Request) (userDB.Session, bool) {
+	session, ok := request.Context().Value(sessionContextKey{}).(userDB.Session)
+	return session, ok
+}
+
+// Returns if a role has admin permissions
+func isAdminRole(role string) bool {
+	return role == userDB.RoleAdmin || role == userDB.RoleSuper
+}
+
+// The only endpoints a session can use while its user must change their password
+var passwordChangeEndpoints = map[string]bool{
+	"/changePassword": true,
+	"/logout":         true,
+}
+
+// Endpoints browsers open with EventSource, which can't send headers, so they also accept the session token as a certificate query parameter
+var queryTokenEndpoints = map[string]bool{
+	"/pickList/updates": true,
+}
+
+// Returns the session token of a request: its Certificate header, or on endpoints in queryTokenEndpoints, its certificate query parameter
+func sessionToken(pattern string, request *http.Request) string {
+	token := request.Header.Get("Certificate")
+	if token == "" && queryTokenEndpoints[pattern] {
+		token = request.URL.Query().Get("certificate")
+	}
+	return token
+}
+
+// Returns if a session (which may not exist) satisfies a permission for a request
+func (permission Permission) allows(session userDB.Session, hasSession bool, request *http.Request) bool {
+	switch permission {
+	case Public:
+		return true
+	case Authenticated:
+		return hasSession
+	case Verified:
+		return hasSession && (session.Role == userDB.RoleVerified || isAdminRole(session.Role))
+	case Admin:
+		return hasSession && isAdminRole(session.Role)
+	case SelfOrAdmin:
+		return hasSession && (isAdminRole(session.Role) || session.Username == request.Header.Get("username"))
+	}
+	return false
+}
+
+// Sets the CORS (Cross-Origin Resource sharing) headers every response needs, as they are typically highly restricted by modern
+// browsers, especially chromium-based ones.
+func setCORSHeaders(w http.ResponseWriter) {
+	w.Header().Set("Access-Control-Allow-Origin", constants.CachedConfigs.FrontendDomain)
+	w.Header().Set("Access-Control-Allow-Methods", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "*, Certificate, "+submissionIDHeader)
+	w.Header().Set("Access-Control-Expose-Headers", "*, Certificate, "+requestIDHeader+", "+duplicateSubmissionHeader)
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+}
+
+// A wrapper enforcing the permission of an endpoint before its handler runs. Preflight requests are answered directly without reaching the handler.
+// Requests without a valid session get a 401 when one is needed, and ones with an insufficient session get a 403.
+// A valid session is stored in the request's context for the handler, even on public endpoints.
+// Sessions of users who must change their password only count on the endpoints in passwordChangeEndpoints; elsewhere
+// they're ignored on public endpoints and get a 403 on the rest.
+func requirePermission(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	permission, configured := endpointPermissions[pattern]
+	if !configured {
+		greenlogger.LogMessagef("%v has no configured permission; every request to it will be rejected", pattern)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			setCORSHeaders(w)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		session, hasSession := userDB.VerifySession(sessionToken(pattern, r))
+
+		if hasSession && !passwordChangeEndpoints[pattern] && userDB.MustChangePassword(session.Username) {
+			if !configured || permission != Public {
+				setCORSHeaders(w)
+				httpError(w, r, http.StatusForbidden, "You must change your password before doing that.")
+				return
+			}
+			hasSession = false
+		}
+
+		if !configured || !permission.allows(session, hasSession, r) {
+			setCORSHeaders(w)
+
+			if configured && !hasSession && permission != Public {
+				httpError(w, r, http.StatusUnauthorized, "Not successfully authenticated. Please ensure you have correct login details.")
+			} else {
+				httpError(w, r, http.StatusForbidden, "You don't have permission to do that.")
+			}
+			return
+		}
+
+		if hasSession {
+			r = r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, session))
+		}
+
+		handler(w, r)
+	}
+}
+
+// Registers a handler for an endpoint, giving each request an ID, enforcing its permission and handling CORS
+func handle(pattern string, handler http.HandlerFunc) {
+	http.HandleFunc(pattern, withRequestID(requirePermission(pattern, handleWithCORS(handler))))
+}
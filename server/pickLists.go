@@ -0,0 +1,240 @@
+package server
+
+// Serving pick lists and alliance selection to strategists, including live updates
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/pickList"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// How often an idle update stream is sent a comment, so proxies don't close it
+const pickListKeepAlive = 30 * time.Second
+
+// A request naming a pick list, and optionally a team on it
+type pickListRequest struct {
+	List    int64  // The identifier of the list
+	Team    int    // The team number, when the request is about one team
+	Name    string // The name of a new list
+	Version *int   // The version of the list a change was made from
+}
+
+// Writes a pick list, or the error changing it, as the response
+func writePickListResponse(writer http.ResponseWriter, request *http.Request, list pickList.PickList, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, pickList.ErrListNotFound), errors.Is(err, pickList.ErrTeamNotListed):
+		httpError(writer, request, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, pickList.ErrListExists), errors.Is(err, pickList.ErrStaleList):
+		httpError(writer, request, http.StatusConflict, "%v", err)
+		return
+	case errors.Is(err, pickList.ErrInvalidName), errors.Is(err, pickList.ErrInvalidStatus), errors.Is(err, pickList.ErrNoVersion),
+		errors.Is(err, pickList.ErrInvalidTeam):
+		httpError(writer, request, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		httpError(writer, request, http.StatusInternalServerError, "There was a problem updating the pick list")
+		return
+	}
+
+	encodeErr := json.NewEncoder(writer).Encode(list)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", list)
+	}
+}
+
+// Returns the pick list named by the list header of a request, writing a 400 if it isn't a number
+func listFromHeader(writer http.ResponseWriter, request *http.Request) (int64, bool) {
+	id, parseErr := strconv.ParseInt(request.Header.Get("list"), 10, 64)
+	if parseErr != nil {
+		httpError(writer, request, http.StatusBadRequest, "Could not parse the list %v: %v", request.Header.Get("list"), parseErr)
+		return 0, false
+	}
+	return id, true
+}
+
+// Serves every pick list of the current event, without their teams
+func servePickLists(writer http.ResponseWriter, request *http.Request) {
+	lists := pickList.GetLists(lib.GetCurrentEvent())
+
+	encodeErr := json.NewEncoder(writer).Encode(lists)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", lists)
+	}
+}
+
+// Serves one pick list, named by the list header, with every team on it
+func servePickList(writer http.ResponseWriter, request *http.Request) {
+	id, valid := listFromHeader(writer, request)
+	if !valid {
+		return
+	}
+
+	list, err := pickList.GetList(id)
+	writePickListResponse(writer, request, list, err)
+}
+
+// Handles creating a pick list at the current event
+func handlePickListCreation(writer http.ResponseWriter, request *http.Request) {
+	var listRequest pickListRequest
+	if !decodeJSONBody(writer, request, &listRequest) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	list, err := pickList.CreateList(lib.GetCurrentEvent(), listRequest.Name, session.Username)
+	writePickListResponse(writer, request, list, err)
+}
+
+// Handles reordering a pick list
+func handlePickListOrder(writer http.ResponseWriter, request *http.Request) {
+	var order pickList.ListOrder
+	if !decodeJSONBody(writer, request, &order) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	list, err := pickList.ReorderList(order, session.Username)
+	writePickListResponse(writer, request, list, err)
+}
+
+// Handles adding a team to a pick list or changing its tags, notes and do not pick flag
+func handlePickListTeam(writer http.ResponseWriter, request *http.Request) {
+	var edit pickList.TeamEdit
+	if !decodeJSONBody(writer, request, &edit) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	list, err := pickList.EditTeam(edit, session.Username)
+	writePickListResponse(writer, request, list, err)
+}
+
+// Handles taking a team off a pick list
+func handlePickListRemoval(writer http.ResponseWriter, request *http.Request) {
+	var listRequest pickListRequest
+	if !decodeJSONBody(writer, request, &listRequest) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	list, err := pickList.RemoveTeam(listRequest.List, listRequest.Team, listRequest.Version, session.Username)
+	writePickListResponse(writer, request, list, err)
+}
+
+// Handles deleting a pick list
+func handlePickListDeletion(writer http.ResponseWriter, request *http.Request) {
+	var listRequest pickListRequest
+	if !decodeJSONBody(writer, request, &listRequest) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	if err := pickList.DeleteList(listRequest.List, session.Username); err != nil {
+		writePickListResponse(writer, request, pickList.PickList{}, err)
+		return
+	}
+
+	httpResponsef(writer, "Problem writing http response to pick list deletion", "Successfully deleted pick list %v\n", listRequest.List)
+}
+
+// Serves the history of the pick list named by the list header, newest first.
+// The strategist header limits it to one strategist's changes.
+func servePickListHistory(writer http.ResponseWriter, request *http.Request) {
+	id, valid := listFromHeader(writer, request)
+	if !valid {
+		return
+	}
+
+	history := pickList.GetHistory(id, request.Header.Get("strategist"))
+
+	encodeErr := json.NewEncoder(writer).Encode(history)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", history)
+	}
+}
+
+// Serves every team marked during alliance selection at the current event
+func serveAllianceSelection(writer http.ResponseWriter, request *http.Request) {
+	selections := pickList.GetSelections(lib.GetCurrentEvent())
+
+	encodeErr := json.NewEncoder(writer).Encode(selections)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", selections)
+	}
+}
+
+// Handles marking a team as picked or declined during alliance selection at the current event
+func handleAllianceSelectionMark(writer http.ResponseWriter, request *http.Request) {
+	var selection pickList.Selection
+	if !decodeJSONBody(writer, request, &selection) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	selection.Event, selection.MarkedBy = lib.GetCurrentEvent(), session.Username
+
+	if err := pickList.MarkSelection(selection); err != nil {
+		writePickListResponse(writer, request, pickList.PickList{}, err)
+		return
+	}
+
+	httpResponsef(writer, "Problem writing http response to alliance selection", "Successfully marked %v as %v\n", selection.Team, selection.Status)
+}
+
+// Streams every change to the current event's pick lists and alliance selection as server-sent events, until the client disconnects.
+// Each event is named by its kind and carries a pickList.Update; clients fetch whatever changed.
+func streamPickListUpdates(writer http.ResponseWriter, request *http.Request) {
+	flusher, canFlush := writer.(http.Flusher)
+	if !canFlush {
+		httpError(writer, request, http.StatusInternalServerError, "Streaming isn't supported")
+		return
+	}
+
+	updates, unsubscribe := pickList.Subscribe()
+	defer unsubscribe()
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	fmt.Fprint(writer, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(pickListKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+
+		case <-keepAlive.C:
+			fmt.Fprint(writer, ": keep-alive\n\n")
+			flusher.Flush()
+
+		case update := <-updates:
+			if update.Event != lib.GetCurrentEvent() {
+				continue
+			}
+
+			data, marshalErr := json.Marshal(update)
+			if marshalErr != nil {
+				greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", update)
+				continue
+			}
+
+			if _, writeErr := fmt.Fprintf(writer, "event: %v\ndata: %s\n\n", update.Kind, data); writeErr != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+// Ingesting scouting data scanned from QR codes
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/qr"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// The most scanned text accepted in one request
+const maxQRBytes = 256 << 10
+
+// Stores a decoded QR message like any other match submission, submitted by username with submissionID.
+// Without a submission ID, one is made from the message that isn't tied to any user, so the same codes are recognized
+// as a duplicate whoever scans them again.
+func storeQRMessage(requestID string, username string, index int, message qr.Message, submissionID string) BatchResult {
+	if submissionID == "" {
+		submissionID, username = "qr-"+message.ID, ""
+	}
+
+	data, marshalErr := json.Marshal(message.Team)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", message.Team)
+		return BatchResult{Index: index, SubmissionID: submissionID, Status: http.StatusInternalServerError, Message: "Could not save the scouting data"}
+	}
+
+	return handleBatchItem(requestID, username, index, BatchItem{Kind: matchSubmission, SubmissionID: submissionID, Data: data})
+}
+
+// Handles the scanned parts of one QR message, one per line, feeding the scouting data into the ingestion queue
+func postQR(writer http.ResponseWriter, request *http.Request) {
+	requestBytes, readErr := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxQRBytes))
+	if readErr != nil {
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body: %v", readErr)
+		return
+	}
+
+	message, decodeErr := qr.Decode(strings.Split(string(requestBytes), "\n"))
+	if decodeErr != nil {
+		httpError(writer, request, http.StatusBadRequest, "Could not decode the QR codes: %v", decodeErr)
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	result := storeQRMessage(requestIDFromRequest(request), session.Username, 0, message, request.Header.Get(submissionIDHeader))
+
+	if result.Duplicate {
+		writer.Header().Set(duplicateSubmissionHeader, "true")
+	}
+
+	switch {
+	case result.Status == http.StatusOK:
+		httpResponsef(writer, "Problem writing http response to QR post request", "%v\n", result.Message)
+	case result.Status == http.StatusServiceUnavailable:
+		serveQueueFull(writer, request)
+	default:
+		httpError(writer, request, result.Status, "%s", result.Message)
+	}
+}
+
+// Decodes a file of scanned QR codes, one part per line and any number of messages in any order, saving each message to In.
+// The server processes them on its next rescan. Returns how many messages were saved and how many failed.
+func ImportQRFile(path string) (int, int) {
+	contents, readErr := os.ReadFile(path)
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", path)
+		return 0, 0
+	}
+
+	imported, failed := 0, 0
+	for i, group := range qr.Group(strings.Split(string(contents), "\n")) {
+		message, decodeErr := qr.Decode(group)
+		if decodeErr != nil {
+			greenlogger.LogErrorf(decodeErr, "Problem decoding message %v of %v", i+1, path)
+			failed++
+			continue
+		}
+
+		result := storeQRMessage("", "", i, message, "")
+		switch {
+		case result.Duplicate:
+			greenlogger.LogMessagef("Message %v of %v was already submitted: %v", i+1, path, result.Message)
+		case result.Status == http.StatusOK:
+			greenlogger.LogMessagef("Message %v of %v: %v", i+1, path, result.Message)
+			imported++
+		default:
+			greenlogger.LogMessagef("Message %v of %v failed with %v: %v", i+1, path, result.Status, result.Message)
+			failed++
+		}
+	}
+
+	greenlogger.LogMessagef("Imported %v messages from %v, %v failed", imported, path, failed)
+	return imported, failed
+}
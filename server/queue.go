@@ -0,0 +1,254 @@
+package server
+
+// The queue of submitted scouting files waiting to be parsed and written to the sheet
+
+import (
+	"GreenScoutBackend/constants"
+	filemanager "GreenScoutBackend/fileManager"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/matchDB"
+	"GreenScoutBackend/sheet"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The suffix of files in In that are still being written, which are never queued
+const partialSuffix = ".partial"
+
+// How long clients are told to wait before resubmitting when the queue is full
+const queueFullRetrySeconds = 5
+
+// A queue of files in In, processed by a fixed number of workers.
+// Every file with the same key goes to the same worker, so entries for one match and driverstation are never processed at once.
+type ingestQueue struct {
+	shards   []chan string   // The files waiting for each worker
+	mutex    sync.Mutex      // Guards pending
+	pending  map[string]bool // Every file queued or being processed
+	capacity int             // How many files may be pending at once
+}
+
+// The queue used by the server
+var ingest *ingestQueue
+
+// Creates a queue with a number of workers and a maximum number of pending files. The workers aren't started until start is called.
+func newIngestQueue(workers int, capacity int) *ingestQueue {
+	queue := &ingestQueue{
+		shards:   make([]chan string, workers),
+		pending:  make(map[string]bool),
+		capacity: capacity,
+	}
+
+	for i := range queue.shards {
+		// Sends never block, as a shard can't hold more than every pending file
+		queue.shards[i] = make(chan string, capacity)
+	}
+
+	return queue
+}
+
+// Returns the key of a file in In: EVENT_MATCH_DS for match data, and EVENT_TEAM for pit scouting
+func ingestKey(fileName string) string {
+	parts := strings.Split(strings.TrimSuffix(fileName, ".json"), "_")
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+
+	return strings.Join(parts, "_")
+}
+
+// Returns the index of the worker handling a key
+func (queue *ingestQueue) shardOf(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(len(queue.shards)))
+}
+
+// Returns if the queue has no room for another file. Without a queue (when not serving), there's always room.
+func (queue *ingestQueue) full() bool {
+	if queue == nil {
+		return false
+	}
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return len(queue.pending) >= queue.capacity
+}
+
+// Adds a file in In to the queue, persisting it in matches.db. Files already pending are ignored.
+// Returns false if the queue is full, in which case the file is left for a later rescan.
+// Without a queue (when not serving), files are left for the server's first rescan.
+func (queue *ingestQueue) enqueue(fileName string) bool {
+	if queue == nil {
+		return false
+	}
+
+	queue.mutex.Lock()
+	if queue.pending[fileName] {
+		queue.mutex.Unlock()
+		return true
+	}
+	if len(queue.pending) >= queue.capacity {
+		queue.mutex.Unlock()
+		return false
+	}
+	queue.pending[fileName] = true
+	queue.mutex.Unlock()
+
+	key := ingestKey(fileName)
+	matchDB.EnqueueFile(fileName, key)
+	queue.shards[queue.shardOf(key)] <- fileName
+
+	return true
+}
+
+// Starts every worker
+func (queue *ingestQueue) start() {
+	for _, shard := range queue.shards {
+		go queue.work(shard)
+	}
+}
+
+// Processes the files sent to one worker, in order, until the program exits
+func (queue *ingestQueue) work(shard chan string) {
+	for fileName := range shard {
+		// A rescan can queue a file just before another worker finishes with it
+		if _, statErr := os.Stat(filepath.Join(constants.JsonInDirectory, fileName)); errors.Is(statErr, os.ErrNotExist) {
+			matchDB.DequeueFile(fileName)
+			queue.done(fileName)
+			continue
+		}
+
+		attempts := matchDB.RecordAttempt(fileName)
+		if processErr := processFile(fileName); processErr != nil {
+			if sheet.IsTransient(processErr) && attempts < constants.CachedConfigs.IngestConfigs.MaxAttempts {
+				// Still pending, so rescans leave it alone while it waits
+				queue.retryLater(fileName, attempts, processErr)
+				continue
+			}
+
+			deadLetter(fileName, attempts, processErr)
+		}
+
+		// Files that failed to move out of In stay queued, and will be picked up by the next rescan
+		if _, statErr := os.Stat(filepath.Join(constants.JsonInDirectory, fileName)); errors.Is(statErr, os.ErrNotExist) {
+			matchDB.DequeueFile(fileName)
+		}
+
+		queue.done(fileName)
+	}
+}
+
+// Returns how long to wait before retrying a file that has failed a number of times: the base wait, doubled for every earlier failure, up to the maximum.
+// Up to a tenth is added at random so a burst of failures doesn't retry all at once.
+func retryDelay(attempts int) time.Duration {
+	base := time.Duration(constants.CachedConfigs.IngestConfigs.RetryBaseSeconds) * time.Second
+	maximum := time.Duration(constants.CachedConfigs.IngestConfigs.RetryMaxSeconds) * time.Second
+
+	delay := base
+	for i := 1; i < attempts && delay < maximum; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maximum)
+
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
+
+// Sends a file that failed back to its worker after waiting, recording when in matches.db so the wait survives a restart
+func (queue *ingestQueue) retryLater(fileName string, attempts int, processErr error) {
+	delay := retryDelay(attempts)
+	matchDB.ScheduleRetry(fileName, time.Now().Add(delay), processErr.Error())
+	greenlogger.LogMessagef("Attempt %v at %v failed, retrying in %v: %v", attempts, fileName, delay.Round(time.Second), processErr)
+
+	key := ingestKey(fileName)
+	time.AfterFunc(delay, func() {
+		queue.shards[queue.shardOf(key)] <- fileName
+	})
+}
+
+// Removes a file from the pending files once a worker is finished with it
+func (queue *ingestQueue) done(fileName string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	delete(queue.pending, fileName)
+}
+
+// Queues every file in In that isn't already pending: first the ones persisted in matches.db in their original order, then any others.
+// This recovers files left by a crash or restart, or that arrived while the queue was full.
+func (queue *ingestQueue) rescan() {
+	allJson, readErr := os.ReadDir(constants.JsonInDirectory)
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading file %v", constants.JsonInDirectory)
+		return
+	}
+
+	inDirectory := make(map[string]bool)
+	for _, file := range allJson {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			inDirectory[file.Name()] = true
+		}
+	}
+
+	var ordered []string
+	for _, queued := range matchDB.GetQueuedFiles() {
+		if !inDirectory[queued.File] {
+			matchDB.DequeueFile(queued.File)
+			continue
+		}
+
+		// Waiting to be retried after a restart
+		if queued.RetryAt.After(time.Now()) {
+			delete(inDirectory, queued.File)
+			continue
+		}
+
+		ordered = append(ordered, queued.File)
+		delete(inDirectory, queued.File)
+	}
+
+	// ReadDir is sorted by name, which orders entries of the same match and driverstation by time
+	for _, file := range allJson {
+		if inDirectory[file.Name()] {
+			ordered = append(ordered, file.Name())
+		}
+	}
+
+	for _, fileName := range ordered {
+		if !queue.enqueue(fileName) {
+			greenlogger.LogMessagef("Ingestion queue is full, leaving files from %v onward for the next rescan", fileName)
+			return
+		}
+	}
+}
+
+// Writes a submission to In and queues it. The file is written under a temporary name first so it is never processed half-written.
+// An error is only returned if the file couldn't be saved; a full queue leaves it for the next rescan.
+func submitFile(fileName string, data any) error {
+	encoded, marshalErr := json.Marshal(data)
+	if marshalErr != nil {
+		greenlogger.LogErrorf(marshalErr, "Problem marshalling %v", data)
+		return marshalErr
+	}
+
+	path := filepath.Join(constants.JsonInDirectory, fileName)
+	if writeErr := filemanager.WriteFileWithPermissions(path+partialSuffix, encoded); writeErr != nil {
+		greenlogger.LogErrorf(writeErr, "Problem creating %v", path+partialSuffix)
+		return writeErr
+	}
+
+	if renameErr := os.Rename(path+partialSuffix, path); renameErr != nil {
+		greenlogger.LogErrorf(renameErr, "Problem moving %v to %v", path+partialSuffix, path)
+		return renameErr
+	}
+
+	ingest.enqueue(fileName)
+	return nil
+}
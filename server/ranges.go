@@ -0,0 +1,109 @@
+package server
+
+// Serving admins' edits to individual ranges of scouters' schedules
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/schedule"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Writes the error changing a schedule as the response, returning if there was one
+func writeRangeError(writer http.ResponseWriter, request *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, schedule.ErrRangeNotFound):
+		httpError(writer, request, http.StatusNotFound, "%v", err)
+	case errors.Is(err, schedule.ErrInvalidRange), errors.Is(err, schedule.ErrUnknownScouter):
+		httpError(writer, request, http.StatusBadRequest, "%v", err)
+	default:
+		httpError(writer, request, http.StatusInternalServerError, "There was a problem changing the schedule")
+	}
+	return true
+}
+
+// Writes a range, or the error changing it, as the response
+func writeRangeResponse(writer http.ResponseWriter, request *http.Request, scheduled schedule.ScheduledRange, err error) {
+	if writeRangeError(writer, request, err) {
+		return
+	}
+
+	encodeErr := json.NewEncoder(writer).Encode(scheduled)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", scheduled)
+	}
+}
+
+// Serves every range at the current event with its id. The username header limits it to one scouter's.
+func serveRanges(writer http.ResponseWriter, request *http.Request) {
+	ranges := schedule.GetRanges(request.Header.Get("username"))
+
+	encodeErr := json.NewEncoder(writer).Encode(ranges)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", ranges)
+	}
+}
+
+// Handles adding one range to a scouter's schedule
+func handleRangeCreation(writer http.ResponseWriter, request *http.Request) {
+	var scheduled schedule.ScheduledRange
+	if !decodeJSONBody(writer, request, &scheduled) {
+		return
+	}
+
+	created, err := schedule.CreateRange(scheduled)
+	writeRangeResponse(writer, request, created, err)
+}
+
+// Handles changing one range, named by its id
+func handleRangeUpdate(writer http.ResponseWriter, request *http.Request) {
+	var scheduled schedule.ScheduledRange
+	if !decodeJSONBody(writer, request, &scheduled) {
+		return
+	}
+
+	updated, err := schedule.UpdateRange(scheduled)
+	writeRangeResponse(writer, request, updated, err)
+}
+
+// Handles removing one range, named by its id
+func handleRangeDeletion(writer http.ResponseWriter, request *http.Request) {
+	var scheduled schedule.ScheduledRange
+	if !decodeJSONBody(writer, request, &scheduled) {
+		return
+	}
+
+	if writeRangeError(writer, request, schedule.DeleteRange(scheduled.ID)) {
+		return
+	}
+
+	httpResponsef(writer, "Problem writing http response to range deletion", "Successfully deleted range %v\n", scheduled.ID)
+}
+
+// Handles replacing every schedule at the current event, sent as a map of usernames to their ranges
+func handleScheduleReplacement(writer http.ResponseWriter, request *http.Request) {
+	var schedules map[string]schedule.ScoutRanges
+	if !decodeJSONBody(writer, request, &schedules) {
+		return
+	}
+
+	if writeRangeError(writer, request, schedule.ReplaceAllSchedules(schedules)) {
+		return
+	}
+
+	httpResponsef(writer, "Problem writing http response to schedule replacement", "Successfully replaced the schedules of %v scouters\n", len(schedules))
+}
+
+// Handles clearing every scouter's schedule, which also cancels every open swap
+func handleScheduleClear(writer http.ResponseWriter, request *http.Request) {
+	session, _ := sessionFromRequest(request)
+	if !schedule.ClearAllSchedules(session.Username) {
+		httpError(writer, request, http.StatusInternalServerError, "There was a problem clearing the schedules")
+		return
+	}
+
+	httpResponsef(writer, "Problem writing http response to schedule clear", "Successfully cleared every schedule\n")
+}
@@ -0,0 +1,79 @@
+package server
+
+// Request IDs and the error envelope every endpoint responds with when something goes wrong
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// The header a request ID is read from and written to
+const requestIDHeader = "X-Request-ID"
+
+// The body of every unsuccessful response
+type ErrorResponse struct {
+	Code      int    // The HTTP status code, repeated for clients that only see the body
+	Message   string // A human-readable description of what went wrong
+	RequestID string // The ID of the request, also found in the server logs
+}
+
+// The key the ID of a request is stored under in its context
+type requestIDContextKey struct{}
+
+// Returns the ID of a request, or an empty string if it wasn't given one
+func requestIDFromRequest(request *http.Request) string {
+	id, _ := request.Context().Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// A wrapper giving every request an ID, so errors reported by users can be matched to the logs.
+// Uses the client's X-Request-ID if it sent one.
+func withRequestID(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		handler(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id)))
+	}
+}
+
+// Writes an error response with a status code and a formatted message.
+// Server errors (5xx) are logged along with the request ID; client errors aren't, as they're expected.
+func httpError(writer http.ResponseWriter, request *http.Request, code int, message string, args ...any) {
+	response := ErrorResponse{
+		Code:      code,
+		Message:   fmt.Sprintf(message, args...),
+		RequestID: requestIDFromRequest(request),
+	}
+
+	if code >= 500 {
+		greenlogger.LogMessagef("Request %v to %v failed with %v: %v", response.RequestID, request.URL.Path, code, response.Message)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+
+	encodeErr := json.NewEncoder(writer).Encode(response)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", response)
+	}
+}
+
+// Decodes the JSON body of a request into a value, writing a 400 and returning false if it can't be
+func decodeJSONBody(writer http.ResponseWriter, request *http.Request, into any) bool {
+	decodeErr := json.NewDecoder(request.Body).Decode(into)
+	if decodeErr != nil {
+		greenlogger.LogErrorf(decodeErr, "Problem decoding the body of a request to %v", request.URL.Path)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request: %v", decodeErr)
+		return false
+	}
+	return true
+}
@@ -4,10 +4,10 @@ package server
 
 import (
 	"GreenScoutBackend/constants"
-	filemanager "GreenScoutBackend/fileManager"
 	"GreenScoutBackend/gallery"
 	greenlogger "GreenScoutBackend/greenLogger"
 	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
 	"GreenScoutBackend/pfp"
 	"GreenScoutBackend/rsaUtil"
 	"GreenScoutBackend/schedule"
@@ -27,15 +27,19 @@ import (
 	"time"
 )
 
-// Runs the infinite server loop with a looptime of 5 seconds.
+// Starts the ingestion queue's workers, then rescans In on an interval, forever.
+// The first rescan picks up anything left from before the server started.
 func RunServerLoop() {
-	ticker := time.NewTicker(5 * time.Second)
+	ingest.start()
+	ingest.rescan()
+
+	ticker := time.NewTicker(time.Duration(constants.CachedConfigs.IngestConfigs.RescanSeconds) * time.Second)
 	quit := make(chan struct{})
 	func() {
 		for {
 			select {
 			case <-ticker.C:
-				go iterativeServerCall()
+				ingest.rescan()
 			case <-quit:
 				ticker.Stop()
 				return
@@ -44,137 +48,194 @@ func RunServerLoop() {
 	}()
 }
 
-// The call to read and parse one file in InputtedJson
-func iterativeServerCall() {
-	allJson, readErr := os.ReadDir(constants.JsonInDirectory)
-	if readErr != nil {
-		greenlogger.LogErrorf(readErr, "Problem reading file %v", constants.JsonInDirectory)
-		return
-	}
+// Returned by processFile when a submission can't be parsed, which retrying won't fix
+var errUnparsable = errors.New("the submission couldn't be parsed")
+
+// Parses one file in In, stores it and writes it to the sheet, then moves it to Written.
+// If it fails, the file is left in In for the queue to retry or give up on.
+func processFile(fileName string) error {
+	// Parse and write to spreadsheet
+	if len(strings.Split(fileName, "_")) == 2 { // Pit Scouting
+		pit, hadErrs := lib.ParsePitScout(fileName, false)
+		if hadErrs {
+			return errUnparsable
+		}
 
-	// Avoid nil files
-	if len(allJson) > 0 {
-		// Only deal with first file
-		file := allJson[0]
+		if writeErr := sheet.WritePitDataToLine(pit, lib.GetPitRow(pit.TeamNumber)); writeErr != nil {
+			return writeErr
+		}
 
-		// Parse and write to spreadsheet
-		if len(strings.Split(file.Name(), "_")) == 2 { // Pit Scouting
-			pit, hadErrs := lib.ParsePitScout(file.Name())
+		matchDB.StorePitData(fileName, pit)
 
-			if !hadErrs {
-				if sheet.WritePitDataToLine(pit, lib.GetPitRow(pit.TeamNumber)) {
-					lib.MoveFile(filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonPitWrittenDirectory, file.Name()))
-					greenlogger.LogMessagef("Successfully Processed %v ", file.Name())
-					userDB.ModifyUserScore(pit.Scouter, userDB.Increase, 1)
-				} else { // Handle any errors writing
-					lib.MoveFile(filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonErroredDirectory, file.Name()))
-					greenlogger.LogMessagef("Errors in writing %v to sheet, moved to %v", filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonErroredDirectory, file.Name()))
-				}
-			} else { // Handle any errors opening
-				lib.MoveFile(filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonErroredDirectory, file.Name()))
-				greenlogger.LogMessagef("Errors in processing %v, moved to %v", filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonErroredDirectory, file.Name()))
+		lib.MoveFile(filepath.Join(constants.JsonInDirectory, fileName), filepath.Join(constants.JsonPitWrittenDirectory, fileName))
+		greenlogger.LogMessagef("Successfully Processed %v ", fileName)
+		userDB.ModifyUserScore(pit.Scouter, userDB.Increase, 1)
+		return nil
+	}
+
+	team, hadErrs := lib.Parse(fileName, false)
+	if hadErrs {
+		return errUnparsable
+	}
+
+	var writeErr error
+	allMatching := lib.GetAllMatching(fileName)
+	if constants.CachedConfigs.UsingMultiScouting && len(allMatching) > 0 { // Multi-scouting
+		var entries []lib.TeamData
+		entries = append(entries, team)
+		for _, foundFile := range allMatching {
+			if team.Rescouting { // If rescouting, the other ones are discarded once it's written
+				continue
 			}
-		} else {
-			team, hadErrs := lib.Parse(file.Name(), false)
-
-			var successfullyWrote bool
-
-			if !hadErrs {
-				if allMatching := lib.GetAllMatching(file.Name()); constants.CachedConfigs.UsingMultiScouting && len(allMatching) > 0 { // Multi-scouting
-					var entries []lib.TeamData
-					entries = append(entries, team)
-					for _, foundFile := range allMatching {
-						if team.Rescouting { // If rescouting, discard other ones
-							if !lib.MoveFile(filepath.Join(constants.JsonWrittenDirectory, foundFile), filepath.Join(constants.JsonDiscardedDirectory, foundFile)) {
-								greenlogger.LogMessage("File " + filepath.Join(constants.JsonWrittenDirectory, foundFile) + " unable to be moved to Discarded")
-							}
-						} else {
-							// Parse and add to parsed data
-							parsedData, foundErrs := lib.Parse(foundFile, true)
-							if !foundErrs {
-								entries = append(entries, parsedData)
-							} else {
-								if !lib.MoveFile(filepath.Join(constants.JsonWrittenDirectory, foundFile), filepath.Join(constants.JsonErroredDirectory, foundFile)) {
-									greenlogger.FatalLogMessage("File " + filepath.Join(constants.JsonWrittenDirectory, foundFile) + " unable to be moved to Errored, investigate this!")
-								} else {
-									greenlogger.NotifyMessage("Errors in processing " + filepath.Join(constants.JsonWrittenDirectory, foundFile) + ", moved to " + filepath.Join(constants.JsonErroredDirectory, foundFile))
-								}
-							}
-						}
-					}
-
-					if team.Rescouting {
-						successfullyWrote = sheet.WriteTeamDataToLine(team, lib.GetRow(team))
-					} else {
-						successfullyWrote = sheet.WriteMultiScoutedTeamDataToLine(
-							lib.CompileMultiMatch(entries...),
-							lib.GetRow(team),
-							entries,
-						)
-					}
-				} else { // Single scouting
-					successfullyWrote = sheet.WriteTeamDataToLine(team, lib.GetRow(team))
-				}
 
-				//Currently, there is no handling if one can't move. It will loop infinitley. This could be something to improve.
-				if successfullyWrote {
-					lib.MoveFile(filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonWrittenDirectory, file.Name()))
-					greenlogger.LogMessagef("Successfully Processed %v ", file.Name())
-					userDB.ModifyUserScore(team.Scouter, userDB.Increase, 1)
+			// Parse and add to parsed data
+			parsedData, foundErrs := lib.Parse(foundFile, true)
+			if !foundErrs {
+				entries = append(entries, parsedData)
+			} else {
+				if !lib.MoveFile(filepath.Join(constants.JsonWrittenDirectory, foundFile), filepath.Join(constants.JsonErroredDirectory, foundFile)) {
+					greenlogger.FatalLogMessage("File " + filepath.Join(constants.JsonWrittenDirectory, foundFile) + " unable to be moved to Errored, investigate this!")
 				} else {
-					lib.MoveFile(filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonErroredDirectory, file.Name()))
-					greenlogger.LogMessagef("Errors in writing %v to sheet, moved to %v", filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonErroredDirectory, file.Name()))
+					recordFailure(foundFile, erroredDirectory, errUnparsable, 0)
 				}
-			} else {
-				lib.MoveFile(filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonErroredDirectory, file.Name()))
-				greenlogger.LogMessagef("Errors in processing %v, moved to %v", filepath.Join(constants.JsonInDirectory, file.Name()), filepath.Join(constants.JsonErroredDirectory, file.Name()))
 			}
+		}
+
+		if team.Rescouting {
+			writeErr = sheet.WriteTeamDataToLine(team, lib.GetRow(team))
+			if writeErr == nil {
+				clearDiscrepancies(team)
+			}
+		} else {
+			multiMatch := lib.CompileMultiMatch(scouterWeights(), entries...)
+			writeErr = sheet.WriteMultiScoutedTeamDataToLine(multiMatch, lib.GetRow(team))
+			if writeErr == nil {
+				reportDiscrepancies(multiMatch, entries)
+			}
+		}
+	} else { // Single scouting
+		writeErr = sheet.WriteTeamDataToLine(team, lib.GetRow(team))
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	matchDB.StoreTeamData(fileName, team)
 
+	// A rescout replaces the earlier entries, which are only discarded now that it has been written
+	if team.Rescouting {
+		for _, foundFile := range allMatching {
+			discardFile(foundFile)
 		}
 	}
+
+	lib.MoveFile(filepath.Join(constants.JsonInDirectory, fileName), filepath.Join(constants.JsonWrittenDirectory, fileName))
+	greenlogger.LogMessagef("Successfully Processed %v ", fileName)
+	userDB.ModifyUserScore(team.Scouter, userDB.Increase, 1)
+	return nil
+}
+
+// Moves a file in Written that was replaced by a rescout to Discarded, and marks its stored entry as discarded
+func discardFile(fileName string) {
+	if !lib.MoveFile(filepath.Join(constants.JsonWrittenDirectory, fileName), filepath.Join(constants.JsonDiscardedDirectory, fileName)) {
+		greenlogger.LogMessage("File " + filepath.Join(constants.JsonWrittenDirectory, fileName) + " unable to be moved to Discarded")
+	}
+	matchDB.MarkDiscarded(fileName)
 }
 
 // Returns a configured server object
 func SetupServer() *http.Server {
+	ingest = newIngestQueue(constants.CachedConfigs.IngestConfigs.Workers, constants.CachedConfigs.IngestConfigs.QueueSize)
+
+	// The permission of each endpoint is in permissions.go
+
 	//No authentication
-	http.HandleFunc("/", handleWithCORS(handleRoot, true))
-	http.HandleFunc("/pub", handleWithCORS(servePublicKey, false))
-	http.HandleFunc("/schedule", handleWithCORS(handleScheduleRequest, true))
-	http.HandleFunc("/leaderboard", handleWithCORS(serveLeaderboard, true))
-	http.HandleFunc("/scouterLookup", handleWithCORS(serveMatchScouter, true))
-	http.HandleFunc("/userInfo", handleWithCORS(serveUserInfo, true))
-	http.HandleFunc("/certificateValid", handleWithCORS(handleCertificateVerification, true))
-	http.HandleFunc("/getPfp", handleWithCORS(handlePfpRequest, true))
-	http.HandleFunc("/generalInfo", handleWithCORS(handleGeneralInfoRequest, true))
-	http.HandleFunc("/allEvents", handleWithCORS(handleEventsRequest, true))
-	http.HandleFunc("/gallery", handleWithCORS(handleGalleryRequest, true))
-	http.HandleFunc("/adminUserInfo", handleWithCORS(serveUserInfoForAdmins, true))
+	handle("/", handleRoot)
+	handle("/pub", servePublicKey)
+	handle("/schedule", handleScheduleRequest)
+	handle("/leaderboard", serveLeaderboard)
+	handle("/scouterLookup", serveMatchScouter)
+	handle("/userInfo", serveUserInfo)
+	handle("/certificateValid", handleCertificateVerification)
+	handle("/getPfp", handlePfpRequest)
+	handle("/generalInfo", handleGeneralInfoRequest)
+	handle("/allEvents", handleEventsRequest)
+	handle("/gallery", handleGalleryRequest)
 
 	//Provides Authentication
-	http.HandleFunc("/login", handleWithCORS(handleLoginRequest, false))
+	handle("/login", handleLoginRequest)
+
+	//Authenticated by the current password
+	handle("/changePassword", handlePasswordChange)
 
 	//Any Authentication
-	http.HandleFunc("/dataEntry", handleWithCORS(postJson, true))
-	http.HandleFunc("/pitScout", handleWithCORS(postPitScout, true))
-	http.HandleFunc("/singleSchedule", handleWithCORS(serveScouterSchedule, true))
+	handle("/dataEntry", idempotent(postJson))
+	handle("/dataEntry/batch", postBatch)
+	handle("/qrEntry", postQR)
+	handle("/pitScout", idempotent(postPitScout))
+	handle("/singleSchedule", serveScouterSchedule)
+	handle("/swaps", serveSwaps)
+	handle("/swap/propose", handleSwapProposal)
+	handle("/swap/accept", handleSwapAcceptance)
+	handle("/swap/decline", handleSwapDecline)
+	handle("/swap/cancel", handleSwapCancellation)
+	handle("/sessions", serveSessionsRequest)
+	handle("/logout", handleLogout)
 
 	//Admin or curr user
-	http.HandleFunc("/setDisplayName", handleWithCORS(setDisplayName, true))
-	http.HandleFunc("/setUserPfp", handleWithCORS(setPfp, true))
-	http.HandleFunc("/provideAdditions", handleWithCORS(handleFrontendAdditions, true))
-	http.HandleFunc("/setColor", handleWithCORS(handleColorChange, true))
+	handle("/setDisplayName", setDisplayName)
+	handle("/setUserPfp", setPfp)
+	handle("/provideAdditions", handleFrontendAdditions)
+	handle("/setColor", handleColorChange)
 
 	//Admin or verified
-	http.HandleFunc("/spreadsheet", handleWithCORS(serveSpreadsheet, true))
+	handle("/spreadsheet", serveSpreadsheet)
+	handle("/teamStats", serveTeamStats)
+	handle("/teamRatings", serveTeamRatings)
+	handle("/predictions", servePredictions)
+	handle("/pickLists", servePickLists)
+	handle("/pickList", servePickList)
+	handle("/pickList/create", handlePickListCreation)
+	handle("/pickList/order", handlePickListOrder)
+	handle("/pickList/team", handlePickListTeam)
+	handle("/pickList/removeTeam", handlePickListRemoval)
+	handle("/pickList/history", servePickListHistory)
+	handle("/pickList/updates", streamPickListUpdates)
+	handle("/allianceSelection", serveAllianceSelection)
 
 	//Admin tools
-	http.HandleFunc("/addSchedule", handleWithCORS(addIndividualSchedule, true))
-	http.HandleFunc("/modScore", handleWithCORS(handleScoreChange, true))
-	http.HandleFunc("/allUsers", handleWithCORS(serveUsersRequest, true))
-	http.HandleFunc("/addBadge", handleWithCORS(addBadge, true))
-	http.HandleFunc("/badgeConfig", handleWithCORS(setBadges, false))
-	http.HandleFunc("/keyChange", handleWithCORS(handleKeyChange, false))
-	http.HandleFunc("/sheetChange", handleWithCORS(handleSheetChange, false))
+	handle("/adminUserInfo", serveUserInfoForAdmins)
+	handle("/addSchedule", addIndividualSchedule)
+	handle("/ranges", serveRanges)
+	handle("/range/create", handleRangeCreation)
+	handle("/range/update", handleRangeUpdate)
+	handle("/range/delete", handleRangeDeletion)
+	handle("/replaceSchedules", handleScheduleReplacement)
+	handle("/clearSchedules", handleScheduleClear)
+	handle("/generateSchedule", handleScheduleGeneration)
+	handle("/scheduleReport", serveScheduleReport)
+	handle("/swap/review", handleSwapReview)
+	handle("/swap/audit", serveSwapAudit)
+	handle("/modScore", handleScoreChange)
+	handle("/allUsers", serveUsersRequest)
+	handle("/addBadge", addBadge)
+	handle("/badgeConfig", setBadges)
+	handle("/keyChange", handleKeyChange)
+	handle("/sheetChange", handleSheetChange)
+	handle("/accounts", serveAccountsRequest)
+	handle("/addAccount", handleAddAccount)
+	handle("/resetPassword", handlePasswordReset)
+	handle("/setAccountDisabled", handleAccountDisable)
+	handle("/revokeSessions", handleSessionRevocation)
+	handle("/failures", serveFailures)
+	handle("/failure", serveFailure)
+	handle("/requeue", handleRequeue)
+	handle("/validationReport", serveValidationReport)
+	handle("/scouterAccuracy", serveScouterAccuracy)
+	handle("/discrepancies", serveDiscrepancies)
+	handle("/pickList/delete", handlePickListDeletion)
+	handle("/allianceSelection/mark", handleAllianceSelectionMark)
 
 	jsrv := &http.Server{
 		Addr: ":8443",
@@ -191,161 +252,119 @@ func SetupServer() *http.Server {
 
 // Handles calls to any non-specified extension of the domain
 func handleRoot(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		httpError(writer, request, http.StatusNotFound, "%v doesn't exist", request.URL.Path)
+		return
+	}
+
 	httpResponsef(writer, "Problem writing http response to root request", "howdy!")
 }
 
-// Handles posting of scouting JSON to the server
-func postJson(writer http.ResponseWriter, request *http.Request) {
-
-	_, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate")) //Don't care about specific role for post, everyone that is auth'd can.
-
-	if authenticated {
-		requestBytes, readErr := io.ReadAll(request.Body)
-
-		if readErr != nil {
-			greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
-		}
-
-		var team lib.TeamData
-		unmarshalErr := json.Unmarshal(requestBytes, &team)
-
-		if unmarshalErr != nil { // Handle mangling
-			greenlogger.LogErrorf(unmarshalErr, "MANGLED: %v", requestBytes)
-
-			newFileName := filepath.Join(constants.JsonMangledDirectory, time.Now().String()+".json")
-			mangledFile, openErr := filemanager.OpenWithPermissions(newFileName)
-			if openErr != nil {
-				greenlogger.LogErrorf(openErr, "Problem creating %v", newFileName)
-			}
+// Returns the name a match submission is saved as in In: EVENT_MATCH_{COLOR}{DSNUM}_SystemTimeMS.json
+func matchFileName(team lib.TeamData) string {
+	return fmt.Sprintf(
+		"%s_%v_%s_%v.json",
+		lib.GetCurrentEvent(),
+		team.Match.Number,
+		lib.GetDSString(team.DriverStation.IsBlue, uint(team.DriverStation.Number)),
+		time.Now().UnixMilli(),
+	)
+}
 
-			defer mangledFile.Close()
+// Returns the name a pit scouting submission is saved as in In: EVENT_TEAM.json
+func pitFileName(pit lib.PitScoutingData) string {
+	return fmt.Sprintf("%s_%v.json", lib.GetCurrentEvent(), pit.TeamNumber)
+}
 
-			writer.WriteHeader(500)
+// Turns a submission away while the ingestion queue is full, telling the client when to retry
+func serveQueueFull(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Retry-After", strconv.Itoa(queueFullRetrySeconds))
+	httpError(writer, request, http.StatusServiceUnavailable, "The server is busy processing other submissions. Please try again shortly.")
+}
 
-			httpResponsef(writer, "Problem writing http response to Mangled JSON", ":(")
-		} else { // Handle successful unmarshalling
-			//EVENT_MATCH_{COLOR}{DSNUM}_SystemTimeMS
-			fileName := fmt.Sprintf(
-				"%s_%v_%s_%v",
-				lib.GetCurrentEvent(),
-				team.Match.Number,
-				lib.GetDSString(team.DriverStation.IsBlue, uint(team.DriverStation.Number)),
-				time.Now().UnixMilli(),
-			)
+// Handles posting of scouting JSON to the server
+func postJson(writer http.ResponseWriter, request *http.Request) {
+	if ingest.full() {
+		serveQueueFull(writer, request)
+		return
+	}
 
-			file, openErr := filemanager.OpenWithPermissions(filepath.Join(constants.JsonInDirectory, fileName+".json"))
-			if openErr != nil {
-				greenlogger.LogErrorf(openErr, "Problem creating %v", filepath.Join(constants.JsonInDirectory, fileName+".json"))
-			}
-			defer file.Close()
+	requestBytes, readErr := io.ReadAll(request.Body)
 
-			encodeErr := json.NewEncoder(file).Encode(&team)
-			if encodeErr != nil {
-				greenlogger.LogErrorf(encodeErr, "Problem encoding %v", team)
-			}
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
+	}
 
-			if request.Header.Get("joshtown") == "tumble" { //This was used for testing during 2024 GCR. It also used to be more crudely worded.
-				writer.WriteHeader(500)
-			}
+	fileName, storeErr := storeSubmission(matchSubmission, requestBytes)
+	if storeErr != nil {
+		serveSubmissionError(writer, request, storeErr)
+		return
+	}
 
-			httpResponsef(writer, "Problem writing http response to JSON post request", "Processed %v\n", fileName)
-		}
-	} else {
-		writer.WriteHeader(500)
-		httpResponsef(writer, "Problem writing http response to JSON post request with insufficient authentication", "Not authenticated :(")
+	if request.Header.Get("joshtown") == "tumble" { //This was used for testing during 2024 GCR. It also used to be more crudely worded.
+		httpError(writer, request, http.StatusInternalServerError, "Simulated failure of %v", fileName)
+		return
 	}
+
+	httpResponsef(writer, "Problem writing http response to JSON post request", "Processed %v\n", fileName)
 }
 
 // Handles posting of pit scouting JSON to the server
 func postPitScout(writer http.ResponseWriter, request *http.Request) {
-	_, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate")) //Don't care about specific role for post, everyone that is auth'd can.
-
-	if authenticated {
-		requestBytes, readErr := io.ReadAll(request.Body)
-
-		if readErr != nil {
-			greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
-		}
-
-		var pit lib.PitScoutingData
-		unmarshalErr := json.Unmarshal(requestBytes, &pit)
-
-		if unmarshalErr != nil { // Handling mangling
-			greenlogger.LogErrorf(unmarshalErr, "MANGLED: %v", requestBytes)
-
-			newFileName := filepath.Join(constants.JsonMangledDirectory, time.Now().String()+".json")
-			mangledFile, openErr := filemanager.OpenWithPermissions(newFileName)
-			if openErr != nil {
-				greenlogger.LogErrorf(openErr, "Problem creating %v", newFileName)
-			}
-
-			defer mangledFile.Close()
-
-			writer.WriteHeader(500)
+	if ingest.full() {
+		serveQueueFull(writer, request)
+		return
+	}
 
-			httpResponsef(writer, "Problem writing http response to Mangled JSON", ":(")
-		} else {
-			//EVENT_TEAM.json
-			fileName := fmt.Sprintf(
-				"%s_%v",
-				lib.GetCurrentEvent(),
-				pit.TeamNumber,
-			)
-
-			file, openErr := filemanager.OpenWithPermissions(filepath.Join(constants.JsonInDirectory, fileName+".json"))
-			if openErr != nil {
-				greenlogger.LogErrorf(openErr, "Problem creating %v", filepath.Join(constants.JsonInDirectory, fileName+".json"))
-			}
-			defer file.Close()
+	requestBytes, readErr := io.ReadAll(request.Body)
 
-			encodeErr := json.NewEncoder(file).Encode(&pit)
-			if encodeErr != nil {
-				greenlogger.LogErrorf(encodeErr, "Problem encoding %v", pit)
-			}
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
+	}
 
-			httpResponsef(writer, "Problem writing http response to JSON post request", "Processed %v\n", fileName)
-		}
-	} else {
-		writer.WriteHeader(500)
-		httpResponsef(writer, "Problem writing http response to JSON post request with insufficient authentication", "Not authenticated :(")
+	fileName, storeErr := storeSubmission(pitSubmission, requestBytes)
+	if storeErr != nil {
+		serveSubmissionError(writer, request, storeErr)
+		return
 	}
+
+	httpResponsef(writer, "Problem writing http response to JSON post request", "Processed %v\n", fileName)
 }
 
 // Handles requests to change the event key
 func handleKeyChange(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	if authenticated && (role == "admin" || role == "super") {
-		requestBytes, readErr := io.ReadAll(request.Body)
-		if readErr != nil {
-			greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
-			return
-		}
+	requestBytes, readErr := io.ReadAll(request.Body)
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
+	}
 
-		newKey := string(requestBytes)
+	newKey := string(requestBytes)
 
-		if setup.SetEventKey(newKey) {
-			httpResponsef(writer, "Problem writing http response to successful event key change", "Successfully changed event key to %v\n", newKey)
-		} else {
-			httpResponsef(writer, "Problem writing http response to unsuccessful event key change", "There was a problem changing the event key to %v, make sure it's valid!\n", newKey)
-		}
-	} else if !authenticated {
-		httpResponsef(writer, "Problem writing http response to unauthorized attempt to change event key", "Not successfully authenticated. Please ensure you have correct login details.\n")
+	session, _ := sessionFromRequest(request)
+	if setup.SetEventKey(newKey, session.Username) {
+		httpResponsef(writer, "Problem writing http response to successful event key change", "Successfully changed event key to %v\n", newKey)
 	} else {
-		httpResponsef(writer, "Problem writing http response to non-super attempt to change event key", "Not a super user. womp womp\n")
+		httpError(writer, request, http.StatusBadRequest, "There was a problem changing the event key to %v, make sure it's valid!", newKey)
 	}
 }
 
 // Handles requests for schedule.json
 func handleScheduleRequest(writer http.ResponseWriter, request *http.Request) {
 	schedPath := filepath.Join(constants.CachedConfigs.RuntimeDirectory, "schedule.json")
-	file, openErr := os.Open(schedPath)
-	if openErr != nil {
-		greenlogger.LogErrorf(openErr, "Problem opening %v", schedPath)
-	}
-
-	fileBytes, readErr := io.ReadAll(file)
-	if readErr != nil {
-		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+	fileBytes, readErr := os.ReadFile(schedPath)
+	if errors.Is(readErr, os.ErrNotExist) {
+		httpError(writer, request, http.StatusNotFound, "There is no schedule for the current event")
+		return
+	} else if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", schedPath)
+		httpError(writer, request, http.StatusInternalServerError, "Could not read the schedule")
+		return
 	}
 
 	httpResponsef(writer, "Problem writing http response to schedule request", "%s", string(fileBytes))
@@ -358,27 +377,53 @@ func handleLoginRequest(writer http.ResponseWriter, request *http.Request) {
 	decodeErr := json.NewDecoder(request.Body).Decode(&loginRequest)
 	if decodeErr != nil && !errors.Is(decodeErr, io.EOF) {
 		greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the login request")
+		return
 	}
 
 	encryptedBytes, err := base64.StdEncoding.DecodeString(loginRequest.EncryptedPassword)
 	if err != nil {
 		greenlogger.LogErrorf(err, "Problem decoding %v", loginRequest.EncryptedPassword)
+		httpError(writer, request, http.StatusBadRequest, "The password isn't valid base64")
+		return
 	}
 
-	role, authenticated := userDB.Authenticate(encryptedBytes)
+	newEncryptedBytes, err := base64.StdEncoding.DecodeString(loginRequest.NewEncryptedPassword)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem decoding %v", loginRequest.NewEncryptedPassword)
+		httpError(writer, request, http.StatusBadRequest, "The new password isn't valid base64")
+		return
+	}
 
-	if authenticated {
-		uuid, _ := userDB.GetUUID(loginRequest.Username, true)
+	role, authErr := userDB.Authenticate(loginRequest.Username, encryptedBytes, newEncryptedBytes)
+	if errors.Is(authErr, userDB.ErrJoinNeedsPassword) {
+		httpError(writer, request, http.StatusBadRequest, "%v", authErr)
+		return
+	} else if authErr != nil {
+		httpError(writer, request, http.StatusUnauthorized, "Incorrect username or password")
+		return
+	}
 
-		writer.Header().Add("UUID", fmt.Sprintf("%v", uuid))
-		writer.Header().Add("Certificate", fmt.Sprintf("%v", userDB.GetCertificate(loginRequest.Username, role)))
+	writer.Header().Add("MustChangePassword", strconv.FormatBool(userDB.MustChangePassword(loginRequest.Username)))
 
-		if role == "super" {
-			userDB.AddBadge(uuid, userDB.Badge{ID: string(userDB.Admin)})
-			userDB.AddBadge(uuid, userDB.Badge{ID: string(userDB.Super)})
-		} else if role == "admin" {
-			userDB.AddBadge(uuid, userDB.Badge{ID: string(userDB.Admin)})
-		}
+	uuid, _ := userDB.GetUUID(loginRequest.Username, true)
+
+	device := loginRequest.Device
+	if device == "" {
+		device = request.UserAgent()
+	}
+
+	token, session := userDB.CreateSession(loginRequest.Username, role, device)
+
+	writer.Header().Add("UUID", fmt.Sprintf("%v", uuid))
+	writer.Header().Add("Certificate", token)
+	writer.Header().Add("SessionExpires", session.ExpiresAt.UTC().Format(time.RFC3339))
+
+	if role == userDB.RoleSuper {
+		userDB.AddBadge(uuid, userDB.Badge{ID: string(userDB.Admin)})
+		userDB.AddBadge(uuid, userDB.Badge{ID: string(userDB.Super)})
+	} else if role == userDB.RoleAdmin {
+		userDB.AddBadge(uuid, userDB.Badge{ID: string(userDB.Admin)})
 	}
 
 	writer.Header().Add("Role", role)
@@ -386,6 +431,198 @@ func handleLoginRequest(writer http.ResponseWriter, request *http.Request) {
 	httpResponsef(writer, "Problem writing http response to login request", "User accepted as: %s", role)
 }
 
+// Decodes an account request from an admin, writing an error and returning false if it is malformed.
+// Supers are the only ones able to manage super accounts.
+func decodeAccountRequest(writer http.ResponseWriter, request *http.Request) (userDB.AccountRequest, []byte, bool) {
+	var accountRequest userDB.AccountRequest
+
+	session, _ := sessionFromRequest(request)
+
+	decodeErr := json.NewDecoder(request.Body).Decode(&accountRequest)
+	if decodeErr != nil {
+		greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the account request")
+		return accountRequest, nil, false
+	}
+
+	targetAccount, _ := userDB.GetAccount(accountRequest.Username)
+	if session.Role != userDB.RoleSuper && (accountRequest.Role == userDB.RoleSuper || targetAccount.Role == userDB.RoleSuper) {
+		httpError(writer, request, http.StatusForbidden, "Only supers can manage super accounts")
+		return accountRequest, nil, false
+	}
+
+	encryptedBytes, base64Err := base64.StdEncoding.DecodeString(accountRequest.EncryptedPassword)
+	if base64Err != nil {
+		greenlogger.LogErrorf(base64Err, "Problem decoding %v", accountRequest.EncryptedPassword)
+		httpError(writer, request, http.StatusBadRequest, "The password isn't valid base64")
+		return accountRequest, nil, false
+	}
+
+	return accountRequest, encryptedBytes, true
+}
+
+// Writes the response to an account change, choosing the status code of a failure from its error
+func writeAccountResponse(writer http.ResponseWriter, request *http.Request, err error, success string, args ...any) {
+	switch {
+	case err == nil:
+	case errors.Is(err, userDB.ErrAccountExists):
+		httpError(writer, request, http.StatusConflict, "%v", err)
+		return
+	case errors.Is(err, userDB.ErrAccountNotFound):
+		httpError(writer, request, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, userDB.ErrInvalidRole), errors.Is(err, userDB.ErrPasswordTooShort):
+		httpError(writer, request, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		httpError(writer, request, http.StatusInternalServerError, "There was a problem updating the account")
+		return
+	}
+
+	httpResponsef(writer, "Problem writing http response to successful account change", success, args...)
+}
+
+// Serves every account
+func serveAccountsRequest(writer http.ResponseWriter, request *http.Request) {
+	accounts := userDB.GetAllAccounts()
+	encodeErr := json.NewEncoder(writer).Encode(accounts)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", accounts)
+	}
+}
+
+// Handles admin requests to create an account
+func handleAddAccount(writer http.ResponseWriter, request *http.Request) {
+	accountRequest, encryptedBytes, ok := decodeAccountRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	err := userDB.AddAccount(accountRequest.Username, accountRequest.Role, encryptedBytes)
+	writeAccountResponse(writer, request, err, "Successfully created %v account for %v\n", accountRequest.Role, accountRequest.Username)
+}
+
+// Handles admin requests to reset the password of an account
+func handlePasswordReset(writer http.ResponseWriter, request *http.Request) {
+	accountRequest, encryptedBytes, ok := decodeAccountRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	err := userDB.ResetPassword(accountRequest.Username, encryptedBytes)
+	writeAccountResponse(writer, request, err, "Successfully reset the password of %v\n", accountRequest.Username)
+}
+
+// Handles admin requests to disable or re-enable an account
+func handleAccountDisable(writer http.ResponseWriter, request *http.Request) {
+	accountRequest, _, ok := decodeAccountRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	err := userDB.SetAccountDisabled(accountRequest.Username, accountRequest.Disabled)
+	writeAccountResponse(writer, request, err, "Successfully set disabled of %v to %v\n", accountRequest.Username, accountRequest.Disabled)
+}
+
+// Handles users changing their own password, including the required change after their first login
+func handlePasswordChange(writer http.ResponseWriter, request *http.Request) {
+	var change userDB.PasswordChange
+
+	decodeErr := json.NewDecoder(request.Body).Decode(&change)
+	if decodeErr != nil {
+		greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the password change")
+		return
+	}
+
+	oldBytes, oldErr := base64.StdEncoding.DecodeString(change.EncryptedPassword)
+	newBytes, newErr := base64.StdEncoding.DecodeString(change.NewEncryptedPassword)
+	if oldErr != nil || newErr != nil {
+		httpError(writer, request, http.StatusBadRequest, "The passwords aren't valid base64")
+		return
+	}
+
+	err := userDB.ChangePassword(change.Username, oldBytes, newBytes)
+	if errors.Is(err, userDB.ErrWrongPassword) || errors.Is(err, userDB.ErrAccountNotFound) {
+		httpError(writer, request, http.StatusUnauthorized, "Not successfully authenticated. Please ensure you have correct login details.")
+		return
+	}
+
+	writeAccountResponse(writer, request, err, "Successfully changed the password of %v\n", change.Username)
+}
+
+// A session as listed to its user, without anything that could be used to authenticate as it
+type sessionListing struct {
+	ID        string    // The identifier of the session
+	Device    string    // The device it was issued to
+	IssuedAt  time.Time // When it was issued
+	ExpiresAt time.Time // When it expires unless used again
+	LastSeen  time.Time // When it was last used
+	Current   bool      // If it is the session making the request
+}
+
+// Serves the active sessions of the requesting user. Admins can pass a username header to list another user's sessions.
+func serveSessionsRequest(writer http.ResponseWriter, request *http.Request) {
+	current, _ := sessionFromRequest(request)
+
+	username := current.Username
+	if requested := request.Header.Get("username"); requested != "" && requested != username {
+		if !isAdminRole(current.Role) {
+			httpError(writer, request, http.StatusForbidden, "Only admins can list the sessions of other users")
+			return
+		}
+		username = requested
+	}
+
+	listings := []sessionListing{}
+	for _, session := range userDB.GetSessions(username) {
+		listings = append(listings, sessionListing{
+			ID:        session.ID,
+			Device:    session.Device,
+			IssuedAt:  session.IssuedAt,
+			ExpiresAt: session.ExpiresAt,
+			LastSeen:  session.LastSeen,
+			Current:   session.ID == current.ID,
+		})
+	}
+
+	encodeErr := json.NewEncoder(writer).Encode(listings)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", listings)
+	}
+}
+
+// Handles logging out. Logs out the requesting session, or another of the user's sessions if its ID is passed as the session header.
+func handleLogout(writer http.ResponseWriter, request *http.Request) {
+	token := request.Header.Get("Certificate")
+	current, _ := sessionFromRequest(request)
+
+	var revoked bool
+	if id := request.Header.Get("session"); id != "" && id != current.ID {
+		revoked = userDB.RevokeSessionByID(current.Username, id)
+	} else {
+		revoked = userDB.RevokeSession(token)
+	}
+
+	if !revoked {
+		httpError(writer, request, http.StatusNotFound, "No such session")
+		return
+	}
+
+	httpResponsef(writer, "Problem writing http response to logout", "Successfully logged out\n")
+}
+
+// Handles admin requests to revoke every session of a user, logging them out everywhere
+func handleSessionRevocation(writer http.ResponseWriter, request *http.Request) {
+	accountRequest, _, ok := decodeAccountRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	revoked := userDB.RevokeSessions(accountRequest.Username)
+	httpResponsef(writer, "Problem writing http response to session revocation", "Revoked %v sessions of %v\n", revoked, accountRequest.Username)
+}
+
 // Serves the public RSA key
 func servePublicKey(writer http.ResponseWriter, request *http.Request) {
 	writer.Header().Add("Content-Type", "application/x-pem-file")
@@ -395,19 +632,25 @@ func servePublicKey(writer http.ResponseWriter, request *http.Request) {
 
 // Handles changing the google sheets id
 func handleSheetChange(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	if authenticated && (role == "admin" || role == "super") {
-		requestBytes, readErr := io.ReadAll(request.Body)
-		if readErr != nil {
-			greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
-		}
-
-		newID := string(requestBytes)
+	requestBytes, readErr := io.ReadAll(request.Body)
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
+	}
 
-		response := sheet.UpdateSheetID(newID)
+	newID := string(requestBytes)
 
-		httpResponsef(writer, "Problem writing http response to sheet change request", "%s", response)
+	updateErr := sheet.UpdateSheetID(newID)
+	if errors.Is(updateErr, sheet.ErrInvalidSheetID) {
+		httpError(writer, request, http.StatusBadRequest, "Sheet ID %v is invalid!", newID)
+		return
+	} else if updateErr != nil {
+		httpError(writer, request, http.StatusInternalServerError, "There was a problem updating the sheet ID")
+		return
 	}
+
+	httpResponsef(writer, "Problem writing http response to sheet change request", "Successfully updated sheet ID to %s", newID)
 }
 
 // Handles serving the schedule for one scouter
@@ -415,6 +658,8 @@ func serveScouterSchedule(writer http.ResponseWriter, request *http.Request) {
 	requestBytes, readErr := io.ReadAll(request.Body)
 	if readErr != nil {
 		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
 	}
 
 	nameToLookup := string(requestBytes)
@@ -426,23 +671,88 @@ func serveScouterSchedule(writer http.ResponseWriter, request *http.Request) {
 
 // Handles adding schedules to a given scouter
 func addIndividualSchedule(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	if authenticated && (role == "admin" || role == "super") {
-		requestBytes, readErr := io.ReadAll(request.Body)
-		if readErr != nil {
-			greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
-		}
-		var requestStruct schedule.ScoutRanges
+	requestBytes, readErr := io.ReadAll(request.Body)
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
+	}
+	var requestStruct schedule.ScoutRanges
+
+	nameToLookup := request.Header.Get("userInput")
+	if nameToLookup == "" {
+		httpError(writer, request, http.StatusBadRequest, "No scouter was given in the userInput header")
+		return
+	}
+
+	unmarshalErr := json.Unmarshal(requestBytes, &requestStruct)
+	if unmarshalErr != nil {
+		greenlogger.LogErrorf(unmarshalErr, "Error unmarshalling %v", requestBytes)
+		httpError(writer, request, http.StatusBadRequest, "Could not parse the schedule: %v", unmarshalErr)
+		return
+	}
+
+	addErr := schedule.AddIndividualSchedule(nameToLookup, true, requestStruct)
+	if errors.Is(addErr, schedule.ErrInvalidRange) {
+		httpError(writer, request, http.StatusBadRequest, "%v", addErr)
+		return
+	} else if addErr != nil {
+		httpError(writer, request, http.StatusInternalServerError, "There was a problem adding the schedule")
+		return
+	}
+
+	httpResponsef(writer, "Problem writing http response for individual schedule change request", "Successfully added schedule for %s", nameToLookup)
+}
+
+// Handles generating a rotation of scouters over every match in schedule.json, writing it unless it's a dry run
+func handleScheduleGeneration(writer http.ResponseWriter, request *http.Request) {
+	var assignmentRequest schedule.AssignmentRequest
+
+	decodeErr := json.NewDecoder(request.Body).Decode(&assignmentRequest)
+	if decodeErr != nil {
+		greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the assignment request")
+		return
+	}
 
-		nameToLookup := request.Header.Get("userInput")
-		unmarshalErr := json.Unmarshal(requestBytes, &requestStruct)
-		if unmarshalErr != nil {
-			greenlogger.LogErrorf(unmarshalErr, "Error unmarshalling %v", requestBytes)
+	assignment, err := schedule.GenerateAssignments(assignmentRequest)
+	switch {
+	case err == nil:
+	case errors.Is(err, schedule.ErrNoScouters), errors.Is(err, schedule.ErrInvalidShift), errors.Is(err, schedule.ErrDuplicateScouter):
+		httpError(writer, request, http.StatusBadRequest, "%v", err)
+		return
+	case errors.Is(err, schedule.ErrNoMatches):
+		httpError(writer, request, http.StatusConflict, "%v", err)
+		return
+	default:
+		httpError(writer, request, http.StatusInternalServerError, "There was a problem writing the generated schedules")
+		return
+	}
+
+	encodeErr := json.NewEncoder(writer).Encode(assignment)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", assignment)
+	}
+}
+
+// Serves a report of every gap and conflict in the stored schedules, and how they compare to what was submitted.
+// The maxShift header sets the longest shift allowed before it's reported.
+func serveScheduleReport(writer http.ResponseWriter, request *http.Request) {
+	maxShiftLength := schedule.DefaultMaxShiftLength
+	if header := request.Header.Get("maxShift"); header != "" {
+		parsed, parseErr := strconv.Atoi(header)
+		if parseErr != nil || parsed <= 0 {
+			httpError(writer, request, http.StatusBadRequest, "Could not parse the max shift length %v", header)
+			return
 		}
+		maxShiftLength = parsed
+	}
 
-		schedule.AddIndividualSchedule(nameToLookup, true, requestStruct)
+	report := schedule.GenerateCoverageReport(maxShiftLength)
 
-		httpResponsef(writer, "Problem writing http response for individual schedule change request", "Successfully added schedule for %s", nameToLookup)
+	encodeErr := json.NewEncoder(writer).Encode(report)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", report)
 	}
 }
 
@@ -465,66 +775,73 @@ func serveLeaderboard(writer http.ResponseWriter, request *http.Request) {
 	}
 }
 
+// Returns how reliable every rated scouter is, from their accuracy rating, for merging multi-scouted data
+func scouterWeights() lib.ScouterWeights {
+	weights := make(lib.ScouterWeights)
+	for _, rating := range userDB.GetScouterAccuracy() {
+		weights[rating.Username] = rating.Rating / 100
+	}
+	return weights
+}
+
+// Serves the accuracy rating of every scouter compared to TBA score breakdowns, most accurate first
+func serveScouterAccuracy(writer http.ResponseWriter, request *http.Request) {
+	ratings := userDB.GetScouterAccuracy()
+	encodeErr := json.NewEncoder(writer).Encode(ratings)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", ratings)
+	}
+}
+
 // Handles requests to alter the leaderboard
 func handleScoreChange(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	if authenticated && (role == "admin" || role == "super") {
-		requestBytes, readErr := io.ReadAll(request.Body)
-		if readErr != nil {
-			greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
-		}
+	requestBytes, readErr := io.ReadAll(request.Body)
+	if readErr != nil {
+		greenlogger.LogErrorf(readErr, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
+	}
 
-		var requestStruct userDB.ModRequest
+	var requestStruct userDB.ModRequest
 
-		unmarshalErr := json.Unmarshal(requestBytes, &requestStruct)
-		if unmarshalErr != nil {
-			greenlogger.LogErrorf(unmarshalErr, "Error unmarshalling %v", requestBytes)
-		}
+	unmarshalErr := json.Unmarshal(requestBytes, &requestStruct)
+	if unmarshalErr != nil {
+		greenlogger.LogErrorf(unmarshalErr, "Error unmarshalling %v", requestBytes)
+		httpError(writer, request, http.StatusBadRequest, "Could not parse the score change: %v", unmarshalErr)
+		return
+	}
 
-		userDB.ModifyUserScore(requestStruct.Name, requestStruct.Mod, requestStruct.By)
+	userDB.ModifyUserScore(requestStruct.Name, requestStruct.Mod, requestStruct.By)
 
-		httpResponsef(writer, "Problem writing http response for score change request", "Successfully modified score of %s", requestStruct.Name)
-	}
+	httpResponsef(writer, "Problem writing http response for score change request", "Successfully modified score of %s", requestStruct.Name)
 }
 
 // A wrapper for http handler functions to allow them to perform with
-// CORS (Cross-Origin Resource sharing), which is typically highly restricted by modern
-// browsers, especially chromium-based ones.
-// The okCode parameter exists because some requests require a 200 response even before acting. This is honestly just trial and error to determine.
-func handleWithCORS(handler http.HandlerFunc, okCode bool) http.HandlerFunc {
+// CORS (Cross-Origin Resource sharing). Permissions are enforced before this runs; see permissions.go.
+func handleWithCORS(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", constants.CachedConfigs.FrontendDomain)
-		w.Header().Set("Access-Control-Allow-Methods", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "*, Certificate")
-		w.Header().Set("Access-Control-Expose-Headers", "*, Certificate")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		if okCode {
-			w.WriteHeader(200)
-		}
+		setCORSHeaders(w)
 		handler(w, r)
 	}
 }
 
 // Serves the entire list of users
 func serveUsersRequest(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	if authenticated && (role == "admin" || role == "super") {
-		users := userDB.GetAllUsers()
-		encodeErr := json.NewEncoder(writer).Encode(userDB.GetAllUsers())
-		if encodeErr != nil {
-			greenlogger.LogErrorf(encodeErr, "Problem encoding %v", users)
-		}
+	users := userDB.GetAllUsers()
+	encodeErr := json.NewEncoder(writer).Encode(userDB.GetAllUsers())
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", users)
 	}
 }
 
 // Handles the request for the scouters of a specific match
 func serveMatchScouter(writer http.ResponseWriter, request *http.Request) {
-
 	var match lib.MatchInfoRequest
 	decodeErr := json.NewDecoder(request.Body).Decode(&match)
 	if decodeErr != nil {
 		greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not parse the match: %v", decodeErr)
+		return
 	}
 
 	httpResponsef(writer, "Problem serving scouter for a given match", "%s", lib.GetNameFromWritten(match))
@@ -534,13 +851,14 @@ func serveMatchScouter(writer http.ResponseWriter, request *http.Request) {
 func serveUserInfo(writer http.ResponseWriter, request *http.Request) {
 	info := userDB.GetUserInfo(request.Header.Get("username"))
 
-	if request.Header.Get("uuid") != "" && userDB.UUIDToUser(request.Header.Get("uuid")) == request.Header.Get("username") {
+	// Users viewing their own info have now been notified of their accolades
+	if session, authenticated := sessionFromRequest(request); authenticated && session.Username == request.Header.Get("username") {
 		var accoladesNotified []userDB.AccoladeData
 		for _, accolade := range info.Accolades {
 			accoladesNotified = append(accoladesNotified, userDB.AccoladeData{Accolade: accolade.Accolade, Notified: true})
 		}
 
-		userDB.SetAccolades(request.Header.Get("uuid"), accoladesNotified)
+		userDB.SetAccolades(session.UUID, accoladesNotified)
 	}
 
 	encodeErr := json.NewEncoder(writer).Encode(info)
@@ -562,72 +880,58 @@ func serveUserInfoForAdmins(writer http.ResponseWriter, request *http.Request) {
 
 // Handles requests to alter display names
 func setDisplayName(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	uuid, _ := userDB.GetUUID(request.Header.Get("username"), true)
+	userDB.SetDisplayName(request.Header.Get("username"), request.Header.Get("displayName"))
 
-	isUser := uuid == request.Header.Get("uuid")
-
-	if (authenticated && (role == "admin" || role == "super")) || isUser {
-		userDB.SetDisplayName(request.Header.Get("username"), request.Header.Get("displayName"))
-
-		info := userDB.GetUserInfo(request.Header.Get("username"))
-		writer.WriteHeader(200)
-		encodeErr := json.NewEncoder(writer).Encode(info)
-		if encodeErr != nil {
-			greenlogger.LogErrorf(encodeErr, "Problem encoding %v", info)
-		}
+	info := userDB.GetUserInfo(request.Header.Get("username"))
+	encodeErr := json.NewEncoder(writer).Encode(info)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", info)
 	}
 }
 
 // Handles requests to alter profile pictures
 func setPfp(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	uuid, _ := userDB.GetUUID(request.Header.Get("username"), true)
-
-	isUser := uuid == request.Header.Get("uuid")
+	requestBytes, err := io.ReadAll(request.Body)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem reading %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not read the request body")
+		return
+	}
 
-	if (authenticated && (role == "admin" || role == "super")) || isUser {
-		userDB.SetPfp(request.Header.Get("username"), request.Header.Get("Filename"))
-		requestBytes, err := io.ReadAll(request.Body)
-		if err != nil {
-			greenlogger.LogErrorf(err, "Problem reading %v", request.Body)
-		}
-		if pfp.WritePfp(requestBytes, request.Header.Get("Filename")) {
-			writer.WriteHeader(200)
-		} else {
-			writer.WriteHeader(500)
-		}
+	if !pfp.WritePfp(requestBytes, request.Header.Get("Filename")) {
+		httpError(writer, request, http.StatusInternalServerError, "Could not save the profile picture")
+		return
 	}
+
+	userDB.SetPfp(request.Header.Get("username"), request.Header.Get("Filename"))
+	writer.WriteHeader(200)
 }
 
 // Handles additions of accolades from the frontend
 func handleFrontendAdditions(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	uuid, _ := userDB.GetUUID(request.Header.Get("username"), true)
+	session, _ := sessionFromRequest(request)
+	isAdmin := isAdminRole(session.Role)
 
-	isUser := uuid == request.Header.Get("uuid")
-
-	if (authenticated && (role == "admin" || role == "super")) || isUser {
-		var Additions userDB.FrontendAdds
-		err := json.NewDecoder(request.Body).Decode(&Additions)
-		if err != nil {
-			greenlogger.LogErrorf(err, "Problem decoding %v", request.Body)
-		}
+	var Additions userDB.FrontendAdds
+	err := json.NewDecoder(request.Body).Decode(&Additions)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem decoding %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not parse the additions: %v", err)
+		return
+	}
 
-		userDB.ConsumeFrontendAdditions(Additions, true)
+	if !isAdmin { // Users can only add to themselves
+		Additions.UUID = session.UUID
 	}
+
+	userDB.ConsumeFrontendAdditions(Additions, isAdmin)
 }
 
 // Handles requests to alter leaderboard colors
 func handleColorChange(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
 	uuid, _ := userDB.GetUUID(request.Header.Get("username"), true)
 
-	isUser := uuid == request.Header.Get("uuid")
-
-	if (authenticated && (role == "admin" || role == "super")) || isUser {
-		userDB.SetColor(uuid, parseColor(request.Header.Get("color")))
-	}
+	userDB.SetColor(uuid, parseColor(request.Header.Get("color")))
 }
 
 // Conversion method from the string header of the color to the const value index
@@ -643,50 +947,48 @@ func parseColor(colStr string) userDB.LBColor {
 
 // Handles requests to add badges
 func addBadge(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	if authenticated && (role == "admin" || role == "super") {
-		usernameToAdd := request.Header.Get("username")
-		uuid, _ := userDB.GetUUID(usernameToAdd, true)
-
-		var badge userDB.Badge
-		decodeErr := json.NewDecoder(request.Body).Decode(&badge)
-		if decodeErr != nil {
-			greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
-		}
-
-		userDB.AddBadge(uuid, badge)
+	usernameToAdd := request.Header.Get("username")
+	uuid, _ := userDB.GetUUID(usernameToAdd, true)
 
-		httpResponsef(writer, "Problem writing http response for badge addition request", "Successfully added %s to %s", badge.ID, usernameToAdd)
+	var badge userDB.Badge
+	decodeErr := json.NewDecoder(request.Body).Decode(&badge)
+	if decodeErr != nil {
+		greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not parse the badge: %v", decodeErr)
+		return
 	}
+
+	userDB.AddBadge(uuid, badge)
+
+	httpResponsef(writer, "Problem writing http response for badge addition request", "Successfully added %s to %s", badge.ID, usernameToAdd)
 }
 
 // Handles requests to add badges
 func setBadges(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	if authenticated && (role == "admin" || role == "super") {
-		usernameToAdd := request.Header.Get("username")
-		uuid, _ := userDB.GetUUID(usernameToAdd, true)
-
-		var badges []userDB.Badge
-		decodeErr := json.NewDecoder(request.Body).Decode(&badges)
-		if decodeErr != nil {
-			greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
-		}
-
-		userDB.SetBadges(uuid, badges)
+	usernameToAdd := request.Header.Get("username")
+	uuid, _ := userDB.GetUUID(usernameToAdd, true)
 
-		httpResponsef(writer, "Problem writing http response for badge addition request", "Successfully set badges of %s to %v", usernameToAdd, badges)
+	var badges []userDB.Badge
+	decodeErr := json.NewDecoder(request.Body).Decode(&badges)
+	if decodeErr != nil {
+		greenlogger.LogErrorf(decodeErr, "Problem decoding %v", request.Body)
+		httpError(writer, request, http.StatusBadRequest, "Could not parse the badges: %v", decodeErr)
+		return
 	}
+
+	userDB.SetBadges(uuid, badges)
+
+	httpResponsef(writer, "Problem writing http response for badge addition request", "Successfully set badges of %s to %v", usernameToAdd, badges)
 }
 
 // A simple check for if the certificate is valid
 func handleCertificateVerification(writer http.ResponseWriter, request *http.Request) {
-	_, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
+	_, authenticated := sessionFromRequest(request)
 
 	if authenticated {
 		writer.WriteHeader(200)
 	} else {
-		writer.WriteHeader(500)
+		httpError(writer, request, http.StatusUnauthorized, "The certificate is invalid or expired")
 	}
 }
 
@@ -722,7 +1024,8 @@ func handleEventsRequest(writer http.ResponseWriter, request *http.Request) {
 func handleGalleryRequest(writer http.ResponseWriter, request *http.Request) {
 	ind, err := strconv.ParseInt(request.URL.Query().Get("index"), 10, 64)
 	if err != nil {
-		greenlogger.LogMessagef("Problem parsing %v as int", request.URL.Query().Get("index"))
+		httpError(writer, request, http.StatusBadRequest, "%v isn't a valid gallery index", request.URL.Query().Get("index"))
+		return
 	}
 
 	http.ServeFile(writer, request, gallery.GetImage(int(ind)))
@@ -731,10 +1034,7 @@ func handleGalleryRequest(writer http.ResponseWriter, request *http.Request) {
 
 // Serves the spreadsheet link
 func serveSpreadsheet(writer http.ResponseWriter, request *http.Request) {
-	role, authenticated := userDB.VerifyCertificate(request.Header.Get("Certificate"))
-	if authenticated && (role == "1816" || role == "admin" || role == "super") {
-		httpResponsef(writer, "Error serving spreadsheet", "https://docs.google.com/spreadsheets/d/"+constants.CachedConfigs.SpreadSheetID)
-	}
+	httpResponsef(writer, "Error serving spreadsheet", "https://docs.google.com/spreadsheets/d/"+constants.CachedConfigs.SpreadSheetID)
 }
 
 // A simple wrapper for http responses that handles formatting and errors
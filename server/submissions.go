@@ -0,0 +1,184 @@
+package server
+
+// Making submissions safe to retry, using an ID generated by the client
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// The kinds of scouting data that can be submitted
+const (
+	matchSubmission = "match" // lib.TeamData, posted to /dataEntry
+	pitSubmission   = "pit"   // lib.PitScoutingData, posted to /pitScout
+)
+
+// The header a client puts the ID of a submission in. Retrying with the same ID gets the original response instead of storing it again.
+const submissionIDHeader = "Submission-ID"
+
+// The header set on responses repeated from an earlier submission with the same ID
+const duplicateSubmissionHeader = "Duplicate-Submission"
+
+// The longest submission ID accepted
+const maxSubmissionIDLength = 64
+
+// An error storing a submission, along with the status code it should be responded to with
+type submissionError struct {
+	Status  int    // The HTTP status code
+	Message string // What went wrong
+}
+
+// Returns the message of a submission error
+func (err *submissionError) Error() string {
+	return err.Message
+}
+
+// Parses one submission of a kind and saves it to In for the ingestion queue, returning the name it was saved as.
+// Submissions that can't be parsed are saved to Mangled instead. Match scouting is checked against the schedule first.
+func storeSubmission(kind string, requestBytes []byte) (string, error) {
+	var submission any
+	var fileName string
+	var unmarshalErr error
+	description := "scouting data"
+
+	switch kind {
+	case matchSubmission:
+		var team lib.TeamData
+		unmarshalErr = json.Unmarshal(requestBytes, &team)
+		submission, fileName = team, matchFileName(team)
+	case pitSubmission:
+		var pit lib.PitScoutingData
+		unmarshalErr = json.Unmarshal(requestBytes, &pit)
+		submission, fileName = pit, pitFileName(pit)
+		description = "pit scouting data"
+	default:
+		return "", &submissionError{http.StatusBadRequest, fmt.Sprintf("%v isn't a kind of submission", kind)}
+	}
+
+	if unmarshalErr != nil { // Handle mangling
+		greenlogger.LogErrorf(unmarshalErr, "MANGLED: %v", requestBytes)
+		saveMangled(requestBytes, unmarshalErr)
+
+		return "", &submissionError{http.StatusBadRequest, fmt.Sprintf("Could not parse the %v: %v", description, unmarshalErr)}
+	}
+
+	var issue *matchDB.ValidationIssue
+	if team, isMatch := submission.(lib.TeamData); isMatch {
+		var validateErr error
+		if team, issue, validateErr = validateTeamData(team); validateErr != nil {
+			return "", validateErr
+		}
+		submission = team
+	}
+
+	if submitErr := submitFile(fileName, submission); submitErr != nil {
+		return "", &submissionError{http.StatusInternalServerError, "Could not save the " + description}
+	}
+
+	if issue != nil {
+		issue.File = fileName
+		matchDB.RecordValidationIssue(*issue)
+	}
+
+	return fileName, nil
+}
+
+// Responds to a request with the error from storing its submission
+func serveSubmissionError(writer http.ResponseWriter, request *http.Request, storeErr error) {
+	var subErr *submissionError
+	if errors.As(storeErr, &subErr) {
+		httpError(writer, request, subErr.Status, "%s", subErr.Message)
+	} else {
+		httpError(writer, request, http.StatusInternalServerError, "%s", storeErr)
+	}
+}
+
+// A response writer that keeps a copy of the status and body written through it
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// Records the status code before writing it
+func (writer *recordingWriter) WriteHeader(status int) {
+	if writer.status == 0 {
+		writer.status = status
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+// Records the body before writing it
+func (writer *recordingWriter) Write(data []byte) (int, error) {
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+	writer.body.Write(data)
+	return writer.ResponseWriter.Write(data)
+}
+
+// A wrapper making a submission endpoint idempotent. Requests with a Submission-ID header are handled once; retries of them
+// get the original response back with a Duplicate-Submission header. Requests without one are handled as normal.
+// Server errors (5xx) aren't remembered, so a retry after one is handled again. IDs belong to the user who submitted them.
+func idempotent(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		id := request.Header.Get(submissionIDHeader)
+		if id == "" {
+			handler(writer, request)
+			return
+		}
+
+		if len(id) > maxSubmissionIDLength {
+			httpError(writer, request, http.StatusBadRequest, "Submission IDs can't be longer than %v characters", maxSubmissionIDLength)
+			return
+		}
+
+		session, _ := sessionFromRequest(request)
+		if existing, reserved := matchDB.ReserveSubmission(session.Username, id, request.URL.Path); !reserved {
+			serveDuplicateSubmission(writer, request, existing)
+			return
+		}
+
+		recorder := &recordingWriter{ResponseWriter: writer}
+		handler(recorder, request)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		if recorder.status >= 500 {
+			matchDB.ReleaseSubmission(session.Username, id)
+		} else {
+			matchDB.CompleteSubmission(session.Username, id, recorder.status, recorder.Header().Get("Content-Type"), recorder.body.String())
+		}
+	}
+}
+
+// Repeats the response to an earlier submission with the same ID
+func serveDuplicateSubmission(writer http.ResponseWriter, request *http.Request, existing matchDB.Submission) {
+	if existing.Endpoint != request.URL.Path {
+		httpError(writer, request, http.StatusConflict, "Submission %v was already used for %v", existing.ID, existing.Endpoint)
+		return
+	}
+
+	if existing.Pending {
+		writer.Header().Set("Retry-After", strconv.Itoa(queueFullRetrySeconds))
+		httpError(writer, request, http.StatusConflict, "Submission %v is still being handled. Please try again shortly.", existing.ID)
+		return
+	}
+
+	writer.Header().Set(duplicateSubmissionHeader, "true")
+	if existing.ContentType != "" {
+		writer.Header().Set("Content-Type", existing.ContentType)
+	}
+	writer.WriteHeader(existing.Status)
+
+	httpResponsef(writer, "Problem writing http response to duplicate submission", "%s", existing.Response)
+}
@@ -0,0 +1,145 @@
+package server
+
+// Serving shift swaps between scouters
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/schedule"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// A request naming a swap, and whether an admin approves it
+type swapRequest struct {
+	Swap    int64 // The identifier of the swap
+	Approve bool  // If the swap is approved, when an admin reviews it
+}
+
+// Writes a swap, or the error changing it, as the response
+func writeSwapResponse(writer http.ResponseWriter, request *http.Request, swap schedule.Swap, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, schedule.ErrSwapNotFound):
+		httpError(writer, request, http.StatusNotFound, "%v", err)
+		return
+	case errors.Is(err, schedule.ErrNotSwapParty):
+		httpError(writer, request, http.StatusForbidden, "%v", err)
+		return
+	case errors.Is(err, schedule.ErrSwapClosed), errors.Is(err, schedule.ErrRangeNotOwned), errors.Is(err, schedule.ErrScheduleConflict):
+		httpError(writer, request, http.StatusConflict, "%v", err)
+		return
+	case errors.Is(err, schedule.ErrInvalidRange), errors.Is(err, schedule.ErrSelfSwap),
+		errors.Is(err, schedule.ErrTradeNeedsTarget), errors.Is(err, schedule.ErrUnknownScouter):
+		httpError(writer, request, http.StatusBadRequest, "%v", err)
+		return
+	default:
+		httpError(writer, request, http.StatusInternalServerError, "There was a problem updating the swap")
+		return
+	}
+
+	encodeErr := json.NewEncoder(writer).Encode(swap)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", swap)
+	}
+}
+
+// Serves the swaps of the current event, newest first. Admins get every swap; everyone else gets the ones they're part of
+// and the pending ones anyone can accept.
+func serveSwaps(writer http.ResponseWriter, request *http.Request) {
+	session, _ := sessionFromRequest(request)
+
+	username := session.Username
+	if isAdminRole(session.Role) {
+		username = ""
+	}
+
+	swaps := schedule.GetSwaps(lib.GetCurrentEvent(), username)
+
+	encodeErr := json.NewEncoder(writer).Encode(swaps)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", swaps)
+	}
+}
+
+// Handles a scouter proposing to give away or trade a range of their schedule
+func handleSwapProposal(writer http.ResponseWriter, request *http.Request) {
+	var proposal schedule.SwapProposal
+	if !decodeJSONBody(writer, request, &proposal) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	swap, err := schedule.ProposeSwap(proposal, session.Username)
+	writeSwapResponse(writer, request, swap, err)
+}
+
+// Handles a scouter accepting a swap
+func handleSwapAcceptance(writer http.ResponseWriter, request *http.Request) {
+	var swapRequest swapRequest
+	if !decodeJSONBody(writer, request, &swapRequest) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	swap, err := schedule.AcceptSwap(swapRequest.Swap, session.Username)
+	writeSwapResponse(writer, request, swap, err)
+}
+
+// Handles a scouter turning down a swap offered to them
+func handleSwapDecline(writer http.ResponseWriter, request *http.Request) {
+	var swapRequest swapRequest
+	if !decodeJSONBody(writer, request, &swapRequest) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	swap, err := schedule.DeclineSwap(swapRequest.Swap, session.Username)
+	writeSwapResponse(writer, request, swap, err)
+}
+
+// Handles a scouter withdrawing a swap they proposed, or an admin withdrawing any swap
+func handleSwapCancellation(writer http.ResponseWriter, request *http.Request) {
+	var swapRequest swapRequest
+	if !decodeJSONBody(writer, request, &swapRequest) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	swap, err := schedule.CancelSwap(swapRequest.Swap, session.Username, isAdminRole(session.Role))
+	writeSwapResponse(writer, request, swap, err)
+}
+
+// Handles an admin approving or rejecting an accepted swap
+func handleSwapReview(writer http.ResponseWriter, request *http.Request) {
+	var swapRequest swapRequest
+	if !decodeJSONBody(writer, request, &swapRequest) {
+		return
+	}
+
+	session, _ := sessionFromRequest(request)
+	swap, err := schedule.ReviewSwap(swapRequest.Swap, session.Username, swapRequest.Approve)
+	writeSwapResponse(writer, request, swap, err)
+}
+
+// Serves the audit trail of the current event's swaps, newest first. The swap header limits it to one swap.
+func serveSwapAudit(writer http.ResponseWriter, request *http.Request) {
+	var id int64
+	if header := request.Header.Get("swap"); header != "" {
+		parsed, parseErr := strconv.ParseInt(header, 10, 64)
+		if parseErr != nil {
+			httpError(writer, request, http.StatusBadRequest, "Could not parse the swap %v: %v", header, parseErr)
+			return
+		}
+		id = parsed
+	}
+
+	audit := schedule.GetSwapAudit(lib.GetCurrentEvent(), id)
+
+	encodeErr := json.NewEncoder(writer).Encode(audit)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", audit)
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+// Serving aggregate statistics, ratings and match predictions for the current event
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Serves the aggregate statistics of every team scouted at the current event, ordered by team number.
+// The team header limits them to one team, and the trend header sets how many recent matches trends cover.
+func serveTeamStats(writer http.ResponseWriter, request *http.Request) {
+	trendMatches := lib.DefaultTrendMatches
+	if trendHeader := request.Header.Get("trend"); trendHeader != "" {
+		parsed, parseErr := strconv.Atoi(trendHeader)
+		if parseErr != nil || parsed < 1 {
+			httpError(writer, request, http.StatusBadRequest, "Could not parse the trend %v: it must be a positive number of matches", trendHeader)
+			return
+		}
+		trendMatches = parsed
+	}
+
+	var entries []matchDB.StoredTeamData
+	if teamHeader := request.Header.Get("team"); teamHeader != "" {
+		team, parseErr := strconv.Atoi(teamHeader)
+		if parseErr != nil {
+			httpError(writer, request, http.StatusBadRequest, "Could not parse the team %v: %v", teamHeader, parseErr)
+			return
+		}
+		entries = matchDB.GetTeamEntries(lib.GetCurrentEvent(), team)
+	} else {
+		entries = matchDB.GetEventTeamData(lib.GetCurrentEvent())
+	}
+
+	var teams []lib.TeamData
+	for _, entry := range entries {
+		teams = append(teams, entry.Data)
+	}
+
+	results := lib.CompileTeamStats(teams, trendMatches)
+
+	encodeErr := json.NewEncoder(writer).Encode(results)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", results)
+	}
+}
+
+// Serves the OPR, DPR, CCWM and component OPRs of every team at the current event, best OPR first, as last computed
+func serveTeamRatings(writer http.ResponseWriter, request *http.Request) {
+	ratings := matchDB.GetTeamRatings(lib.GetCurrentEvent())
+
+	encodeErr := json.NewEncoder(writer).Encode(ratings)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", ratings)
+	}
+}
+
+// Every stored prediction of an event, along with how accurate the checked ones were
+type predictionsResponse struct {
+	Accuracy    lib.PredictionAccuracy // How accurate the predictions of played matches were
+	Predictions []lib.Prediction       // Every prediction, ordered by match
+}
+
+// Serves the latest prediction of every match at the current event, including how played ones turned out
+func servePredictions(writer http.ResponseWriter, request *http.Request) {
+	predictions := matchDB.GetPredictions(lib.GetCurrentEvent())
+	response := predictionsResponse{Accuracy: lib.SummarizePredictions(predictions), Predictions: predictions}
+
+	encodeErr := json.NewEncoder(writer).Encode(response)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", response)
+	}
+}
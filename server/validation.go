@@ -0,0 +1,71 @@
+package server
+
+// Checking submitted scouting data against the event schedule, and the admin report of what didn't match
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Checks match scouting data against the schedule and handles it according to the configured validation mode.
+// Returns the data to save, with its warnings set, the issue to record once it is saved, if any, and an error if it was rejected.
+func validateTeamData(team lib.TeamData) (lib.TeamData, *matchDB.ValidationIssue, error) {
+	// Warnings are only ever set by the server
+	team.Warnings = nil
+
+	mode := constants.CachedConfigs.ValidationConfigs.Mode
+	if mode == lib.ValidationOff {
+		return team, nil, nil
+	}
+
+	validation := lib.ValidateTeamData(team)
+	if len(validation.Warnings) == 0 {
+		return team, nil, nil
+	}
+
+	issue := &matchDB.ValidationIssue{
+		Event:         lib.GetCurrentEvent(),
+		Match:         int(team.Match.Number),
+		IsBlue:        team.DriverStation.IsBlue,
+		DSNumber:      team.DriverStation.Number,
+		Team:          int(team.TeamNumber),
+		ScheduledTeam: int(validation.ScheduledTeam),
+		Scouter:       team.Scouter,
+		Action:        mode,
+		Warnings:      validation.Warnings,
+		RecordedAt:    time.Now(),
+	}
+
+	switch mode {
+	case lib.ValidationReject:
+		matchDB.RecordValidationIssue(*issue)
+		greenlogger.LogMessagef("Rejected match %v data from %v: %v", team.Match.Number, team.Scouter, strings.Join(validation.Warnings, ", "))
+		return team, nil, &submissionError{http.StatusUnprocessableEntity, "The scouting data doesn't match the schedule: " + strings.Join(validation.Warnings, ", ")}
+
+	case lib.ValidationCorrect:
+		if validation.ScheduledTeam != 0 && validation.ScheduledTeam != team.TeamNumber {
+			issue.Warnings = append(issue.Warnings, fmt.Sprintf("Corrected team %v to %v", team.TeamNumber, validation.ScheduledTeam))
+			team.TeamNumber = validation.ScheduledTeam
+		}
+	}
+
+	team.Warnings = issue.Warnings
+	return team, issue, nil
+}
+
+// Serves every submission to the current event that didn't match the schedule, most recent first
+func serveValidationReport(writer http.ResponseWriter, request *http.Request) {
+	issues := matchDB.GetValidationIssues(lib.GetCurrentEvent())
+
+	encodeErr := json.NewEncoder(writer).Encode(issues)
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", issues)
+	}
+}
@@ -7,9 +7,12 @@ import (
 	filemanager "GreenScoutBackend/fileManager"
 	greenlogger "GreenScoutBackend/greenLogger"
 	"GreenScoutBackend/lib"
+	"GreenScoutBackend/matchDB"
+	"GreenScoutBackend/pickList"
 	"GreenScoutBackend/rsaUtil"
 	"GreenScoutBackend/schedule"
 	"GreenScoutBackend/sheet"
+	"GreenScoutBackend/tba"
 	"GreenScoutBackend/userDB"
 	"crypto/rand"
 	"crypto/rsa"
@@ -40,6 +43,11 @@ func TotalSetup(publicHosting bool) {
 	configs := retrieveGeneralConfigs()
 	greenlogger.LogMessagef("General configs retrieved: %v", configs)
 
+	// Game definition
+	greenlogger.LogMessage("Retreiving game definition...")
+	constants.CachedGameConfig = ensureGameConfig()
+	greenlogger.LogMessagef("Game definition for %v %v retrieved", constants.CachedGameConfig.Season, constants.CachedGameConfig.Name)
+
 	workingDir, err := os.Getwd()
 	if err != nil {
 		panic(err)
@@ -116,10 +124,15 @@ func TotalSetup(publicHosting bool) {
 	ensureScoutDB(configs)
 	greenlogger.LogMessage("Schedule database confirmed to exist")
 
-	// TBA API package
-	greenlogger.LogMessage("Ensuring TBA API python package...")
-	downloadAPIPackage()
-	greenlogger.LogMessage("API package downloaded")
+	// Matches.db
+	greenlogger.LogMessage("Ensuring scouting data database...")
+	ensureMatchDB(configs)
+	greenlogger.LogMessage("Scouting data database confirmed to exist")
+
+	// Picklists.db
+	greenlogger.LogMessage("Ensuring pick list database...")
+	ensurePickListDB(configs)
+	greenlogger.LogMessage("Pick list database confirmed to exist")
 
 	// Network
 	if publicHosting {
@@ -137,11 +150,6 @@ func TotalSetup(publicHosting bool) {
 		greenlogger.LogMessage("TEST MODE: Skipping ip and domain name ensuring...")
 	}
 
-	// Python
-	greenlogger.LogMessage("Ensuring python driver...")
-	configs.PythonDriver = ensurePythonDriver(configs.PythonDriver)
-	greenlogger.LogMessagef("Python driver validated: %v", configs.PythonDriver)
-
 	// TBA API key
 	greenlogger.LogMessage("Ensuring TBA API key...")
 	configs.TBAKey = ensureTBAKey(configs)
@@ -198,6 +206,95 @@ func TotalSetup(publicHosting bool) {
 		greenlogger.ShutdownLogFile()
 	}
 
+	// Accounts
+	if !configs.AccountConfigs.Configured {
+		configs.AccountConfigs.Configured = true
+		configs.AccountConfigs.AllowJoinCode = true // Existing scouters only have the shared passwords until accounts are made for them
+	}
+	if configs.AccountConfigs.SessionIdleHours <= 0 {
+		configs.AccountConfigs.SessionIdleHours = userDB.DefaultSessionIdleHours
+	}
+	if configs.AccountConfigs.SessionMaxDays <= 0 {
+		configs.AccountConfigs.SessionMaxDays = userDB.DefaultSessionMaxDays
+	}
+
+	// Ingestion
+	if !configs.IngestConfigs.Configured {
+		configs.IngestConfigs.Configured = true
+	}
+	if configs.IngestConfigs.Workers <= 0 {
+		configs.IngestConfigs.Workers = constants.DefaultIngestWorkers
+	}
+	if configs.IngestConfigs.QueueSize <= 0 {
+		configs.IngestConfigs.QueueSize = constants.DefaultIngestQueueSize
+	}
+	if configs.IngestConfigs.RescanSeconds <= 0 {
+		configs.IngestConfigs.RescanSeconds = constants.DefaultIngestRescanSeconds
+	}
+	if configs.IngestConfigs.MaxAttempts <= 0 {
+		configs.IngestConfigs.MaxAttempts = constants.DefaultIngestMaxAttempts
+	}
+	if configs.IngestConfigs.RetryBaseSeconds <= 0 {
+		configs.IngestConfigs.RetryBaseSeconds = constants.DefaultIngestRetryBaseSeconds
+	}
+	if configs.IngestConfigs.RetryMaxSeconds <= 0 {
+		configs.IngestConfigs.RetryMaxSeconds = constants.DefaultIngestRetryMaxSeconds
+	}
+
+	// Validation
+	if !configs.ValidationConfigs.Configured {
+		configs.ValidationConfigs.Configured = true
+	}
+	if !lib.IsValidationMode(configs.ValidationConfigs.Mode) {
+		if configs.ValidationConfigs.Mode != "" {
+			greenlogger.LogMessagef("%v isn't a validation mode, using %v", configs.ValidationConfigs.Mode, constants.DefaultValidationMode)
+		}
+		configs.ValidationConfigs.Mode = constants.DefaultValidationMode
+	}
+
+	// Accuracy
+	if !configs.AccuracyConfigs.Configured {
+		configs.AccuracyConfigs.Configured = true
+	}
+	if configs.AccuracyConfigs.Schedule == "" {
+		configs.AccuracyConfigs.Schedule = constants.DefaultAccuracySchedule
+	}
+	if configs.AccuracyConfigs.AccurateWithin <= 0 {
+		configs.AccuracyConfigs.AccurateWithin = constants.DefaultAccuracyAccurateWithin
+	}
+	if configs.AccuracyConfigs.LeaderboardPoints < 0 {
+		configs.AccuracyConfigs.LeaderboardPoints = 0
+	}
+
+	if !configs.RatingConfigs.Configured {
+		configs.RatingConfigs.Configured = true
+	}
+	if configs.RatingConfigs.Schedule == "" {
+		configs.RatingConfigs.Schedule = constants.DefaultRatingSchedule
+	}
+
+	if !configs.PredictionConfigs.Configured {
+		configs.PredictionConfigs.Configured = true
+	}
+	if configs.PredictionConfigs.Schedule == "" {
+		configs.PredictionConfigs.Schedule = constants.DefaultPredictionSchedule
+	}
+	if configs.PredictionConfigs.Simulations <= 0 {
+		configs.PredictionConfigs.Simulations = constants.DefaultPredictionSimulations
+	}
+
+	if !configs.SwapConfigs.Configured {
+		configs.SwapConfigs.Configured = true
+	}
+
+	if !configs.MultiScoutConfigs.Configured {
+		configs.MultiScoutConfigs.Configured = true
+		configs.MultiScoutConfigs.MinCycleConfidence = constants.DefaultMinCycleConfidence
+	}
+	if configs.MultiScoutConfigs.MinCycleConfidence < 0 || configs.MultiScoutConfigs.MinCycleConfidence > 100 {
+		configs.MultiScoutConfigs.MinCycleConfidence = constants.DefaultMinCycleConfidence
+	}
+
 	/// writing
 
 	configFile, openErr := filemanager.OpenWithPermissions(constants.ConfigFilePath)
@@ -243,46 +340,52 @@ func retrieveGeneralConfigs() constants.GeneralConfigs {
 	return genConfigs
 }
 
-// Runs the python ensurance routine and returns the driver eventually
-func ensurePythonDriver(existingDriver string) string {
-	if validatePythonDriver(existingDriver) {
-		return existingDriver
-	}
+// Gets the game definition from yaml. If there isn't one, writes the default game definition and returns it.
+func ensureGameConfig() constants.GameConfigs {
+	configBytes, readErr := os.ReadFile(constants.GameConfigFilePath)
 
-	return recursivePythonValidation(true)
-}
+	if errors.Is(readErr, os.ErrNotExist) {
+		greenlogger.LogMessagef("No game definition found, writing the default (%v %v) to %v", constants.DefaultGameConfig.Season, constants.DefaultGameConfig.Name, constants.GameConfigFilePath)
 
-// Validates for the python driver. If for some reason the entered in driver doesn't validate, it will recurse and not return until it has a valid one
-func recursivePythonValidation(firstRun bool) string {
-	if firstRun {
-		greenlogger.LogMessage("Enter the python driver installed on this machine (what you type to run a .py file from the command line): ")
-	}
+		greenlogger.HandleMkdirAll(filepath.Dir(constants.GameConfigFilePath))
+		configFile, openErr := filemanager.OpenWithPermissions(constants.GameConfigFilePath)
+		if openErr != nil {
+			greenlogger.LogErrorf(openErr, "Problem creating %v", constants.GameConfigFilePath)
+			return constants.DefaultGameConfig
+		}
+		defer configFile.Close()
 
-	var driver string
-	_, scanErr := fmt.Scanln(&driver)
+		encodeErr := yaml.NewEncoder(configFile).Encode(&constants.DefaultGameConfig)
+		if encodeErr != nil {
+			greenlogger.LogErrorf(encodeErr, "Problem encoding %v", constants.DefaultGameConfig)
+		}
 
-	if scanErr != nil {
-		greenlogger.LogError(scanErr, "Problem scanning python driver input")
+		return constants.DefaultGameConfig
+	} else if readErr != nil {
+		greenlogger.FatalError(readErr, "Problem reading "+constants.GameConfigFilePath)
 	}
 
-	if validatePythonDriver(driver) {
-		return driver
-	} else {
-		greenlogger.LogMessagef("Sorry, %v doesn't appear to be a valid python driver. Please ensure you didn't make a typo!", driver)
-		return recursivePythonValidation(false)
+	var gameConfigs constants.GameConfigs
+	unmarshalErr := yaml.Unmarshal(configBytes, &gameConfigs)
+	if unmarshalErr != nil {
+		greenlogger.FatalError(unmarshalErr, "Problem unmarshalling "+constants.GameConfigFilePath)
 	}
-}
 
-// Checks if the python driver is valid by checking for its version
-func validatePythonDriver(driver string) bool {
-	runnable := exec.Command(driver, "--version")
+	if len(gameConfigs.Columns) == 0 {
+		greenlogger.FatalLogMessage(constants.GameConfigFilePath + " doesn't define any columns!")
+	}
 
-	out, execErr := runnable.Output()
-	if execErr != nil && !strings.Contains(execErr.Error(), "no command") {
-		greenlogger.LogErrorf(execErr, "Problem executing %v %v", driver, "--version")
+	// Game definitions written before accuracy rating existed can still use the default's comparisons
+	if len(gameConfigs.Accuracy) == 0 && gameConfigs.Season == constants.DefaultGameConfig.Season {
+		gameConfigs.Accuracy = constants.DefaultGameConfig.Accuracy
 	}
 
-	return len(out) > 0 && strings.Contains(string(out), "Python")
+	// Likewise for component OPRs
+	if len(gameConfigs.RatingFields) == 0 && gameConfigs.Season == constants.DefaultGameConfig.Season {
+		gameConfigs.RatingFields = constants.DefaultGameConfig.RatingFields
+	}
+
+	return gameConfigs
 }
 
 // Checks if the sqlite3 driver exists on the machine and returns sqlite3. If not, it will fatal the program.
@@ -312,27 +415,24 @@ func validateSqliteDriver() bool {
 	return re.FindString(string(out)) != ""
 }
 
-// Runs getStatus.py with the entered in TBA key, returning if it was successful.
+// Requests TBA's status with the entered in TBA key, returning if it was accepted.
 // This is unreliable because TBA is very weird at times. It will sometimes let an incorrect api key authenticate, so please ensure you've got the right one.
-func validateTBAKey(configs constants.GeneralConfigs, key string) bool {
+func validateTBAKey(key string) bool {
 	if key == "" {
 		return false
 	}
 
-	runnable := exec.Command(configs.PythonDriver, "getStatus.py", key)
-
-	out, execErr := runnable.Output()
-
-	if execErr != nil {
-		greenlogger.LogErrorf(execErr, "Problem executing %v %v %v", configs.PythonDriver, "getStatus.py", key)
+	_, err := tba.NewClient(key).Status()
+	if err != nil && !errors.Is(err, tba.ErrUnauthorized) {
+		greenlogger.LogErrorf(err, "Problem requesting the TBA status with key %v", key)
 	}
 
-	return string(out) != "ERR"
+	return err == nil
 }
 
 // Runs the TBA-key ensurance routine, eventually returning the valid key
 func ensureTBAKey(configs constants.GeneralConfigs) string {
-	if validateTBAKey(configs, configs.TBAKey) {
+	if validateTBAKey(configs.TBAKey) {
 		return configs.TBAKey
 	}
 
@@ -352,7 +452,7 @@ func recursiveTBAKeyValidation(configs *constants.GeneralConfigs, firstRun bool)
 		greenlogger.LogError(scanErr, "Problem scanning TBA key input")
 	}
 
-	if validateTBAKey(*configs, key) {
+	if validateTBAKey(key) {
 		return key
 	} else {
 		greenlogger.LogMessagef("Sorry, %v doesn't appear to be a valid TBA Key. ", key)
@@ -360,33 +460,35 @@ func recursiveTBAKeyValidation(configs *constants.GeneralConfigs, firstRun bool)
 	}
 }
 
-// Validates for the TBA event key. If it is a custom event key, it will simply return that. If not, it will run getEvent.py and return its result.
-func validateEventKey(configs constants.GeneralConfigs, key string) string {
+// Validates for the TBA event key, returning the name of the event and if it is valid.
+// If it is a custom event key, it will simply return the configured name. If not, it will request the event from TBA.
+func validateEventKey(configs constants.GeneralConfigs, key string) (string, bool) {
 	if len(key) != 0 {
 		if string(key[0]) == "c" { // Check for custom event
 			constants.CustomEventKey = true
-			return configs.EventKeyName
+			return configs.EventKeyName, true
 		}
 	}
 
-	runnable := exec.Command(configs.PythonDriver, "getEvent.py", configs.TBAKey, key)
-
-	out, execErr := runnable.Output()
+	if key == "" {
+		return "", false
+	}
 
-	if execErr != nil {
-		greenlogger.LogErrorf(execErr, "Problem executing %v %v %v %v", configs.PythonDriver, "getEvent.py", configs.TBAKey, key)
+	event, err := tba.NewClient(configs.TBAKey).Event(key)
+	if err != nil {
+		if !errors.Is(err, tba.ErrNotFound) {
+			greenlogger.LogErrorf(err, "Problem requesting event %v from TBA", key)
+		}
+		return "", false
 	}
 
-	return string(out)
+	return event.Name, true
 }
 
 // Runs the event key ensurance routine. It will eventually return a valid TBA event key or a custom key.
 func ensureEventKey(configs constants.GeneralConfigs) (string, string) {
-	response := validateEventKey(configs, configs.EventKey)
-	if !strings.Contains(response, "ERR") {
-		configs.EventKeyName = strings.ReplaceAll(strings.Trim(response, "\n"), "'", "")
-
-		return configs.EventKey, configs.EventKeyName
+	if name, valid := validateEventKey(configs, configs.EventKey); valid {
+		return configs.EventKey, name
 	}
 
 	return recursiveEventKeyValidation(&configs, true)
@@ -405,9 +507,9 @@ func recursiveEventKeyValidation(configs *constants.GeneralConfigs, firstRun boo
 		greenlogger.LogError(scanErr, "Problem scanning TBA key input")
 	}
 
-	if name := validateEventKey(*configs, key); !strings.Contains(name, "ERR") {
+	if name, valid := validateEventKey(*configs, key); valid {
 		moveOldJson(key)
-		return key, strings.ReplaceAll(strings.Trim(name, "\n"), "'", "")
+		return key, name
 	} else {
 		greenlogger.LogMessagef("Sorry, %v doesn't appear to be a valid Event Key. ", key)
 		return recursiveEventKeyValidation(configs, false)
@@ -415,17 +517,19 @@ func recursiveEventKeyValidation(configs *constants.GeneralConfigs, firstRun boo
 }
 
 // Handles setting the event key. If the passed in key is valid, it will change the cached configs, the file-encoded configs, and trigger
-// writing to schedule.json, TeamLists, storing teams, and resetting user scores.
-func SetEventKey(key string) bool {
+// writing to schedule.json, TeamLists, storing teams, and resetting user scores. If the key changed, every schedule is cleared
+// and every open swap cancelled, recorded as done by username.
+func SetEventKey(key string, username string) bool {
 	file, openErr := filemanager.OpenWithPermissions(constants.ConfigFilePath)
 	if openErr != nil {
 		greenlogger.LogErrorf(openErr, "Problem creating %v", constants.ConfigFilePath)
 	}
 	defer file.Close()
 
-	if name := validateEventKey(constants.CachedConfigs, key); !strings.Contains(name, "ERR") {
+	if name, valid := validateEventKey(constants.CachedConfigs, key); valid {
+		changed := constants.CachedConfigs.EventKey != key
 		constants.CachedConfigs.EventKey = key
-		constants.CachedConfigs.EventKeyName = strings.Trim(strings.ReplaceAll(name, "'", ""), "\n")
+		constants.CachedConfigs.EventKeyName = name
 
 		encodeErr := yaml.NewEncoder(file).Encode(&constants.CachedConfigs)
 
@@ -439,6 +543,11 @@ func SetEventKey(key string) bool {
 
 		userDB.ResetScores()
 
+		// Schedules and swaps from the last event don't apply to the new one
+		if changed {
+			schedule.ClearAllSchedules(username)
+		}
+
 		greenlogger.LogMessagef("Successfully changed Event Key to %v", key)
 
 		return true
@@ -595,12 +704,74 @@ func ensureScoutDB(configs constants.GeneralConfigs) {
 		}
 	}
 
+	for _, statement := range schedule.Schema {
+		if _, execErr := dbRef.Exec(statement); execErr != nil {
+			greenlogger.FatalError(execErr, "Problem creating scouting schedule database")
+		}
+	}
+
+	schedule.MigrateSchedules(dbRef, configs.EventKey)
+
 	closeErr := dbRef.Close()
 	if closeErr != nil {
 		greenlogger.LogError(closeErr, "Problem closing scouting schedule database")
 	}
 }
 
+// Ensures matches.db exists and has every table it needs. If not, creates them.
+func ensureMatchDB(configs constants.GeneralConfigs) {
+	dbPath := filepath.Join(configs.RuntimeDirectory, matchDB.DatabaseName)
+
+	_, err := os.Stat(dbPath)
+	if err != nil && os.IsNotExist(err) && filemanager.IsSudo() {
+		greenlogger.FatalLogMessage(matchDB.DatabaseName + " must still be created, please run 'go run main.go setup' without sudo so you can alter its contents in the future.")
+	}
+
+	dbRef, openErr := sql.Open(configs.SqliteDriver, dbPath)
+
+	if openErr != nil {
+		greenlogger.FatalLogMessage(openErr.Error())
+	}
+
+	for _, statement := range matchDB.Schema {
+		if _, execErr := dbRef.Exec(statement); execErr != nil {
+			greenlogger.FatalError(execErr, "Problem creating scouting data database")
+		}
+	}
+
+	closeErr := dbRef.Close()
+	if closeErr != nil {
+		greenlogger.LogError(closeErr, "Problem closing scouting data database")
+	}
+}
+
+// Ensures picklists.db exists and has every table it needs. If not, creates them.
+func ensurePickListDB(configs constants.GeneralConfigs) {
+	dbPath := filepath.Join(configs.RuntimeDirectory, pickList.DatabaseName)
+
+	_, err := os.Stat(dbPath)
+	if err != nil && os.IsNotExist(err) && filemanager.IsSudo() {
+		greenlogger.FatalLogMessage(pickList.DatabaseName + " must still be created, please run 'go run main.go setup' without sudo so you can alter its contents in the future.")
+	}
+
+	dbRef, openErr := sql.Open(configs.SqliteDriver, dbPath)
+
+	if openErr != nil {
+		greenlogger.FatalLogMessage(openErr.Error())
+	}
+
+	for _, statement := range pickList.Schema {
+		if _, execErr := dbRef.Exec(statement); execErr != nil {
+			greenlogger.FatalError(execErr, "Problem creating pick list database")
+		}
+	}
+
+	closeErr := dbRef.Close()
+	if closeErr != nil {
+		greenlogger.LogError(closeErr, "Problem closing pick list database")
+	}
+}
+
 // Checks for credentials.json, required for the sheets API. If it doesn't exist, it will exit the program.
 func ensureSheetsAPI(configs constants.GeneralConfigs) {
 	creds, err := os.ReadFile(filepath.Join("conf", "credentials.json"))
@@ -725,24 +896,6 @@ func ensureDatabasesExist(configs constants.GeneralConfigs) {
 	}
 }
 
-// Downloads the tba api client for python. Always runs, just to be safe. If it cannot install with either pip or pip3, it will fatal.
-func downloadAPIPackage() {
-	runnable := exec.Command("pip", "install", "git+https://github.com/TBA-API/tba-api-client-python.git")
-	_, execErr := runnable.Output()
-
-	if execErr != nil && !strings.Contains(execErr.Error(), "exit status 1") {
-		greenlogger.LogError(execErr, "Problem executing pip install git+https://github.com/TBA-API/tba-api-client-python.git")
-		greenlogger.LogMessage("Attempting to run with pip3...")
-
-		runnable = exec.Command("pip3", "install", "git+https://github.com/TBA-API/tba-api-client-python.git")
-		_, err := runnable.Output()
-		if err != nil && !strings.Contains(err.Error(), "exit status 1") {
-			greenlogger.FatalError(err, "Could not install tba-api-client-python with pip or pip3! Please ensure you have pip in your $PATH")
-		}
-	}
-
-}
-
 // Runs the slack ensurance routine.
 func ensureSlackConfiguration(configs constants.SlackConfigs) constants.SlackConfigs {
 	var configsToReturn constants.SlackConfigs = configs
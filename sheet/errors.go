@@ -0,0 +1,40 @@
+package sheet
+
+// Classifying errors from the google sheets API
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Returns if an error from the sheets API is likely to go away on its own, such as running out of quota or a network blip.
+// Anything else, such as a bad range or missing permissions, will fail the same way every time.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		case http.StatusForbidden:
+			// Older quota errors are 403s, told apart by their reason
+			for _, item := range apiErr.Errors {
+				if strings.Contains(strings.ToLower(item.Reason), "ratelimitexceeded") || item.Reason == "quotaExceeded" {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
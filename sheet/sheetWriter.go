@@ -9,6 +9,7 @@ import (
 	"GreenScoutBackend/lib"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -105,82 +106,19 @@ func SetupSheetsAPI(b []byte) {
 }
 
 // Writes team data from multi-scouting to a specified line
-func WriteMultiScoutedTeamDataToLine(matchdata lib.MultiMatch, row int, sources []lib.TeamData) bool {
-	ampTendency, speakerTendency, distanceTendency, shuttleTendency := lib.GetCycleTendencies(matchdata.CycleData.AllCycles)
-	ampAccuracy, speakerAccuracy, distanceAccuracy, shuttleAccuracy := lib.GetCycleAccuracies(matchdata.CycleData.AllCycles)
-
-	// This is ONE ROW. Each value is a cell in that row.
-	valuesToWrite := []interface{}{
-		matchdata.TeamNumber,
-		matchdata.CycleData.AvgCycleTime,
-		matchdata.CycleData.NumCycles,
-		math.Round(ampTendency*10000) / 100,                   // Amp tendency
-		ampAccuracy,                                           // Amp Accuracy
-		math.Round(speakerTendency*10000) / 100,               // Speaker tendency
-		speakerAccuracy,                                       // Speaker Accuracy
-		math.Round(distanceTendency*10000) / 100,              // Distance tendency
-		distanceAccuracy,                                      // Distance accuracy
-		math.Round(shuttleTendency*10000) / 100,               // Shuttle tendency
-		shuttleAccuracy,                                       // Shuttle accuracy
-		lib.GetSpeakerPosAsString(matchdata.SpeakerPositions), // Speaker positions
-		lib.GetPickupLocations(matchdata.Pickups),             // Pickup positions
-		matchdata.Auto.Can,                                    // Had Auto
-		matchdata.Auto.Scores,                                 // Scores in auto
-		lib.GetAutoAccuracy(matchdata.Auto),                   // Auto accuracy
-		matchdata.Auto.Ejects,                                 // Auto shuttles
-		matchdata.Climb.Succeeded,                             // Can climb
-		matchdata.Climb.Time,                                  // Climb Time
-		matchdata.Parked,                                      // Parked
-		matchdata.TrapScore,                                   // Trap Score
-		lib.CompileNotes2(matchdata, sources),                 // Notes + Penalties + DC + Lost track
-	}
-
-	var vr sheets.ValueRange
-
-	vr.Values = append(vr.Values, valuesToWrite)
-
-	writeRange := fmt.Sprintf("RawData!B%v", row)
-
-	_, err := Srv.Spreadsheets.Values.Update(SpreadsheetId, writeRange, &vr).ValueInputOption("RAW").Do()
-
-	if err != nil {
-		greenlogger.LogError(err, "Unable to write data to sheet")
-		return false
-	}
-	return true
+func WriteMultiScoutedTeamDataToLine(matchdata lib.MultiMatch, row int) error {
+	// This is ONE ROW. Each value is a cell in that row, as configured in the game config.
+	return writeRawDataRow(matchdata.Columns, row)
 }
 
 // Writes data from a single-scouted match to a line
-func WriteTeamDataToLine(teamData lib.TeamData, row int) bool {
-	ampTendency, speakerTendency, distanceTendency, shuttleTendency := lib.GetCycleTendencies(teamData.Cycles)
-	ampAccuracy, speakerAccuracy, distanceAccuracy, shuttleAccuracy := lib.GetCycleAccuracies(teamData.Cycles)
-
-	// This is ONE ROW. Each value is a cell in that row.
-	valuesToWrite := []interface{}{
-		teamData.TeamNumber,                           // Team Number
-		lib.GetAvgCycleTime(teamData.Cycles),          // Avg cycle time
-		lib.GetNumCycles(teamData.Cycles),             // Num Cycles
-		math.Round(ampTendency*10000) / 100,           // Amp tendency
-		ampAccuracy,                                   // Amp Accuracy
-		math.Round(speakerTendency*10000) / 100,       // Speaker tendency
-		speakerAccuracy,                               // Speaker Accuracy
-		math.Round(distanceTendency*10000) / 100,      // Distance tendency
-		distanceAccuracy,                              // Distance accuracy
-		math.Round(shuttleTendency*10000) / 100,       // Shuttle tendency
-		shuttleAccuracy,                               // Shuttle accuracy
-		lib.GetSpeakerPosAsString(teamData.Positions), // Speaker positions
-		lib.GetPickupLocations(teamData.Pickups),      // Pickup positions
-		teamData.Auto.Can,                             // Had Auto
-		teamData.Auto.Scores,                          // Scores in auto
-		lib.GetAutoAccuracy(teamData.Auto),            // Auto accuracy
-		teamData.Auto.Ejects,                          // Auto shuttles
-		teamData.Climb.Succeeded,                      // Can climb
-		teamData.Climb.Time,                           // Climb Time
-		teamData.Misc.Parked,                          // Parked
-		teamData.Trap.Score,                           // Trap Score
-		lib.CompileNotes(teamData),                    // Notes + Penalties + DC + Lost track
-	}
+func WriteTeamDataToLine(teamData lib.TeamData, row int) error {
+	// This is ONE ROW. Each value is a cell in that row, as configured in the game config.
+	return writeRawDataRow(lib.GetRowValues(teamData), row)
+}
 
+// Writes one row of values to RawData, starting at column B
+func writeRawDataRow(valuesToWrite []interface{}, row int) error {
 	var vr sheets.ValueRange
 
 	vr.Values = append(vr.Values, valuesToWrite)
@@ -191,11 +129,10 @@ func WriteTeamDataToLine(teamData lib.TeamData, row int) bool {
 
 	if err != nil {
 		greenlogger.LogError(err, "Unable to write data to sheet")
-		return false
+		return err
 	}
 
-	return true
-
+	return nil
 }
 
 // Wrapper around sheets' batch update.
@@ -236,30 +173,33 @@ func FillMatches(startMatch int, endMatch int) {
 	}
 }
 
+// Returned by UpdateSheetID when the new sheet can't be read
+var ErrInvalidSheetID = errors.New("the sheet ID is invalid")
+
 // Updates the ID of the sheet to be used, in memory and yaml.
-func UpdateSheetID(newSheet string) string {
-	if IsSheetValid(newSheet) {
-		constants.CachedConfigs.SpreadSheetID = newSheet
-
-		configFile, openErr := filemanager.OpenWithPermissions(constants.ConfigFilePath)
-		if openErr != nil {
-			greenlogger.LogErrorf(openErr, "Problem opening %v", constants.ConfigFilePath)
-			return "There was a problem updating the sheet ID"
-		}
+func UpdateSheetID(newSheet string) error {
+	if !IsSheetValid(newSheet) {
+		return ErrInvalidSheetID
+	}
 
-		defer configFile.Close()
+	constants.CachedConfigs.SpreadSheetID = newSheet
 
-		encodeErr := yaml.NewEncoder(configFile).Encode(&constants.CachedConfigs)
+	configFile, openErr := filemanager.OpenWithPermissions(constants.ConfigFilePath)
+	if openErr != nil {
+		greenlogger.LogErrorf(openErr, "Problem opening %v", constants.ConfigFilePath)
+		return openErr
+	}
 
-		if encodeErr != nil {
-			greenlogger.LogErrorf(encodeErr, "Problem encoding %v", constants.CachedConfigs)
-			return "There was a problem updating the sheet ID"
-		}
+	defer configFile.Close()
+
+	encodeErr := yaml.NewEncoder(configFile).Encode(&constants.CachedConfigs)
 
-		return "Successfully updated sheet ID to " + newSheet
+	if encodeErr != nil {
+		greenlogger.LogErrorf(encodeErr, "Problem encoding %v", constants.CachedConfigs)
+		return encodeErr
 	}
-	return "Sheet ID " + newSheet + " is invalid!"
 
+	return nil
 }
 
 // Tries to read the top-left cell of the RawData tab, returning if it can.
@@ -360,25 +300,10 @@ func WriteConditionalFormatting() {
 }
 
 // Writes data from pit scouting to a line
-func WritePitDataToLine(pitData lib.PitScoutingData, row int) bool {
-
-	// This is ONE ROW. Each value is a cell in that row.
-	valuesToWrite := []interface{}{
-		pitData.TeamNumber,                       // Team Number
-		pitData.PitIdentifier,                    // Pit Identifier
-		pitData.Drivetrain,                       // Drivetrain type
-		lib.GetSpeakerPosAsString(pitData.Sides), // Speaker positions
-		pitData.Distance.Can,                     // Can distance at all
-		lib.GetDistance(pitData),                 // Distance from which shooting is possible
-		pitData.AutoScores,                       // Auto Scores
-		pitData.MiddleControls,                   // Middle notes controllable in auto
-		pitData.NoteDetection,                    // Has note detection
-		pitData.Cycles,                           // Avg cycles
-		pitData.DriverExperience,                 // Driver years of experience
-		pitData.BotType,                          // ex. Amp, Speaker, Defense
-		pitData.EndgameBehavior,                  // Climb or park basically
-		lib.GetClimbTime(pitData),                // Time to climb
-	}
+func WritePitDataToLine(pitData lib.PitScoutingData, row int) error {
+
+	// This is ONE ROW. Each value is a cell in that row, as configured in the game config.
+	valuesToWrite := lib.GetPitRowValues(pitData)
 
 	var vr sheets.ValueRange
 
@@ -390,9 +315,9 @@ func WritePitDataToLine(pitData lib.PitScoutingData, row int) bool {
 
 	if err != nil {
 		greenlogger.LogError(err, "Unable to write data to sheet")
-		return false
+		return err
 	}
 
-	return true
+	return nil
 
 }
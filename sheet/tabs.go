@@ -0,0 +1,125 @@
+package sheet
+
+// Writing tabs the server owns completely, which are rewritten from scratch whenever they change
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/lib"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// The name of the tab listing what the scouters of multi-scouted matches disagreed on
+const DiscrepanciesTab = "Discrepancies"
+
+// The name of the tab listing every team's OPR, DPR and CCWM
+const RatingsTab = "Ratings"
+
+// Adds a tab to the sheet if it doesn't have one with that title already
+func ensureTab(title string) error {
+	spreadsheet, getErr := Srv.Spreadsheets.Get(SpreadsheetId).Fields("sheets.properties.title").Do()
+	if getErr != nil {
+		greenlogger.LogError(getErr, "Unable to read the tabs of the sheet")
+		return getErr
+	}
+
+	for _, tab := range spreadsheet.Sheets {
+		if tab.Properties.Title == title {
+			return nil
+		}
+	}
+
+	_, addErr := Srv.Spreadsheets.BatchUpdate(SpreadsheetId, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{AddSheet: &sheets.AddSheetRequest{Properties: &sheets.SheetProperties{Title: title}}}},
+	}).Do()
+	if addErr != nil {
+		greenlogger.LogErrorf(addErr, "Unable to add the %v tab to the sheet", title)
+		return addErr
+	}
+
+	greenlogger.LogMessagef("Added the %v tab to the sheet", title)
+	return nil
+}
+
+// Replaces everything in a tab with a header row and the rows under it, adding the tab if it doesn't exist
+func rewriteTab(title string, header []interface{}, rows [][]interface{}) error {
+	if tabErr := ensureTab(title); tabErr != nil {
+		return tabErr
+	}
+
+	if _, clearErr := Srv.Spreadsheets.Values.Clear(SpreadsheetId, title, &sheets.ClearValuesRequest{}).Do(); clearErr != nil {
+		greenlogger.LogErrorf(clearErr, "Unable to clear the %v tab", title)
+		return clearErr
+	}
+
+	vr := sheets.ValueRange{Values: append([][]interface{}{header}, rows...)}
+	if _, updateErr := Srv.Spreadsheets.Values.Update(SpreadsheetId, title+"!A1", &vr).ValueInputOption("RAW").Do(); updateErr != nil {
+		greenlogger.LogErrorf(updateErr, "Unable to write the %v tab", title)
+		return updateErr
+	}
+
+	return nil
+}
+
+// Rewrites the Discrepancies tab with every discrepancy of every report, one per row
+func WriteDiscrepancies(reports []lib.DiscrepancyReport) error {
+	header := []interface{}{"Match", "Driver Station", "Team", "Scouters", "Field", "Written", "Spread", "Outlier", "Values"}
+
+	var rows [][]interface{}
+	for _, report := range reports {
+		for _, discrepancy := range report.Discrepancies {
+			scouters := make([]string, 0, len(discrepancy.Values))
+			for scouter := range discrepancy.Values {
+				scouters = append(scouters, scouter)
+			}
+			sort.Strings(scouters)
+
+			var values []string
+			for _, scouter := range scouters {
+				values = append(values, fmt.Sprintf("%v: %v", scouter, discrepancy.Values[scouter]))
+			}
+
+			rows = append(rows, []interface{}{
+				report.Match,
+				lib.GetDSString(report.IsBlue, uint(report.DSNumber)),
+				report.Team,
+				strings.Join(report.Scouters, ", "),
+				discrepancy.Column,
+				fmt.Sprint(discrepancy.Merged),
+				discrepancy.Spread,
+				discrepancy.Outlier,
+				strings.Join(values, ", "),
+			})
+		}
+	}
+
+	return rewriteTab(DiscrepanciesTab, header, rows)
+}
+
+// Rewrites the Ratings tab with every team's ratings, one per row, followed by a column for every component field
+func WriteRatings(ratings []lib.TeamRating, components []string) error {
+	header := []interface{}{"Team", "Matches", "OPR", "DPR", "CCWM"}
+	for _, component := range components {
+		header = append(header, component)
+	}
+
+	var rows [][]interface{}
+	for _, rating := range ratings {
+		row := []interface{}{rating.Team, rating.Matches, roundRating(rating.OPR), roundRating(rating.DPR), roundRating(rating.CCWM)}
+		for _, component := range components {
+			row = append(row, roundRating(rating.Components[component]))
+		}
+		rows = append(rows, row)
+	}
+
+	return rewriteTab(RatingsTab, header, rows)
+}
+
+// Rounds a rating to two decimal places for the sheet
+func roundRating(rating float64) float64 {
+	return math.Round(rating*100) / 100
+}
@@ -0,0 +1,173 @@
+// A client for the parts of The Blue Alliance's v3 API (https://www.thebluealliance.com/apidocs/v3) that the server uses.
+package tba
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The root of every TBA v3 endpoint
+const DefaultBaseURL = "https://www.thebluealliance.com/api/v3"
+
+// Returned when TBA rejects the API key
+var ErrUnauthorized = errors.New("tba: api key was rejected")
+
+// Returned when the requested resource (event, team, etc.) doesn't exist
+var ErrNotFound = errors.New("tba: resource not found")
+
+// An unsuccessful response from TBA. Matches ErrUnauthorized and ErrNotFound with errors.Is() where appropriate.
+type APIError struct {
+	StatusCode int    // The HTTP status code of the response
+	Path       string // The endpoint that was requested
+	Message    string // The body of the response
+}
+
+func (err *APIError) Error() string {
+	return fmt.Sprintf("tba: %v returned %v: %v", err.Path, err.StatusCode, err.Message)
+}
+
+// Allows errors.Is(err, ErrUnauthorized) and errors.Is(err, ErrNotFound)
+func (err *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return err.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return err.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// A previously successful response, kept so unchanged data doesn't have to be re-sent by TBA
+type cachedResponse struct {
+	etag         string // The ETag header of the response
+	lastModified string // The Last-Modified header of the response
+	body         []byte // The body of the response
+}
+
+// The responses cached by every client, by the API key and URL they were requested with
+var cache = struct {
+	sync.Mutex
+	responses map[string]cachedResponse
+}{responses: make(map[string]cachedResponse)}
+
+// A client authenticated with one API key
+type Client struct {
+	BaseURL    string       // The root of every endpoint; DefaultBaseURL unless testing
+	HTTPClient *http.Client // The client requests are sent through
+	apiKey     string
+}
+
+// Creates a client that authenticates with the passed in API key
+func NewClient(apiKey string) *Client {
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		apiKey:     apiKey,
+	}
+}
+
+// Sends a GET to an endpoint and decodes its json into the passed in pointer.
+// Sends If-None-Match and If-Modified-Since when a previous response was cached, reusing it if TBA says nothing changed.
+func (client *Client) get(path string, into any) error {
+	url := client.BaseURL + path
+	cacheKey := client.apiKey + " " + url
+
+	request, requestErr := http.NewRequest(http.MethodGet, url, nil)
+	if requestErr != nil {
+		return requestErr
+	}
+
+	request.Header.Set("X-TBA-Auth-Key", client.apiKey)
+	request.Header.Set("Accept", "application/json")
+
+	cache.Lock()
+	cached, isCached := cache.responses[cacheKey]
+	cache.Unlock()
+
+	if isCached {
+		if cached.etag != "" {
+			request.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			request.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	response, doErr := client.HTTPClient.Do(request)
+	if doErr != nil {
+		return fmt.Errorf("tba: requesting %v: %w", path, doErr)
+	}
+	defer response.Body.Close()
+
+	var body []byte
+	switch {
+	case response.StatusCode == http.StatusNotModified && isCached:
+		body = cached.body
+
+	case response.StatusCode == http.StatusOK:
+		var readErr error
+		body, readErr = io.ReadAll(response.Body)
+		if readErr != nil {
+			return fmt.Errorf("tba: reading %v: %w", path, readErr)
+		}
+
+		cache.Lock()
+		cache.responses[cacheKey] = cachedResponse{
+			etag:         response.Header.Get("ETag"),
+			lastModified: response.Header.Get("Last-Modified"),
+			body:         body,
+		}
+		cache.Unlock()
+
+	default:
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return &APIError{StatusCode: response.StatusCode, Path: path, Message: string(message)}
+	}
+
+	if unmarshalErr := json.Unmarshal(body, into); unmarshalErr != nil {
+		return fmt.Errorf("tba: decoding %v: %w", path, unmarshalErr)
+	}
+
+	return nil
+}
+
+// Gets the status of TBA. As it requires authentication, this also checks that the API key is valid.
+func (client *Client) Status() (Status, error) {
+	var status Status
+	err := client.get("/status", &status)
+	return status, err
+}
+
+// Gets an event by its key (ex. 2024mnst)
+func (client *Client) Event(eventKey string) (Event, error) {
+	var event Event
+	err := client.get("/event/"+eventKey, &event)
+	return event, err
+}
+
+// Gets every match of an event, including score breakdowns of those that have been played
+func (client *Client) EventMatches(eventKey string) ([]Match, error) {
+	var matches []Match
+	err := client.get("/event/"+eventKey+"/matches", &matches)
+	return matches, err
+}
+
+// Gets every team attending an event
+func (client *Client) EventTeams(eventKey string) ([]Team, error) {
+	var teams []Team
+	err := client.get("/event/"+eventKey+"/teams/simple", &teams)
+	return teams, err
+}
+
+// Gets every event of a year
+func (client *Client) EventsByYear(year int) ([]Event, error) {
+	var events []Event
+	err := client.get("/events/"+strconv.Itoa(year)+"/simple", &events)
+	return events, err
+}
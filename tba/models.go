@@ -0,0 +1,94 @@
+package tba
+
+// Typed results of TBA endpoints. Only the fields the server uses are included.
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Comp levels of a match
+const (
+	Qualification = "qm"
+	EighthFinal   = "ef"
+	QuarterFinal  = "qf"
+	SemiFinal     = "sf"
+	Final         = "f"
+)
+
+// The status of TBA
+type Status struct {
+	CurrentSeason    int  `json:"current_season"`     // The season TBA considers current
+	MaxSeason        int  `json:"max_season"`         // The latest season TBA has data for
+	IsDatafeedDown   bool `json:"is_datafeed_down"`   // If TBA's FIRST datafeed is down
+	DownEventsLength int  `json:"down_events_length"` // Unused, kept to document the response
+}
+
+// An event
+type Event struct {
+	Key       string `json:"key"`        // The event key (ex. 2024mnst)
+	Name      string `json:"name"`       // The full name of the event
+	ShortName string `json:"short_name"` // The name of the event without any sponsors
+	EventCode string `json:"event_code"` // The event key without the year
+	EventType int    `json:"event_type"` // The type of event (regional, district, etc)
+	City      string `json:"city"`       // The city the event is held in
+	StateProv string `json:"state_prov"` // The state or province the event is held in
+	Country   string `json:"country"`    // The country the event is held in
+	StartDate string `json:"start_date"` // The first day of the event, as yyyy-mm-dd
+	EndDate   string `json:"end_date"`   // The last day of the event, as yyyy-mm-dd
+	Year      int    `json:"year"`       // The year of the event
+}
+
+// A team attending an event
+type Team struct {
+	Key        string `json:"key"`         // The team key (ex. frc1816)
+	TeamNumber int    `json:"team_number"` // The team number
+	Nickname   string `json:"nickname"`    // The team's nickname
+	Name       string `json:"name"`        // The team's full name
+	City       string `json:"city"`        // The team's city
+	StateProv  string `json:"state_prov"`  // The team's state or province
+	Country    string `json:"country"`     // The team's country
+}
+
+// One alliance of a match
+type Alliance struct {
+	Score             int      `json:"score"`               // The score, or -1 if the match hasn't been played
+	TeamKeys          []string `json:"team_keys"`           // The teams on the alliance, in driverstation order
+	SurrogateTeamKeys []string `json:"surrogate_team_keys"` // Teams playing as surrogates
+	DQTeamKeys        []string `json:"dq_team_keys"`        // Teams that were disqualified
+}
+
+// Both alliances of a match
+type MatchAlliances struct {
+	Red  Alliance `json:"red"`
+	Blue Alliance `json:"blue"`
+}
+
+// A match
+type Match struct {
+	Key             string                    `json:"key"`              // The match key (ex. 2024mnst_qm1)
+	CompLevel       string                    `json:"comp_level"`       // The comp level (qm, ef, qf, sf, f)
+	SetNumber       int                       `json:"set_number"`       // The set number, for playoffs
+	MatchNumber     int                       `json:"match_number"`     // The match number within its comp level and set
+	Alliances       MatchAlliances            `json:"alliances"`        // The alliances
+	WinningAlliance string                    `json:"winning_alliance"` // red, blue, or empty for ties and unplayed matches
+	EventKey        string                    `json:"event_key"`        // The event the match is in
+	Time            int64                     `json:"time"`             // The scheduled time, as unix seconds
+	ActualTime      int64                     `json:"actual_time"`      // The time it was actually played, as unix seconds
+	PostResultTime  int64                     `json:"post_result_time"` // The time its results were posted, as unix seconds
+	ScoreBreakdown  map[string]map[string]any `json:"score_breakdown"`  // The season-specific score breakdown, keyed by red and blue
+}
+
+// Returns if the match has been played and its results posted
+func (match Match) IsPlayed() bool {
+	return match.Alliances.Red.Score >= 0 && match.Alliances.Blue.Score >= 0 && match.PostResultTime > 0
+}
+
+// Converts a team key (ex. frc1816) to its team number, returning 0 if it isn't a valid team key.
+func TeamNumber(teamKey string) int {
+	number, err := strconv.Atoi(strings.TrimPrefix(teamKey, "frc"))
+	if err != nil {
+		return 0
+	}
+	return number
+}
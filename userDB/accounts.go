@@ -0,0 +1,321 @@
+package userDB
+
+// Utilities for managing per-user accounts in auth.db
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"GreenScoutBackend/rsaUtil"
+	"database/sql"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The roles with special meaning to the server. Other roles in the role table can log in, but have no extra permissions.
+const (
+	RoleSuper    = "super" // Can do anything, including managing admins
+	RoleAdmin    = "admin" // Can manage users, schedules and the server's configuration
+	RoleVerified = "1816"  // Members of the team, able to see team-only information like the spreadsheet
+)
+
+// The minimum length of a password chosen by a user or admin. Join codes aren't held to this.
+const minPasswordLength = 8
+
+// The cost passed to bcrypt when hashing account passwords
+const passwordCost = 10
+
+// The statements creating every auth.db table that isn't maintained by hand
+var authSchema = []string{
+	`create table if not exists accounts (
+		username text primary key,
+		uuid text not null,
+		password text not null,
+		role text not null,
+		disabled integer not null default 0,
+		must_change integer not null default 0
+	)`,
+	`create table if not exists sessions (
+		token_hash text primary key,
+		id text not null unique,
+		username text not null,
+		uuid text not null,
+		role text not null,
+		device text not null,
+		issued_at integer not null,
+		expires_at integer not null,
+		last_seen integer not null,
+		revoked integer not null default 0
+	)`,
+	`create index if not exists sessions_username on sessions (username)`,
+}
+
+var (
+	ErrAccountExists     = errors.New("an account with that username already exists")
+	ErrAccountNotFound   = errors.New("no account with that username exists")
+	ErrInvalidRole       = errors.New("that role doesn't exist")
+	ErrPasswordTooShort  = errors.New("passwords must be at least 8 characters")
+	ErrWrongPassword     = errors.New("the current password is incorrect")
+	ErrLoginRejected     = errors.New("incorrect username or password")
+	ErrJoinNeedsPassword = errors.New("joining with a join code needs a new password of at least 8 characters that isn't the join code")
+)
+
+// An account, without its password hash
+type Account struct {
+	Username           string // The username
+	UUID               string // The uuid of the matching user in users.db
+	Role               string // The role, matching one in the role table
+	Disabled           bool   // If the account is blocked from logging in
+	MustChangePassword bool   // If the password was set by a join code or an admin and must be changed
+}
+
+// A request from an admin to create, reset or disable an account
+type AccountRequest struct {
+	Username          string // The username of the account
+	Role              string // The role of a new account
+	EncryptedPassword string // The base64 encoded, RSA encrypted temporary password
+	Disabled          bool   // If the account should be disabled
+}
+
+// A request from a user to change their own password
+type PasswordChange struct {
+	Username             string // The username of the account
+	EncryptedPassword    string // The base64 encoded, RSA encrypted current password
+	NewEncryptedPassword string // The base64 encoded, RSA encrypted new password
+}
+
+// Creates the account and session tables in auth.db if they don't exist
+func ensureAccountTables() {
+	for _, statement := range authSchema {
+		if _, err := authDB.Exec(statement); err != nil {
+			greenlogger.FatalError(err, "Problem creating auth.db tables with sql query "+statement)
+		}
+	}
+}
+
+// Returns the account of a username, and if it exists
+func GetAccount(username string) (Account, bool) {
+	var account Account
+	result := authDB.QueryRow("select username, uuid, role, disabled, must_change from accounts where username = ?", username)
+	scanErr := result.Scan(&account.Username, &account.UUID, &account.Role, &account.Disabled, &account.MustChangePassword)
+
+	if scanErr != nil {
+		if !errors.Is(scanErr, sql.ErrNoRows) {
+			greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT ... FROM accounts WHERE username = ? with arg: %v", username)
+		}
+		return Account{}, false
+	}
+
+	return account, true
+}
+
+// Returns the password hash of an account, or an empty string if it doesn't exist
+func getPasswordHash(username string) string {
+	var hash string
+	scanErr := authDB.QueryRow("select password from accounts where username = ?", username).Scan(&hash)
+
+	if scanErr != nil && !errors.Is(scanErr, sql.ErrNoRows) {
+		greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT password FROM accounts WHERE username = ? with arg: %v", username)
+	}
+
+	return hash
+}
+
+// Returns every account
+func GetAllAccounts() []Account {
+	rows, queryErr := authDB.Query("select username, uuid, role, disabled, must_change from accounts order by username")
+	if queryErr != nil {
+		greenlogger.LogError(queryErr, "Problem executing sql query SELECT ... FROM accounts")
+		return nil
+	}
+	defer rows.Close()
+
+	var accounts []Account
+	for rows.Next() {
+		var account Account
+		if scanErr := rows.Scan(&account.Username, &account.UUID, &account.Role, &account.Disabled, &account.MustChangePassword); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM accounts")
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts
+}
+
+// Returns the role whose shared password (join code) matches the passed in plaintext password, and if one did
+func matchJoinCode(passwordPlain string) (string, bool) {
+	checkAgainst := make(map[string]string)
+
+	rows, queryErr := authDB.Query("select role, password from role")
+	if queryErr != nil {
+		greenlogger.LogError(queryErr, "Problem in sql query SELECT role, password FROM role")
+		return "", false
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role string
+		var hashedWord string
+		scanErr := rows.Scan(&role, &hashedWord)
+
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT role, password FROM role")
+		}
+
+		checkAgainst[role] = hashedWord
+	}
+
+	for role, toCheck := range checkAgainst {
+		if comparePasswordBCrypt(passwordPlain, toCheck) {
+			return role, true
+		}
+	}
+
+	return "", false
+}
+
+// Returns if a role exists in the role table
+func roleExists(role string) bool {
+	var count int
+	scanErr := authDB.QueryRow("select count(1) from role where role = ?", role).Scan(&count)
+
+	if scanErr != nil {
+		greenlogger.LogErrorf(scanErr, "Problem scanning response to sql query SELECT COUNT(1) FROM role WHERE role = ? with arg: %v", role)
+	}
+
+	return count > 0
+}
+
+// Hashes a plaintext password with bcrypt
+func hashPassword(passwordPlain string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(passwordPlain), passwordCost)
+	if err != nil {
+		greenlogger.LogError(err, "Problem hashing password")
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Creates an account with a plaintext password, creating the matching user in users.db if needed
+func createAccount(username string, passwordPlain string, role string, mustChange bool) error {
+	if _, exists := GetAccount(username); exists {
+		return ErrAccountExists
+	}
+
+	if !roleExists(role) {
+		return ErrInvalidRole
+	}
+
+	hash, hashErr := hashPassword(passwordPlain)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	uuid, _ := GetUUID(username, true)
+
+	_, execErr := authDB.Exec("insert into accounts values(?,?,?,?,0,?)", username, uuid, hash, role, mustChange)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT INTO accounts VALUES (?,?,?,?,0,?) with args: %v, %v, %v, %v", username, uuid, role, mustChange)
+		return execErr
+	}
+
+	greenlogger.LogMessagef("Created %v account for %v", role, username)
+	return nil
+}
+
+// Creates an account from an admin request. The account must change its password on first login.
+func AddAccount(username string, role string, passwordEncoded []byte) error {
+	passwordPlain := rsaUtil.DecryptPassword(passwordEncoded)
+	if len(passwordPlain) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	return createAccount(username, passwordPlain, role, true)
+}
+
+// Sets an account's password to a temporary one from an admin, requiring it to be changed and revoking all of its sessions
+func ResetPassword(username string, passwordEncoded []byte) error {
+	if _, exists := GetAccount(username); !exists {
+		return ErrAccountNotFound
+	}
+
+	passwordPlain := rsaUtil.DecryptPassword(passwordEncoded)
+	if len(passwordPlain) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	hash, hashErr := hashPassword(passwordPlain)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	_, execErr := authDB.Exec("update accounts set password = ?, must_change = 1 where username = ?", hash, username)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE accounts SET password = ?, must_change = 1 WHERE username = ? with arg: %v", username)
+		return execErr
+	}
+
+	RevokeSessions(username)
+	return nil
+}
+
+// Disables or re-enables an account. Disabling also revokes all of its sessions.
+func SetAccountDisabled(username string, disabled bool) error {
+	if _, exists := GetAccount(username); !exists {
+		return ErrAccountNotFound
+	}
+
+	_, execErr := authDB.Exec("update accounts set disabled = ? where username = ?", disabled, username)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE accounts SET disabled = ? WHERE username = ? with args: %v, %v", disabled, username)
+		return execErr
+	}
+
+	if disabled {
+		RevokeSessions(username)
+	}
+	return nil
+}
+
+// Changes an account's password after checking its current one, clearing any required password change
+func ChangePassword(username string, oldPasswordEncoded []byte, newPasswordEncoded []byte) error {
+	account, exists := GetAccount(username)
+	if !exists || account.Disabled {
+		return ErrAccountNotFound
+	}
+
+	if !comparePasswordBCrypt(rsaUtil.DecryptPassword(oldPasswordEncoded), getPasswordHash(username)) {
+		return ErrWrongPassword
+	}
+
+	newPasswordPlain := rsaUtil.DecryptPassword(newPasswordEncoded)
+	if len(newPasswordPlain) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	hash, hashErr := hashPassword(newPasswordPlain)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	_, execErr := authDB.Exec("update accounts set password = ?, must_change = 0 where username = ?", hash, username)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE accounts SET password = ?, must_change = 0 WHERE username = ? with arg: %v", username)
+		return execErr
+	}
+
+	return nil
+}
+
+// Returns if an account has to change its password before doing anything else
+func MustChangePassword(username string) bool {
+	account, exists := GetAccount(username)
+	return exists && account.MustChangePassword
+}
+
+// Returns if join codes may currently be used to create accounts
+func joinCodesAllowed() bool {
+	return constants.CachedConfigs.AccountConfigs.AllowJoinCode
+}
@@ -0,0 +1,126 @@
+package userDB
+
+// Utilities for storing how accurately scouters scout, compared to TBA score breakdowns
+
+import (
+	greenlogger "GreenScoutBackend/greenLogger"
+	"time"
+)
+
+// The statements creating every users.db table that isn't maintained by hand
+var userSchema = []string{
+	`create table if not exists accuracy_matches (
+		username text not null,
+		event text not null,
+		match integer not null,
+		is_blue integer not null,
+		error real not null,
+		official real not null,
+		awarded integer not null default 0,
+		compared_at integer not null,
+		primary key (username, event, match, is_blue)
+	)`,
+	`create table if not exists scouter_accuracy (
+		username text primary key,
+		matches integer not null,
+		error real not null,
+		official real not null,
+		rating real not null,
+		updated_at integer not null
+	)`,
+}
+
+// How one scouter's data for one alliance in one match compared to its score breakdown
+type MatchAccuracy struct {
+	Username string  // The scouter
+	Event    string  // The event key
+	Match    int     // The qualification match number
+	IsBlue   bool    // If they scouted the blue alliance
+	Error    float64 // How far off the alliance's scouted totals were, summed across every compared quantity
+	Official float64 // The alliance's official totals, summed across every compared quantity
+}
+
+// The overall accuracy of one scouter
+type ScouterAccuracy struct {
+	Username  string    // The scouter
+	Matches   int       // How many matches have been compared
+	Error     float64   // The total error across every compared match
+	Official  float64   // The total official count across every compared match
+	Rating    float64   // 100 minus the error as a percentage of the official count, never below 0
+	UpdatedAt time.Time // When it was last recalculated
+}
+
+// Creates the accuracy tables in users.db if they don't exist
+func ensureUserTables() {
+	for _, statement := range userSchema {
+		if _, err := userDB.Exec(statement); err != nil {
+			greenlogger.FatalError(err, "Problem creating users.db tables with sql query "+statement)
+		}
+	}
+}
+
+// Records how one scouter's match compared, replacing any earlier comparison of it. Whether it was awarded points is kept.
+func RecordMatchAccuracy(result MatchAccuracy) {
+	_, err := userDB.Exec(
+		`insert into accuracy_matches (username, event, match, is_blue, error, official, compared_at) values (?,?,?,?,?,?,?)
+		on conflict (username, event, match, is_blue) do update set error = excluded.error, official = excluded.official, compared_at = excluded.compared_at`,
+		result.Username, result.Event, result.Match, result.IsBlue, result.Error, result.Official, time.Now().UnixMilli(),
+	)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem executing sql query INSERT INTO accuracy_matches ... with args: %v", result)
+	}
+}
+
+// Marks a scouter's match as awarded leaderboard points, returning true if it hadn't been already
+func MarkAccuracyAwarded(result MatchAccuracy) bool {
+	updated, err := userDB.Exec(
+		"update accuracy_matches set awarded = 1 where username = ? and event = ? and match = ? and is_blue = ? and awarded = 0",
+		result.Username, result.Event, result.Match, result.IsBlue,
+	)
+	if err != nil {
+		greenlogger.LogErrorf(err, "Problem executing sql query UPDATE accuracy_matches SET awarded = 1 WHERE ... with args: %v", result)
+		return false
+	}
+
+	affected, _ := updated.RowsAffected()
+	return affected > 0
+}
+
+// Recalculates the rating of every scouter from all of their compared matches
+func UpdateAccuracyRatings() {
+	_, err := userDB.Exec(
+		`insert or replace into scouter_accuracy
+		select username, count(1), sum(error), sum(official), max(0, 100 * (1 - sum(error) / max(sum(official), 1))), ?
+		from accuracy_matches group by username`,
+		time.Now().UnixMilli(),
+	)
+	if err != nil {
+		greenlogger.LogError(err, "Problem executing sql query INSERT OR REPLACE INTO scouter_accuracy SELECT ... FROM accuracy_matches")
+	}
+}
+
+// Returns the rating of every scouter who has been compared, most accurate first
+func GetScouterAccuracy() []ScouterAccuracy {
+	var results []ScouterAccuracy
+
+	rows, err := userDB.Query("select username, matches, error, official, rating, updated_at from scouter_accuracy order by rating desc, matches desc")
+	if err != nil {
+		greenlogger.LogError(err, "Problem executing sql query SELECT ... FROM scouter_accuracy")
+		return results
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var accuracy ScouterAccuracy
+		var updatedAt int64
+		if scanErr := rows.Scan(&accuracy.Username, &accuracy.Matches, &accuracy.Error, &accuracy.Official, &accuracy.Rating, &updatedAt); scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning results of sql query SELECT ... FROM scouter_accuracy")
+			continue
+		}
+
+		accuracy.UpdatedAt = time.UnixMilli(updatedAt)
+		results = append(results, accuracy)
+	}
+
+	return results
+}
@@ -25,45 +25,50 @@ func InitAuthDB() {
 	if dbOpenErr != nil {
 		greenlogger.FatalError(dbOpenErr, "Problem opening database "+filepath.Join(constants.CachedConfigs.PathToDatabases, "auth.db"))
 	}
+
+	ensureAccountTables()
 }
 
 // An attempt to log in
 type LoginAttempt struct {
-	Username          string
-	EncryptedPassword string
+	Username             string
+	EncryptedPassword    string
+	Device               string // An optional name for the device logging in, shown when listing sessions. Defaults to the User-Agent.
+	NewEncryptedPassword string // The password of the new account when joining with a join code, base64 encoded and RSA encrypted like EncryptedPassword
 }
 
-// Authenticates the password through the database, returning the role it turned out to be and if it authenticated.
-func Authenticate(passwordEncoded []byte) (string, bool) {
+// Authenticates a user's password against their account, returning the role of the account, or ErrLoginRejected if it didn't authenticate.
+// If join codes are allowed and the username has never been used, a password matching a role's shared password creates an account
+// with that role and the new password, which has to be sent along (ErrJoinNeedsPassword otherwise). Users that already exist can only
+// get accounts from an admin, so nobody can join as them.
+func Authenticate(username string, passwordEncoded []byte, newPasswordEncoded []byte) (string, error) {
 	passwordPlain := rsaUtil.DecryptPassword(passwordEncoded) // Decrypt password with private key
 
-	checkAgainst := make(map[string]string) // Make a new map {string:string}
-
-	rows, queryErr := authDB.Query("select role, password from role")
-
-	if queryErr != nil {
-		greenlogger.LogError(queryErr, "Problem in sql query SELECT role, password FROM role")
+	if username == "" || passwordPlain == "" {
+		return "", ErrLoginRejected
 	}
 
-	for rows.Next() {
-		var role string
-		var hashedWord string
-		scanErr := rows.Scan(&role, &hashedWord)
-
-		if scanErr != nil {
-			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT role, password FROM role")
+	if account, exists := GetAccount(username); exists {
+		if !account.Disabled && comparePasswordBCrypt(passwordPlain, getPasswordHash(username)) {
+			return account.Role, nil
 		}
-
-		checkAgainst[role] = hashedWord
+		return "", ErrLoginRejected
 	}
 
-	for role, toCheck := range checkAgainst {
-		if comparePasswordBCrypt(passwordPlain, toCheck) {
-			return role, true
+	if joinCodesAllowed() && !userExists(username) {
+		if role, matched := matchJoinCode(passwordPlain); matched {
+			newPasswordPlain := rsaUtil.DecryptPassword(newPasswordEncoded)
+			if len(newPasswordPlain) < minPasswordLength || newPasswordPlain == passwordPlain {
+				return "", ErrJoinNeedsPassword
+			}
+
+			if createAccount(username, newPasswordPlain, role, false) == nil {
+				return role, nil
+			}
 		}
 	}
 
-	return "Not accepted nuh uh", false
+	return "", ErrLoginRejected
 }
 
 // A wrapper for comparing an unhashed and hashed password through bcrypt
@@ -0,0 +1,126 @@
+package userDB
+
+import (
+	"GreenScoutBackend/constants"
+	"GreenScoutBackend/rsaUtil"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The join code of the verified role in the test databases
+const testJoinCode = "go green"
+
+// Sets up users.db and auth.db in a temporary directory, with an RSA key pair and a join code for the verified role
+func setupAuthTest(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	constants.CachedConfigs.SqliteDriver = "sqlite3"
+	constants.CachedConfigs.PathToDatabases = dir
+	constants.CachedConfigs.AccountConfigs.AllowJoinCode = true
+
+	key, keyErr := rsa.GenerateKey(rand.Reader, 2048)
+	if keyErr != nil {
+		t.Fatal(keyErr)
+	}
+	constants.RSAPrivateKeyPath = filepath.Join(dir, "private.pem")
+	constants.RSAPubKeyPath = filepath.Join(dir, "public.pem")
+	writePEM(t, constants.RSAPrivateKeyPath, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	writePEM(t, constants.RSAPubKeyPath, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&key.PublicKey))
+
+	// users and role are made by hand on real servers, so they're made here the same way
+	users, openErr := sql.Open("sqlite3", filepath.Join(dir, "users.db"))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer users.Close()
+	if _, execErr := users.Exec("create table users(uuid, username, displayname, certificate, badges, score, pfp, lifescore, highscore, accolades, color)"); execErr != nil {
+		t.Fatal(execErr)
+	}
+
+	auth, openErr := sql.Open("sqlite3", filepath.Join(dir, "auth.db"))
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer auth.Close()
+	hash, hashErr := hashPassword(testJoinCode)
+	if hashErr != nil {
+		t.Fatal(hashErr)
+	}
+	if _, execErr := auth.Exec("create table role(role text, password text)"); execErr != nil {
+		t.Fatal(execErr)
+	}
+	if _, execErr := auth.Exec("insert into role values(?, ?)", RoleVerified, hash); execErr != nil {
+		t.Fatal(execErr)
+	}
+
+	InitUserDB()
+	InitAuthDB()
+}
+
+// Writes one PEM block to a file
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	t.Helper()
+
+	if writeErr := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+}
+
+func TestJoinCodeCreatesAccount(t *testing.T) {
+	setupAuthTest(t)
+
+	role, err := Authenticate("newbie", rsaUtil.EncodeWithPublicKey(testJoinCode), rsaUtil.EncodeWithPublicKey("my own password"))
+	if err != nil || role != RoleVerified {
+		t.Fatalf("joining returned %q, %v; want %q", role, err, RoleVerified)
+	}
+
+	if _, err := Authenticate("newbie", rsaUtil.EncodeWithPublicKey("my own password"), nil); err != nil {
+		t.Errorf("logging in with the chosen password returned %v", err)
+	}
+	if _, err := Authenticate("newbie", rsaUtil.EncodeWithPublicKey(testJoinCode), nil); !errors.Is(err, ErrLoginRejected) {
+		t.Errorf("logging in with the join code after joining returned %v, want %v", err, ErrLoginRejected)
+	}
+}
+
+func TestJoinCodeNeedsNewPassword(t *testing.T) {
+	setupAuthTest(t)
+
+	for _, newPassword := range []string{"", "short", testJoinCode} {
+		var encoded []byte
+		if newPassword != "" {
+			encoded = rsaUtil.EncodeWithPublicKey(newPassword)
+		}
+
+		if _, err := Authenticate("newbie", rsaUtil.EncodeWithPublicKey(testJoinCode), encoded); !errors.Is(err, ErrJoinNeedsPassword) {
+			t.Errorf("joining with new password %q returned %v, want %v", newPassword, err, ErrJoinNeedsPassword)
+		}
+	}
+
+	if _, exists := GetAccount("newbie"); exists {
+		t.Error("an account was created without a new password")
+	}
+}
+
+func TestJoinCodeCantClaimExistingUser(t *testing.T) {
+	setupAuthTest(t)
+
+	NewUser("veteran", "veteran-uuid")
+	ModifyUserScore("veteran", Increase, 40)
+
+	_, err := Authenticate("veteran", rsaUtil.EncodeWithPublicKey(testJoinCode), rsaUtil.EncodeWithPublicKey("taken over now"))
+	if !errors.Is(err, ErrLoginRejected) {
+		t.Fatalf("joining as an existing user returned %v, want %v", err, ErrLoginRejected)
+	}
+
+	if _, exists := GetAccount("veteran"); exists {
+		t.Error("joining created an account for an existing user")
+	}
+}
@@ -0,0 +1,243 @@
+package userDB
+
+// Utilities for managing login sessions
+
+import (
+	"GreenScoutBackend/constants"
+	greenlogger "GreenScoutBackend/greenLogger"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How long a session lasts without being used, if not configured
+const DefaultSessionIdleHours = 72
+
+// How long a session can last in total, regardless of use, if not configured
+const DefaultSessionMaxDays = 14
+
+// How often a session's expiry is pushed back while it is being used. Keeps every request from being a write.
+const sessionRefreshInterval = time.Minute
+
+// How long expired and revoked sessions are kept before being deleted
+const sessionRetention = 7 * 24 * time.Hour
+
+// A login session. The token itself is only ever given to the client; auth.db stores its sha256 hash.
+type Session struct {
+	ID        string    // The identifier of the session, safe to show to users
+	Username  string    // The user it belongs to
+	UUID      string    // The uuid of the user it belongs to
+	Role      string    // The role it was issued with
+	Device    string    // The device it was issued to
+	IssuedAt  time.Time // When it was issued
+	ExpiresAt time.Time // When it expires unless used again
+	LastSeen  time.Time // When it was last used
+}
+
+// Hashes a session token for storage
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns the configured idle and maximum lifetimes of a session
+func sessionLifetimes() (time.Duration, time.Duration) {
+	idleHours := constants.CachedConfigs.AccountConfigs.SessionIdleHours
+	if idleHours <= 0 {
+		idleHours = DefaultSessionIdleHours
+	}
+
+	maxDays := constants.CachedConfigs.AccountConfigs.SessionMaxDays
+	if maxDays <= 0 {
+		maxDays = DefaultSessionMaxDays
+	}
+
+	return time.Duration(idleHours) * time.Hour, time.Duration(maxDays) * 24 * time.Hour
+}
+
+// Returns the earlier of two times
+func earliest(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// Creates a new session for a user on a device, returning its token (sent by the client as the Certificate header) and the session.
+func CreateSession(username string, role string, device string) (string, Session) {
+	tokenBytes := make([]byte, 32)
+	if _, randErr := rand.Read(tokenBytes); randErr != nil {
+		greenlogger.LogError(randErr, "Problem generating session token")
+		return "", Session{}
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	idle, max := sessionLifetimes()
+	now := time.Now()
+	userUUID, _ := GetUUID(username, true)
+
+	session := Session{
+		ID:        uuid.New().String(),
+		Username:  username,
+		UUID:      userUUID,
+		Role:      role,
+		Device:    device,
+		IssuedAt:  now,
+		ExpiresAt: earliest(now.Add(idle), now.Add(max)),
+		LastSeen:  now,
+	}
+
+	_, execErr := authDB.Exec("insert into sessions values(?,?,?,?,?,?,?,?,?,0)",
+		hashToken(token), session.ID, session.Username, session.UUID, session.Role, session.Device,
+		session.IssuedAt.Unix(), session.ExpiresAt.Unix(), session.LastSeen.Unix())
+
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query INSERT INTO sessions VALUES (...) for %v", username)
+		return "", Session{}
+	}
+
+	pruneSessions()
+
+	return token, session
+}
+
+// Scans a session from a row with the columns id, username, uuid, role, device, issued_at, expires_at, last_seen
+func scanSession(row interface{ Scan(...any) error }) (Session, error) {
+	var session Session
+	var issued, expires, lastSeen int64
+
+	err := row.Scan(&session.ID, &session.Username, &session.UUID, &session.Role, &session.Device, &issued, &expires, &lastSeen)
+
+	session.IssuedAt = time.Unix(issued, 0)
+	session.ExpiresAt = time.Unix(expires, 0)
+	session.LastSeen = time.Unix(lastSeen, 0)
+
+	return session, err
+}
+
+// Verifies a session token, returning its session and if it is valid. Using a session pushes its expiry back, up to its maximum lifetime.
+func VerifySession(token string) (Session, bool) {
+	if token == "" {
+		return Session{}, false
+	}
+
+	tokenHash := hashToken(token)
+
+	session, scanErr := scanSession(authDB.QueryRow(
+		"select id, username, uuid, role, device, issued_at, expires_at, last_seen from sessions where token_hash = ? and revoked = 0", tokenHash))
+
+	if scanErr != nil {
+		if !errors.Is(scanErr, sql.ErrNoRows) {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM sessions WHERE token_hash = ?")
+		}
+		return Session{}, false
+	}
+
+	now := time.Now()
+	if !now.Before(session.ExpiresAt) {
+		return Session{}, false
+	}
+
+	if now.Sub(session.LastSeen) >= sessionRefreshInterval {
+		idle, max := sessionLifetimes()
+		session.LastSeen = now
+		session.ExpiresAt = earliest(now.Add(idle), session.IssuedAt.Add(max))
+
+		_, execErr := authDB.Exec("update sessions set last_seen = ?, expires_at = ? where token_hash = ?", session.LastSeen.Unix(), session.ExpiresAt.Unix(), tokenHash)
+		if execErr != nil {
+			greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE sessions SET last_seen = ?, expires_at = ? for session %v", session.ID)
+		}
+	}
+
+	return session, true
+}
+
+// Verifies a session token, returning the role it was issued with and if it is valid.
+func VerifyCertificate(certificate string) (string, bool) {
+	session, valid := VerifySession(certificate)
+	if !valid {
+		return "none", false
+	}
+
+	return session.Role, true
+}
+
+// Returns every active session of a user, most recently used first
+func GetSessions(username string) []Session {
+	rows, queryErr := authDB.Query(
+		"select id, username, uuid, role, device, issued_at, expires_at, last_seen from sessions where username = ? and revoked = 0 and expires_at > ? order by last_seen desc",
+		username, time.Now().Unix())
+
+	if queryErr != nil {
+		greenlogger.LogErrorf(queryErr, "Problem executing sql query SELECT ... FROM sessions WHERE username = ? with arg: %v", username)
+		return nil
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		session, scanErr := scanSession(rows)
+		if scanErr != nil {
+			greenlogger.LogError(scanErr, "Problem scanning response to sql query SELECT ... FROM sessions WHERE username = ?")
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions
+}
+
+// Revokes the session of a token, returning if there was one to revoke
+func RevokeSession(token string) bool {
+	result, execErr := authDB.Exec("update sessions set revoked = 1 where token_hash = ? and revoked = 0", hashToken(token))
+	if execErr != nil {
+		greenlogger.LogError(execErr, "Problem executing sql query UPDATE sessions SET revoked = 1 WHERE token_hash = ?")
+		return false
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0
+}
+
+// Revokes one of a user's sessions by its ID, returning if there was one to revoke
+func RevokeSessionByID(username string, id string) bool {
+	result, execErr := authDB.Exec("update sessions set revoked = 1 where username = ? and id = ? and revoked = 0", username, id)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE sessions SET revoked = 1 WHERE username = ? AND id = ? with args: %v, %v", username, id)
+		return false
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0
+}
+
+// Revokes every session of a user, logging them out everywhere. Returns the number of sessions revoked.
+func RevokeSessions(username string) int {
+	result, execErr := authDB.Exec("update sessions set revoked = 1 where username = ? and revoked = 0", username)
+	if execErr != nil {
+		greenlogger.LogErrorf(execErr, "Problem executing sql query UPDATE sessions SET revoked = 1 WHERE username = ? with arg: %v", username)
+		return 0
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected > 0 {
+		greenlogger.LogMessagef("Revoked %v sessions of %v", affected, username)
+	}
+	return int(affected)
+}
+
+// Deletes sessions that expired or were revoked long enough ago to be of no use
+func pruneSessions() {
+	cutoff := time.Now().Add(-sessionRetention).Unix()
+
+	_, execErr := authDB.Exec("delete from sessions where expires_at < ? or (revoked = 1 and last_seen < ?)", cutoff, cutoff)
+	if execErr != nil {
+		greenlogger.LogError(execErr, "Problem executing sql query DELETE FROM sessions WHERE expires_at < ?")
+	}
+}
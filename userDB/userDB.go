@@ -27,6 +27,8 @@ func InitUserDB() {
 	if dbOpenErr != nil {
 		greenlogger.FatalError(dbOpenErr, "Problem opening database "+dbPath)
 	}
+
+	ensureUserTables()
 }
 
 // Creates a new user